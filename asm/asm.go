@@ -0,0 +1,343 @@
+// Package asm assembles CHIP-8 programs written with the conventional
+// (Cowgod) mnemonics into ROM images.
+//
+// Source is line based, ';' starts a comment:
+//
+//	:const SPEED 2
+//	:macro move reg amount
+//	    add reg, amount
+//	:endm
+//
+//	start:  ld   i, sprite
+//	        drw  v0, v1, 5
+//	        move v0, SPEED
+//	        jp   start
+//	sprite: :byte 0xF0, 0x90, 0x90, 0x90, 0xF0
+//
+// Numbers may be decimal, hex (0x or #) or binary (0b or %), and operands
+// may add or subtract labels, constants and numbers. The package does not
+// depend on the emulator, so emulator tests can use it to build programs.
+//
+// The Octo statements written by the disassembler are also accepted, one per
+// line, so its output reassembles to the same ROM:
+//
+//	: start
+//	    i := sprite
+//	    if v0 != 0x05 then
+//	    v0 += 0x01
+//	    jump start
+//	: sprite
+//	    0xF0 0x90 0x90 0x90 0xF0
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// DefaultOrigin is the address assembly starts at, where CHIP-8 programs are loaded
+const DefaultOrigin = 0x200
+
+// MaxAddress is the highest address that can be assembled, the end of XO-CHIP memory
+const MaxAddress = 0xFFFF
+
+// Error is an assembly error in a line of source
+type Error struct {
+	Line int // 1-based source line
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Program is the result of assembling a source
+type Program struct {
+	// ROM image, to be loaded at Origin
+	ROM []byte
+	// Origin is the address of the first byte of ROM
+	Origin uint16
+	// Labels maps label names to their addresses
+	Labels map[string]uint16
+}
+
+// WriteSymbols writes the program's labels to w, one "0xADDR name" per line
+// in address order, for use by debuggers and disassemblers
+func (p *Program) WriteSymbols(w io.Writer) error {
+	names := make([]string, 0, len(p.Labels))
+	for name := range p.Labels {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if p.Labels[a] != p.Labels[b] {
+			return int(p.Labels[a]) - int(p.Labels[b])
+		}
+		return strings.Compare(a, b)
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		fmt.Fprintf(bw, "0x%04X %s\n", p.Labels[name], name)
+	}
+	return bw.Flush()
+}
+
+// line is a statement of source after macro expansion
+type line struct {
+	number    int    // source line number
+	label     string // label defined on the line, if any
+	directive string // lower case mnemonic or directive, empty for label-only lines
+	operands  []string
+}
+
+// assembler holds the state of an assembly
+type assembler struct {
+	lines     []line
+	constants map[string]int
+	labels    map[string]uint16
+	image     map[uint16]byte
+	pc        int
+}
+
+// Assemble assembles source into a program starting at DefaultOrigin
+func Assemble(source string) (*Program, error) {
+	lines, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &assembler{
+		lines:     lines,
+		constants: make(map[string]int),
+		labels:    make(map[string]uint16),
+	}
+
+	// first pass assigns addresses to labels, second pass emits code
+	if err := a.pass(false); err != nil {
+		return nil, err
+	}
+	if err := a.pass(true); err != nil {
+		return nil, err
+	}
+
+	program := &Program{Origin: DefaultOrigin, Labels: a.labels}
+	if len(a.image) == 0 {
+		return program, nil
+	}
+	lowest, highest := MaxAddress, 0
+	for address := range a.image {
+		lowest = min(lowest, int(address))
+		highest = max(highest, int(address))
+	}
+	if lowest < DefaultOrigin {
+		return nil, fmt.Errorf("code at 0x%04X is below the program start 0x%04X", lowest, DefaultOrigin)
+	}
+	program.ROM = make([]byte, highest-DefaultOrigin+1)
+	for address, b := range a.image {
+		program.ROM[int(address)-DefaultOrigin] = b
+	}
+	return program, nil
+}
+
+// MustAssemble is like Assemble but panics on error, for programs in tests
+func MustAssemble(source string) []byte {
+	program, err := Assemble(source)
+	if err != nil {
+		panic(err)
+	}
+	return program.ROM
+}
+
+func (a *assembler) pass(emit bool) error {
+	a.pc = DefaultOrigin
+	a.image = make(map[uint16]byte)
+	if !emit {
+		clear(a.constants)
+	}
+
+	for _, l := range a.lines {
+		if l.label != "" && !emit {
+			if err := a.defineLabel(l.label); err != nil {
+				return &Error{Line: l.number, Err: err}
+			}
+		}
+		if l.directive == "" {
+			continue
+		}
+		if err := a.statement(l, emit); err != nil {
+			return &Error{Line: l.number, Err: err}
+		}
+	}
+	return nil
+}
+
+func (a *assembler) defineLabel(name string) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid label name %q", name)
+	}
+	if _, ok := a.labels[name]; ok {
+		return fmt.Errorf("label %q already defined", name)
+	}
+	if _, ok := a.constants[name]; ok {
+		return fmt.Errorf("label %q already defined as a constant", name)
+	}
+	a.labels[name] = uint16(a.pc)
+	return nil
+}
+
+// statement assembles a directive or instruction. On the first pass only the
+// size matters, and operands referring to labels may not be resolvable yet.
+func (a *assembler) statement(l line, emit bool) error {
+	switch l.directive {
+	case ":org":
+		if len(l.operands) != 1 {
+			return fmt.Errorf(":org takes one address")
+		}
+		// must be resolvable on the first pass, so no forward references
+		address, err := a.resolve(l.operands[0], MaxAddress, true)
+		if err != nil {
+			return err
+		}
+		a.pc = address
+		return nil
+	case ":const":
+		if emit {
+			return nil
+		}
+		if len(l.operands) != 2 {
+			return fmt.Errorf(":const takes a name and a value")
+		}
+		name := l.operands[0]
+		if !isIdentifier(name) {
+			return fmt.Errorf("invalid constant name %q", name)
+		}
+		if _, ok := a.constants[name]; ok {
+			return fmt.Errorf("constant %q already defined", name)
+		}
+		value, err := a.resolve(l.operands[1], MaxAddress, true)
+		if err != nil {
+			return err
+		}
+		a.constants[name] = value
+		return nil
+	case ":byte":
+		if len(l.operands) == 0 {
+			return fmt.Errorf(":byte takes at least one value")
+		}
+		for _, operand := range l.operands {
+			value, err := a.resolve(operand, 0xFF, emit)
+			if err != nil {
+				return err
+			}
+			if err := a.emitByte(byte(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	words, err := a.encode(l.directive, l.operands, emit)
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		if err := a.emitByte(byte(word >> 8)); err != nil {
+			return err
+		}
+		if err := a.emitByte(byte(word)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *assembler) emitByte(b byte) error {
+	if a.pc > MaxAddress {
+		return fmt.Errorf("program exceeds memory")
+	}
+	if _, ok := a.image[uint16(a.pc)]; ok {
+		return fmt.Errorf("address 0x%04X already assembled", a.pc)
+	}
+	a.image[uint16(a.pc)] = b
+	a.pc++
+	return nil
+}
+
+// resolve evaluates an expression of numbers, labels and constants joined by
+// + and -, checking the result is in the range 0 to limit. Unless strict, an
+// unknown name evaluates to 0, as labels defined later are unknown on the first pass.
+func (a *assembler) resolve(expr string, limit int, strict bool) (int, error) {
+	value, known, err := a.evaluate(expr, strict)
+	if err != nil || !known {
+		return 0, err
+	}
+	if value < 0 || value > limit {
+		return 0, fmt.Errorf("value %s (0x%X) out of range, max is 0x%X", expr, value, limit)
+	}
+	return value, nil
+}
+
+// evaluate returns the value of an expression, and whether all of its symbols are known
+func (a *assembler) evaluate(expr string, strict bool) (int, bool, error) {
+	terms := splitTerms(expr)
+	if len(terms) == 0 {
+		return 0, false, fmt.Errorf("missing operand")
+	}
+
+	total := 0
+	known := true
+	sign := 1
+	expectOperator := false
+	for _, term := range terms {
+		if term == "+" || term == "-" {
+			if term == "-" {
+				sign = -sign
+			}
+			expectOperator = false
+			continue
+		}
+		if expectOperator {
+			return 0, false, fmt.Errorf("invalid operand %q", expr)
+		}
+
+		value, ok, err := a.term(term, strict)
+		if err != nil {
+			return 0, false, err
+		}
+		known = known && ok
+		total += sign * value
+		sign = 1
+		expectOperator = true
+	}
+	if !expectOperator {
+		return 0, false, fmt.Errorf("invalid operand %q", expr)
+	}
+	return total, known, nil
+}
+
+// term returns the value of a number or symbol, and whether it is known
+func (a *assembler) term(term string, strict bool) (int, bool, error) {
+	if value, ok := parseNumber(term); ok {
+		return value, true, nil
+	}
+	if value, ok := a.constants[term]; ok {
+		return value, true, nil
+	}
+	if address, ok := a.labels[term]; ok {
+		return int(address), true, nil
+	}
+	if !isIdentifier(term) {
+		return 0, false, fmt.Errorf("invalid operand %q", term)
+	}
+	if strict {
+		return 0, false, fmt.Errorf("undefined symbol %q", term)
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,220 @@
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	t.Run("Instructions", func(t *testing.T) {
+		tests := []struct {
+			source string
+			want   uint16
+		}{
+			{"cls", 0x00E0},
+			{"ret", 0x00EE},
+			{"sys 0x123", 0x0123},
+			{"jp 0x345", 0x1345},
+			{"call 0x456", 0x2456},
+			{"se v1, 0x22", 0x3122},
+			{"sne v1, 0x22", 0x4122},
+			{"se v1, v2", 0x5120},
+			{"ld v1, 0x22", 0x6122},
+			{"add v1, 0x22", 0x7122},
+			{"ld v1, v2", 0x8120},
+			{"or v1, v2", 0x8121},
+			{"and v1, v2", 0x8122},
+			{"xor v1, v2", 0x8123},
+			{"add v1, v2", 0x8124},
+			{"sub v1, v2", 0x8125},
+			{"shr v1", 0x8116},
+			{"shr v1, v2", 0x8126},
+			{"subn v1, v2", 0x8127},
+			{"shl v1", 0x811E},
+			{"sne v1, v2", 0x9120},
+			{"ld i, 0x345", 0xA345},
+			{"jp v0, 0x345", 0xB345},
+			{"rnd v1, 0x22", 0xC122},
+			{"drw v1, v2, 5", 0xD125},
+			{"skp v1", 0xE19E},
+			{"sknp v1", 0xE1A1},
+			{"ld v1, dt", 0xF107},
+			{"ld v1, k", 0xF10A},
+			{"ld dt, v1", 0xF115},
+			{"ld st, v1", 0xF118},
+			{"add i, v1", 0xF11E},
+			{"ld f, v1", 0xF129},
+			{"ld b, v1", 0xF133},
+			{"ld [i], v1", 0xF155},
+			{"ld v1, [i]", 0xF165},
+			{"scd 4", 0x00C4},
+			{"scr", 0x00FB},
+			{"scl", 0x00FC},
+			{"exit", 0x00FD},
+			{"low", 0x00FE},
+			{"high", 0x00FF},
+			{"ld hf, v1", 0xF130},
+			{"ld r, v1", 0xF175},
+			{"ld v1, r", 0xF185},
+			{"scu 4", 0x00D4},
+			{"save v1, v2", 0x5122},
+			{"load v1, v2", 0x5123},
+			{"plane 3", 0xF301},
+			{"audio", 0xF002},
+			{"pitch v1", 0xF13A},
+			{"LD VA, #FF", 0x6AFF},
+		}
+		for _, test := range tests {
+			program, err := Assemble(test.source)
+			if err != nil {
+				t.Errorf("%q returned error: %v", test.source, err)
+				continue
+			}
+			got := uint16(program.ROM[0])<<8 | uint16(program.ROM[1])
+			if len(program.ROM) != 2 || got != test.want {
+				t.Errorf("%q should assemble to 0x%04X, got % X", test.source, test.want, program.ROM)
+			}
+		}
+	})
+
+	t.Run("Octo statements", func(t *testing.T) {
+		tests := []struct {
+			source string
+			want   []byte
+		}{
+			{"clear", []byte{0x00, 0xE0}},
+			{"return", []byte{0x00, 0xEE}},
+			{"jump 0x345", []byte{0x13, 0x45}},
+			{":call 0x456", []byte{0x24, 0x56}},
+			{"if v1 != 0x22 then", []byte{0x31, 0x22}},
+			{"if v1 == 0x22 then", []byte{0x41, 0x22}},
+			{"if v1 != v2 then", []byte{0x51, 0x20}},
+			{"if v1 == v2 then", []byte{0x91, 0x20}},
+			{"v1 := 0x22", []byte{0x61, 0x22}},
+			{"v1 += 0x22", []byte{0x71, 0x22}},
+			{"v1 := v2", []byte{0x81, 0x20}},
+			{"v1 |= v2", []byte{0x81, 0x21}},
+			{"v1 &= v2", []byte{0x81, 0x22}},
+			{"v1 ^= v2", []byte{0x81, 0x23}},
+			{"v1 += v2", []byte{0x81, 0x24}},
+			{"v1 -= v2", []byte{0x81, 0x25}},
+			{"v1 >>= v2", []byte{0x81, 0x26}},
+			{"v1 =- v2", []byte{0x81, 0x27}},
+			{"v1 <<= v2", []byte{0x81, 0x2E}},
+			{"i := 0x345", []byte{0xA3, 0x45}},
+			{"jump0 0x345", []byte{0xB3, 0x45}},
+			{"v1 := random 0x22", []byte{0xC1, 0x22}},
+			{"sprite v1 v2 5", []byte{0xD1, 0x25}},
+			{"if v1 -key then", []byte{0xE1, 0x9E}},
+			{"if v1 key then", []byte{0xE1, 0xA1}},
+			{"v1 := delay", []byte{0xF1, 0x07}},
+			{"v1 := key", []byte{0xF1, 0x0A}},
+			{"delay := v1", []byte{0xF1, 0x15}},
+			{"buzzer := v1", []byte{0xF1, 0x18}},
+			{"i += v1", []byte{0xF1, 0x1E}},
+			{"i := hex v1", []byte{0xF1, 0x29}},
+			{"bcd v1", []byte{0xF1, 0x33}},
+			{"save v1", []byte{0xF1, 0x55}},
+			{"load v1", []byte{0xF1, 0x65}},
+			{"scroll-down 4", []byte{0x00, 0xC4}},
+			{"scroll-right", []byte{0x00, 0xFB}},
+			{"scroll-left", []byte{0x00, 0xFC}},
+			{"lores", []byte{0x00, 0xFE}},
+			{"hires", []byte{0x00, 0xFF}},
+			{"i := bighex v1", []byte{0xF1, 0x30}},
+			{"saveflags v1", []byte{0xF1, 0x75}},
+			{"loadflags v1", []byte{0xF1, 0x85}},
+			{"scroll-up 4", []byte{0x00, 0xD4}},
+			{"save v1 - v2", []byte{0x51, 0x22}},
+			{"load v1 - v2", []byte{0x51, 0x23}},
+			{"i := long 0x1234", []byte{0xF0, 0x00, 0x12, 0x34}},
+			{"pitch := v1", []byte{0xF1, 0x3A}},
+			{"0xF0 0b10010000 144", []byte{0xF0, 0x90, 0x90}},
+			{": start\n\tjump start", []byte{0x12, 0x00}},
+		}
+		for _, test := range tests {
+			program, err := Assemble(test.source)
+			if err != nil {
+				t.Errorf("%q returned error: %v", test.source, err)
+				continue
+			}
+			if !bytes.Equal(program.ROM, test.want) {
+				t.Errorf("%q should assemble to % X, got % X", test.source, test.want, program.ROM)
+			}
+		}
+	})
+
+	t.Run("Labels, directives and macros", func(t *testing.T) {
+		source := `
+:const SPEED 2
+:macro move reg amount
+	add reg, amount     ; macro body
+:endm
+
+start:  ld   i, sprite
+loop:   drw  v0, v1, 5
+        move v0, SPEED
+        jp   loop
+        ld   i, long far
+sprite: :byte 0xF0, %10010000, 0b10010000, #90, 240
+:org 0x300
+far:    :byte sprite - start + 1
+`
+		program, err := Assemble(source)
+		if err != nil {
+			t.Fatalf("Assemble returned error: %v", err)
+		}
+
+		want := []byte{
+			0xA2, 0x0C, // ld i, sprite
+			0xD0, 0x15, // drw v0, v1, 5
+			0x70, 0x02, // add v0, SPEED
+			0x12, 0x02, // jp loop
+			0xF0, 0x00, 0x03, 0x00, // ld i, long far
+			0xF0, 0x90, 0x90, 0x90, 0xF0,
+		}
+		want = append(want, make([]byte, 0x300-0x211)...)
+		want = append(want, 0x0D)
+		if !bytes.Equal(program.ROM, want) {
+			t.Errorf("ROM should be\n% X\ngot\n% X", want, program.ROM)
+		}
+
+		var symbols strings.Builder
+		program.WriteSymbols(&symbols)
+		wantSymbols := "0x0200 start\n0x0202 loop\n0x020C sprite\n0x0300 far\n"
+		if symbols.String() != wantSymbols {
+			t.Errorf("Symbols should be\n%s\ngot\n%s", wantSymbols, symbols.String())
+		}
+	})
+
+	t.Run("Errors have line numbers", func(t *testing.T) {
+		tests := []struct {
+			source string
+			line   int
+		}{
+			{"cls\nfoo v1", 2},
+			{"cls\n\nld v1, 0x100", 3},
+			{"jp nowhere", 1},
+			{"a: cls\na: cls", 2},
+			{"ld v1, k, 2", 1},
+			{":macro m x\ncls", 1},
+			{":macro m x\nl: cls\n:endm\nm 1", 4},
+			{"drw v1, v2, 16", 1},
+			{"v1: cls", 1},
+			{":org 0x200\ncls\n:org 0x200\ncls", 4},
+		}
+		for _, test := range tests {
+			_, err := Assemble(test.source)
+			var asmErr *Error
+			if !errors.As(err, &asmErr) {
+				t.Errorf("%q should return *Error, got %v", test.source, err)
+				continue
+			}
+			if asmErr.Line != test.line {
+				t.Errorf("%q error should be on line %d, got %v", test.source, test.line, err)
+			}
+		}
+	})
+}
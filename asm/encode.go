@@ -0,0 +1,259 @@
+package asm
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// reserved are the operand names with a special meaning, which can't be used as symbols
+var reserved = map[string]bool{
+	"i": true, "[i]": true, "dt": true, "st": true, "k": true,
+	"f": true, "hf": true, "b": true, "r": true, "long": true,
+}
+
+func isReserved(name string) bool {
+	_, isReg := register(name)
+	return isReg || reserved[strings.ToLower(name)]
+}
+
+// register returns the index of a V0-VF register operand
+func register(operand string) (uint16, bool) {
+	operand = strings.ToLower(operand)
+	if len(operand) != 2 || operand[0] != 'v' {
+		return 0, false
+	}
+	reg, err := strconv.ParseUint(operand[1:], 16, 4)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(reg), true
+}
+
+// operands wraps the operands of an instruction for matching against forms
+type operands []string
+
+// is reports whether the operands match a pattern, where "v" matches any
+// register, "n" any value and other words must match exactly
+func (ops operands) is(pattern ...string) bool {
+	if len(ops) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		_, isReg := register(ops[i])
+		switch p {
+		case "v":
+			if !isReg {
+				return false
+			}
+		case "n":
+			if isReg || reserved[strings.ToLower(ops[i])] {
+				return false
+			}
+		default:
+			if !strings.EqualFold(ops[i], p) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reg returns the register index of operand i, which has been matched as "v"
+func (ops operands) reg(i int) uint16 {
+	reg, _ := register(ops[i])
+	return reg
+}
+
+// encode returns the opcode words for an instruction
+func (a *assembler) encode(mnemonic string, args []string, emit bool) ([]uint16, error) {
+	// cloned as the XO-CHIP long form is rewritten in place
+	ops := operands(slices.Clone(args))
+	var err error
+
+	// value resolves operand i as a number no greater than limit
+	value := func(i int, limit int) uint16 {
+		if err != nil {
+			return 0
+		}
+		var v int
+		v, err = a.resolve(ops[i], limit, emit)
+		return uint16(v)
+	}
+	// xy encodes the common 0xOXY0 register pair form
+	xy := func(base uint16) uint16 {
+		return base | ops.reg(0)<<8 | ops.reg(1)<<4
+	}
+
+	var words []uint16
+	switch mnemonic {
+	case "cls":
+		if ops.is() {
+			words = []uint16{0x00E0}
+		}
+	case "ret":
+		if ops.is() {
+			words = []uint16{0x00EE}
+		}
+	case "sys":
+		if ops.is("n") {
+			words = []uint16{value(0, 0xFFF)}
+		}
+	case "jp":
+		switch {
+		case ops.is("n"):
+			words = []uint16{0x1000 | value(0, 0xFFF)}
+		case ops.is("v0", "n"):
+			words = []uint16{0xB000 | value(1, 0xFFF)}
+		}
+	case "call":
+		if ops.is("n") {
+			words = []uint16{0x2000 | value(0, 0xFFF)}
+		}
+	case "se", "sne":
+		immediate, registers := uint16(0x3000), uint16(0x5000)
+		if mnemonic == "sne" {
+			immediate, registers = 0x4000, 0x9000
+		}
+		switch {
+		case ops.is("v", "v"):
+			words = []uint16{xy(registers)}
+		case ops.is("v", "n"):
+			words = []uint16{immediate | ops.reg(0)<<8 | value(1, 0xFF)}
+		}
+	case "ld":
+		words = a.encodeLoad(ops, value, xy)
+	case "add":
+		switch {
+		case ops.is("v", "v"):
+			words = []uint16{xy(0x8004)}
+		case ops.is("v", "n"):
+			words = []uint16{0x7000 | ops.reg(0)<<8 | value(1, 0xFF)}
+		case ops.is("i", "v"):
+			words = []uint16{0xF01E | ops.reg(1)<<8}
+		}
+	case "or", "and", "xor", "sub", "subn":
+		codes := map[string]uint16{"or": 0x8001, "and": 0x8002, "xor": 0x8003, "sub": 0x8005, "subn": 0x8007}
+		if ops.is("v", "v") {
+			words = []uint16{xy(codes[mnemonic])}
+		}
+	case "shr", "shl":
+		code := uint16(0x8006)
+		if mnemonic == "shl" {
+			code = 0x800E
+		}
+		switch {
+		case ops.is("v"):
+			words = []uint16{code | ops.reg(0)<<8 | ops.reg(0)<<4}
+		case ops.is("v", "v"):
+			words = []uint16{xy(code)}
+		}
+	case "rnd":
+		if ops.is("v", "n") {
+			words = []uint16{0xC000 | ops.reg(0)<<8 | value(1, 0xFF)}
+		}
+	case "drw":
+		if ops.is("v", "v", "n") {
+			words = []uint16{xy(0xD000) | value(2, 0xF)}
+		}
+	case "skp", "sknp":
+		code := uint16(0xE09E)
+		if mnemonic == "sknp" {
+			code = 0xE0A1
+		}
+		if ops.is("v") {
+			words = []uint16{code | ops.reg(0)<<8}
+		}
+
+	// SUPER-CHIP
+	case "scd", "scu":
+		code := uint16(0x00C0)
+		if mnemonic == "scu" {
+			code = 0x00D0 // XO-CHIP
+		}
+		if ops.is("n") {
+			words = []uint16{code | value(0, 0xF)}
+		}
+	case "scr", "scl", "exit", "low", "high":
+		codes := map[string]uint16{"scr": 0x00FB, "scl": 0x00FC, "exit": 0x00FD, "low": 0x00FE, "high": 0x00FF}
+		if ops.is() {
+			words = []uint16{codes[mnemonic]}
+		}
+
+	// XO-CHIP
+	case "save", "load":
+		code := uint16(0x5002)
+		if mnemonic == "load" {
+			code = 0x5003
+		}
+		if ops.is("v", "v") {
+			words = []uint16{xy(code)}
+		}
+	case "plane":
+		if ops.is("n") {
+			words = []uint16{0xF001 | value(0, 0x3)<<8}
+		}
+	case "audio":
+		if ops.is() {
+			words = []uint16{0xF002}
+		}
+	case "pitch":
+		if ops.is("v") {
+			words = []uint16{0xF03A | ops.reg(0)<<8}
+		}
+	default:
+		return nil, fmt.Errorf("unknown instruction %q", mnemonic)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if words == nil {
+		return nil, fmt.Errorf("invalid operands for %s: %s", mnemonic, strings.Join(args, ", "))
+	}
+	return words, nil
+}
+
+// encodeLoad returns the opcode words for the many forms of LD
+func (a *assembler) encodeLoad(ops operands, value func(int, int) uint16, xy func(uint16) uint16) []uint16 {
+	switch {
+	case ops.is("v", "v"):
+		return []uint16{xy(0x8000)}
+	case ops.is("v", "dt"):
+		return []uint16{0xF007 | ops.reg(0)<<8}
+	case ops.is("v", "k"):
+		return []uint16{0xF00A | ops.reg(0)<<8}
+	case ops.is("v", "[i]"):
+		return []uint16{0xF065 | ops.reg(0)<<8}
+	case ops.is("v", "r"):
+		return []uint16{0xF085 | ops.reg(0)<<8}
+	case ops.is("v", "n"):
+		return []uint16{0x6000 | ops.reg(0)<<8 | value(1, 0xFF)}
+	case len(ops) == 2 && strings.EqualFold(ops[0], "i") && hasPrefixFold(ops[1], "long "):
+		// XO-CHIP F000 NNNN, the address is in the following word
+		ops[1] = strings.TrimSpace(ops[1][len("long "):])
+		return []uint16{0xF000, value(1, 0xFFFF)}
+	case ops.is("i", "n"):
+		return []uint16{0xA000 | value(1, 0xFFF)}
+	case ops.is("dt", "v"):
+		return []uint16{0xF015 | ops.reg(1)<<8}
+	case ops.is("st", "v"):
+		return []uint16{0xF018 | ops.reg(1)<<8}
+	case ops.is("f", "v"):
+		return []uint16{0xF029 | ops.reg(1)<<8}
+	case ops.is("hf", "v"):
+		return []uint16{0xF030 | ops.reg(1)<<8}
+	case ops.is("b", "v"):
+		return []uint16{0xF033 | ops.reg(1)<<8}
+	case ops.is("[i]", "v"):
+		return []uint16{0xF055 | ops.reg(1)<<8}
+	case ops.is("r", "v"):
+		return []uint16{0xF075 | ops.reg(1)<<8}
+	}
+	return nil
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
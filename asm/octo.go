@@ -0,0 +1,148 @@
+package asm
+
+import "strings"
+
+// octoAliases are the Octo statements of a single word followed by operands,
+// with the mnemonic they assemble as
+var octoAliases = map[string]string{
+	"clear":        "cls",
+	"return":       "ret",
+	"scroll-down":  "scd",
+	"scroll-up":    "scu",
+	"scroll-right": "scr",
+	"scroll-left":  "scl",
+	"lores":        "low",
+	"hires":        "high",
+	"jump":         "jp",
+	":call":        "call",
+}
+
+// octoOperators maps Octo's register assignment operators to mnemonics
+var octoOperators = map[string]string{
+	"+=":  "add",
+	"|=":  "or",
+	"&=":  "and",
+	"^=":  "xor",
+	"-=":  "sub",
+	"=-":  "subn",
+	">>=": "shr",
+	"<<=": "shl",
+}
+
+// octoStatement translates a statement in Octo syntax, as written by the
+// disassembler, to its mnemonic and operands. ok is false if the fields are
+// not an Octo statement. Octo statements take a line each, and "if ... then"
+// assembles to the skip instruction, leaving the next line to be skipped.
+func octoStatement(fields []string) (directive string, operands []string, ok bool) {
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	word := strings.ToLower(fields[0])
+	args := fields[1:]
+	isReg := func(s string) bool {
+		_, ok := register(s)
+		return ok
+	}
+
+	if mnemonic, found := octoAliases[word]; found {
+		return mnemonic, args, true
+	}
+
+	switch {
+	case allNumbers(fields):
+		// values on their own are bytes of data
+		return ":byte", fields, true
+	case word == "jump0" && len(args) == 1:
+		return "jp", []string{"v0", args[0]}, true
+	case word == "sprite" && len(args) == 3:
+		return "drw", args, true
+	case word == "bcd" && len(args) == 1:
+		return "ld", []string{"b", args[0]}, true
+	case word == "saveflags" && len(args) == 1:
+		return "ld", []string{"r", args[0]}, true
+	case word == "loadflags" && len(args) == 1:
+		return "ld", []string{args[0], "r"}, true
+	case (word == "save" || word == "load") && len(args) == 1 && isReg(args[0]):
+		if word == "save" {
+			return "ld", []string{"[i]", args[0]}, true
+		}
+		return "ld", []string{args[0], "[i]"}, true
+	case (word == "save" || word == "load") && len(args) == 3 && args[1] == "-":
+		// XO-CHIP register range
+		return word, []string{args[0], args[2]}, true
+	case word == "if" && len(args) >= 3 && strings.EqualFold(args[len(args)-1], "then"):
+		return octoCondition(args[:len(args)-1])
+	case len(args) >= 2:
+		return octoAssignment(fields[0], args[0], args[1:])
+	}
+	return "", nil, false
+}
+
+// octoCondition returns the skip instruction for the condition of an "if",
+// which skips the next statement when the condition is false
+func octoCondition(cond []string) (string, []string, bool) {
+	switch {
+	case len(cond) == 2 && strings.EqualFold(cond[1], "key"):
+		return "sknp", cond[:1], true
+	case len(cond) == 2 && strings.EqualFold(cond[1], "-key"):
+		return "skp", cond[:1], true
+	case len(cond) == 3 && cond[1] == "==":
+		return "sne", []string{cond[0], cond[2]}, true
+	case len(cond) == 3 && cond[1] == "!=":
+		return "se", []string{cond[0], cond[2]}, true
+	}
+	return "", nil, false
+}
+
+// octoAssignment returns the instruction for "target operator value"
+func octoAssignment(target, operator string, value []string) (string, []string, bool) {
+	lower := strings.ToLower(target)
+	source := strings.Join(value, " ")
+	sourceWord := strings.ToLower(value[0])
+
+	if _, isReg := register(target); isReg {
+		if mnemonic, found := octoOperators[operator]; found {
+			return mnemonic, []string{target, source}, true
+		}
+		if operator != ":=" {
+			return "", nil, false
+		}
+		switch {
+		case sourceWord == "random" && len(value) == 2:
+			return "rnd", []string{target, value[1]}, true
+		case sourceWord == "delay" && len(value) == 1:
+			return "ld", []string{target, "dt"}, true
+		case sourceWord == "key" && len(value) == 1:
+			return "ld", []string{target, "k"}, true
+		}
+		return "ld", []string{target, source}, true
+	}
+
+	switch {
+	case lower == "i" && operator == "+=":
+		return "add", []string{"i", source}, true
+	case lower == "i" && operator == ":=" && sourceWord == "hex" && len(value) == 2:
+		return "ld", []string{"f", value[1]}, true
+	case lower == "i" && operator == ":=" && sourceWord == "bighex" && len(value) == 2:
+		return "ld", []string{"hf", value[1]}, true
+	case lower == "i" && operator == ":=":
+		// "long ADDRESS" is passed through for the XO-CHIP long load
+		return "ld", []string{"i", source}, true
+	case lower == "delay" && operator == ":=":
+		return "ld", []string{"dt", source}, true
+	case lower == "buzzer" && operator == ":=":
+		return "ld", []string{"st", source}, true
+	case lower == "pitch" && operator == ":=":
+		return "pitch", []string{source}, true
+	}
+	return "", nil, false
+}
+
+func allNumbers(fields []string) bool {
+	for _, field := range fields {
+		if _, ok := parseNumber(field); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,263 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxMacroDepth limits nested macro expansion, catching recursive macros
+const maxMacroDepth = 16
+
+// macro is a named block of source lines with parameters substituted on expansion
+type macro struct {
+	params []string
+	body   []string
+}
+
+// parse splits source into statements, expanding macros
+func parse(source string) ([]line, error) {
+	macros := make(map[string]*macro)
+	var current *macro
+	var currentLine int
+
+	var lines []line
+	for i, text := range strings.Split(source, "\n") {
+		number := i + 1
+		text = stripComment(text)
+		fields := strings.Fields(text)
+
+		if current != nil {
+			if len(fields) > 0 && strings.EqualFold(fields[0], ":endm") {
+				current = nil
+			} else {
+				current.body = append(current.body, text)
+			}
+			continue
+		}
+		if len(fields) > 0 && strings.EqualFold(fields[0], ":macro") {
+			if len(fields) < 2 || !isIdentifier(fields[1]) {
+				return nil, &Error{Line: number, Err: fmt.Errorf(":macro needs a name")}
+			}
+			name := strings.ToLower(fields[1])
+			if _, ok := macros[name]; ok {
+				return nil, &Error{Line: number, Err: fmt.Errorf("macro %q already defined", fields[1])}
+			}
+			current = &macro{params: fields[2:]}
+			currentLine = number
+			macros[name] = current
+			continue
+		}
+
+		expanded, err := expand(text, number, macros, 0)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, expanded...)
+	}
+	if current != nil {
+		return nil, &Error{Line: currentLine, Err: fmt.Errorf(":macro without :endm")}
+	}
+	return lines, nil
+}
+
+// expand parses a line of source, expanding it if it invokes a macro
+func expand(text string, number int, macros map[string]*macro, depth int) ([]line, error) {
+	l, err := parseLine(text, number)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := macros[l.directive]
+	if !ok {
+		return []line{l}, nil
+	}
+	if depth >= maxMacroDepth {
+		return nil, &Error{Line: number, Err: fmt.Errorf("macro %q nested too deeply", l.directive)}
+	}
+	if len(l.operands) != len(m.params) {
+		return nil, &Error{Line: number, Err: fmt.Errorf("macro %q takes %d arguments, got %d", l.directive, len(m.params), len(l.operands))}
+	}
+
+	// a label on the invoking line marks the start of the expansion
+	lines := []line{{number: number, label: l.label}}
+	for _, bodyText := range m.body {
+		for i, param := range m.params {
+			bodyText = replaceWord(bodyText, param, l.operands[i])
+		}
+		expanded, err := expand(bodyText, number, macros, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range expanded {
+			if e.label != "" {
+				return nil, &Error{Line: number, Err: fmt.Errorf("labels cannot be defined in macro %q", l.directive)}
+			}
+		}
+		lines = append(lines, expanded...)
+	}
+	return lines, nil
+}
+
+// parseLine splits a line into an optional label, a directive and its operands
+func parseLine(text string, number int) (line, error) {
+	l := line{number: number}
+	text = strings.TrimSpace(text)
+
+	first, rest, _ := strings.Cut(text, " ")
+	switch {
+	case first == ":":
+		// Octo label, ": name"
+		l.label = strings.TrimSpace(rest)
+	case strings.HasSuffix(first, ":") && !strings.HasPrefix(first, ":"):
+		l.label = strings.TrimSuffix(first, ":")
+	}
+	if l.label != "" {
+		if !isIdentifier(l.label) || isReserved(l.label) {
+			return l, &Error{Line: number, Err: fmt.Errorf("invalid label name %q", l.label)}
+		}
+		if first == ":" {
+			return l, nil
+		}
+		text = strings.TrimSpace(rest)
+	}
+	if text == "" {
+		return l, nil
+	}
+
+	if directive, operands, ok := octoStatement(strings.Fields(text)); ok {
+		l.directive, l.operands = directive, operands
+		return l, nil
+	}
+
+	directive, rest, _ := strings.Cut(text, " ")
+	l.directive = strings.ToLower(directive)
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case rest == "":
+	case strings.Contains(rest, ","):
+		for operand := range strings.SplitSeq(rest, ",") {
+			l.operands = append(l.operands, strings.TrimSpace(operand))
+		}
+	case strings.HasPrefix(l.directive, ":"):
+		// directives may separate operands with spaces
+		l.operands = splitFields(rest)
+	default:
+		l.operands = []string{rest}
+	}
+	return l, nil
+}
+
+// stripComment removes everything from a ';' onwards
+func stripComment(text string) string {
+	if i := strings.IndexByte(text, ';'); i >= 0 {
+		text = text[:i]
+	}
+	return strings.ReplaceAll(text, "\t", " ")
+}
+
+// replaceWord replaces whole identifiers equal to word in text
+func replaceWord(text, word, replacement string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		if !isIdentifierChar(rune(text[i])) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(text) && isIdentifierChar(rune(text[end])) {
+			end++
+		}
+		if text[i:end] == word {
+			out.WriteString(replacement)
+		} else {
+			out.WriteString(text[i:end])
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// splitFields splits space separated operands, keeping expressions such as
+// "end - start" together
+func splitFields(text string) []string {
+	var fields []string
+	joinNext := false
+	for _, field := range strings.Fields(text) {
+		joinPrevious := strings.HasPrefix(field, "+") || strings.HasPrefix(field, "-")
+		if len(fields) > 0 && (joinNext || joinPrevious) {
+			fields[len(fields)-1] += " " + field
+		} else {
+			fields = append(fields, field)
+		}
+		joinNext = strings.HasSuffix(field, "+") || strings.HasSuffix(field, "-")
+	}
+	return fields
+}
+
+// splitTerms splits an expression into operands and + and - operators
+func splitTerms(expr string) []string {
+	var terms []string
+	start := -1
+	for i, c := range expr {
+		switch {
+		case c == '+' || c == '-':
+			if start >= 0 {
+				terms = append(terms, strings.TrimSpace(expr[start:i]))
+				start = -1
+			}
+			terms = append(terms, string(c))
+		case unicode.IsSpace(c):
+			if start >= 0 {
+				terms = append(terms, expr[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		terms = append(terms, expr[start:])
+	}
+	return terms
+}
+
+// parseNumber parses a decimal, hex (0x or #) or binary (0b or %) number
+func parseNumber(s string) (int, bool) {
+	base := 10
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base, s = 16, s[2:]
+	case strings.HasPrefix(lower, "#"):
+		base, s = 16, s[1:]
+	case strings.HasPrefix(lower, "0b"):
+		base, s = 2, s[2:]
+	case strings.HasPrefix(lower, "%"):
+		base, s = 2, s[1:]
+	}
+	value, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int(value), true
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+	for _, c := range s {
+		if !isIdentifierChar(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierChar(c rune) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,94 @@
+// Package capture saves the CHIP-8 display as PNG screenshots and animated
+// GIF recordings. It works on the raw display, as returned by
+// chip8.Emulator.DisplayImage, scaling it up and colouring it with a palette.
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Palette is the colours of display pixels, indexed by their XO-CHIP plane
+// bits: background, plane 1, plane 2 and both planes
+type Palette [4]color.RGBA
+
+// DefaultPalette is the greys of chip8.DisplayPalette
+var DefaultPalette = Palette{
+	{0x00, 0x00, 0x00, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+}
+
+// NewPalette returns a palette from a list of 2 or 4 colours. With 2, every
+// plane uses the second colour.
+func NewPalette(colors []color.RGBA) (Palette, error) {
+	switch len(colors) {
+	case 2:
+		return Palette{colors[0], colors[1], colors[1], colors[1]}, nil
+	case 4:
+		return Palette(colors), nil
+	}
+	return Palette{}, fmt.Errorf("palettes need 2 or 4 colours, got %d", len(colors))
+}
+
+// ParsePalette parses a comma separated list of 2 or 4 #RRGGBB colours
+func ParsePalette(list string) (Palette, error) {
+	var colors []color.RGBA
+	for _, s := range strings.Split(list, ",") {
+		c, err := ParseColor(strings.TrimSpace(s))
+		if err != nil {
+			return Palette{}, err
+		}
+		colors = append(colors, c)
+	}
+	return NewPalette(colors)
+}
+
+// ParseColor parses a #RRGGBB colour
+func ParseColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour: %q", s)
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour: %q", s)
+	}
+	return color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 255}, nil
+}
+
+// colorPalette returns the palette for paletted images
+func (p Palette) colorPalette() color.Palette {
+	colors := make(color.Palette, len(p))
+	for i, c := range p {
+		colors[i] = c
+	}
+	return colors
+}
+
+// Scale returns a copy of a display image with each pixel scale pixels
+// square, coloured with palette
+func Scale(img *image.Paletted, scale int, palette Palette) *image.Paletted {
+	bounds := img.Bounds()
+	scaled := image.NewPaletted(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale), palette.colorPalette())
+	for y := range scaled.Bounds().Dy() {
+		for x := range scaled.Bounds().Dx() {
+			scaled.SetColorIndex(x, y, img.ColorIndexAt(bounds.Min.X+x/scale, bounds.Min.Y+y/scale)&0x3)
+		}
+	}
+	return scaled
+}
+
+// WritePNG writes a display image as a PNG, scaled and coloured with palette
+func WritePNG(w io.Writer, img *image.Paletted, scale int, palette Palette) error {
+	if scale <= 0 {
+		return fmt.Errorf("invalid scale: %d", scale)
+	}
+	return png.Encode(w, Scale(img, scale, palette))
+}
@@ -0,0 +1,172 @@
+package capture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// displayImage returns a display image of the given resolution with pixel (x, 0) set to plane
+func displayImage(highRes bool, x int, plane uint8) *image.Paletted {
+	emu := chip8.New()
+	emu.HighRes = highRes
+	emu.Display[x] = plane
+	return emu.DisplayImage()
+}
+
+func TestParsePalette(t *testing.T) {
+	t.Run("Two colours", func(t *testing.T) {
+		p, err := ParsePalette("#000000, #FF8000")
+		if err != nil {
+			t.Fatalf("ParsePalette returned error: %v", err)
+		}
+		orange := color.RGBA{0xFF, 0x80, 0x00, 0xFF}
+		want := Palette{{0, 0, 0, 0xFF}, orange, orange, orange}
+		if p != want {
+			t.Errorf("Expected %v, got %v", want, p)
+		}
+	})
+
+	t.Run("Four colours", func(t *testing.T) {
+		p, err := ParsePalette("#000000,#111111,#222222,#333333")
+		if err != nil {
+			t.Fatalf("ParsePalette returned error: %v", err)
+		}
+		if p[3] != (color.RGBA{0x33, 0x33, 0x33, 0xFF}) {
+			t.Errorf("Expected both planes colour #333333, got %v", p[3])
+		}
+	})
+
+	for _, list := range []string{"#000000", "#000000,#111111,#222222", "#000000,red", "#00000G,#111111"} {
+		if _, err := ParsePalette(list); err == nil {
+			t.Errorf("Expected error parsing %q", list)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	red := color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+	palette := Palette{{0, 0, 0, 0xFF}, red, red, red}
+
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, displayImage(false, 1, 1), 4, palette); err != nil {
+		t.Fatalf("WritePNG returned error: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode returned error: %v", err)
+	}
+
+	if size := img.Bounds().Size(); size != image.Pt(chip8.LowResWidth*4, chip8.LowResHeight*4) {
+		t.Fatalf("Expected size %dx%d, got %v", chip8.LowResWidth*4, chip8.LowResHeight*4, size)
+	}
+	for _, p := range []struct {
+		x, y int
+		want color.RGBA
+	}{{3, 0, palette[0]}, {4, 0, red}, {7, 3, red}, {8, 0, palette[0]}, {4, 4, palette[0]}} {
+		if got := color.RGBAModel.Convert(img.At(p.x, p.y)); got != p.want {
+			t.Errorf("Pixel (%d, %d): expected %v, got %v", p.x, p.y, p.want, got)
+		}
+	}
+
+	if err := WritePNG(&buf, displayImage(false, 0, 0), 0, palette); err == nil {
+		t.Errorf("Expected error for scale 0")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("Identical frames are merged", func(t *testing.T) {
+		r := NewRecorder()
+		for frame := range 120 {
+			// the pixel moves once a second
+			r.AddFrame(displayImage(false, frame/60, 1))
+		}
+		if r.Frames() != 120 {
+			t.Errorf("Expected 120 frames recorded, got %d", r.Frames())
+		}
+		anim := writeGIF(t, r, 1)
+		if want := []int{100, 100}; !slices.Equal(anim.Delay, want) {
+			t.Errorf("Expected delays %v, got %v", want, anim.Delay)
+		}
+	})
+
+	t.Run("Delays add up to the recording length", func(t *testing.T) {
+		r := NewRecorder()
+		for frame := range 90 {
+			// a new image every 2 frames, which GIF delays can't time exactly
+			r.AddFrame(displayImage(false, frame/2, 1))
+		}
+		anim := writeGIF(t, r, 1)
+		total := 0
+		for _, delay := range anim.Delay {
+			if delay < minGIFDelay {
+				t.Errorf("Delay %d is shorter than the minimum %d", delay, minGIFDelay)
+			}
+			total += delay
+		}
+		if total != 150 {
+			t.Errorf("Expected delays totalling 150, got %d", total)
+		}
+	})
+
+	t.Run("Flicker is not slowed down", func(t *testing.T) {
+		r := NewRecorder()
+		for frame := range 60 {
+			r.AddFrame(displayImage(false, 0, uint8(frame%2)))
+		}
+		anim := writeGIF(t, r, 1)
+		total := 0
+		for _, delay := range anim.Delay {
+			if delay < minGIFDelay {
+				t.Errorf("Delay %d is shorter than the minimum %d", delay, minGIFDelay)
+			}
+			total += delay
+		}
+		if total != 100 {
+			t.Errorf("Expected delays totalling 100, got %d", total)
+		}
+	})
+
+	t.Run("Mixed resolutions", func(t *testing.T) {
+		r := NewRecorder()
+		r.AddFrame(displayImage(false, 0, 1))
+		r.AddFrame(displayImage(false, 0, 1))
+		r.AddFrame(displayImage(true, 0, 1))
+		anim := writeGIF(t, r, 2)
+		if anim.Config.Width != chip8.HighResWidth*2 || anim.Config.Height != chip8.HighResHeight*2 {
+			t.Errorf("Expected size %dx%d, got %dx%d", chip8.HighResWidth*2, chip8.HighResHeight*2, anim.Config.Width, anim.Config.Height)
+		}
+		for i, img := range anim.Image {
+			if img.Bounds().Dx() != chip8.HighResWidth*2 {
+				t.Errorf("Frame %d: expected width %d, got %d", i, chip8.HighResWidth*2, img.Bounds().Dx())
+			}
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewRecorder().WriteGIF(&buf, 1, DefaultPalette); err == nil {
+			t.Errorf("Expected error writing an empty recording")
+		}
+	})
+}
+
+// writeGIF writes and decodes a recording
+func writeGIF(t *testing.T, r *Recorder, scale int) *gif.GIF {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.WriteGIF(&buf, scale, DefaultPalette); err != nil {
+		t.Fatalf("WriteGIF returned error: %v", err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("gif.DecodeAll returned error: %v", err)
+	}
+	return anim
+}
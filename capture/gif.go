@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// minGIFDelay is the shortest frame delay, in hundredths of a second, that
+// browsers play as given rather than slowing down
+const minGIFDelay = 2
+
+// Recorder records the display once per 60Hz frame, to be written as an
+// animated GIF. Runs of identical frames are stored once.
+type Recorder struct {
+	frames []recordedFrame
+	count  int // 60Hz frames recorded
+}
+
+// recordedFrame is a display image and the 60Hz frame it was first shown in
+type recordedFrame struct {
+	img   *image.Paletted
+	start int
+}
+
+// NewRecorder returns an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Frames returns the number of 60Hz frames recorded
+func (r *Recorder) Frames() int {
+	return r.count
+}
+
+// AddFrame records the display for a 60Hz frame. The image is kept, so must
+// not be changed afterwards.
+func (r *Recorder) AddFrame(img *image.Paletted) {
+	defer func() { r.count++ }()
+
+	if n := len(r.frames); n > 0 {
+		last := &r.frames[n-1]
+		if sameImage(last.img, img) {
+			return
+		}
+		// GIF delays are whole hundredths of a second, so frames shorter than
+		// browsers will play are replaced by the next
+		if frameTime(r.count)-frameTime(last.start) < minGIFDelay {
+			if n > 1 && sameImage(r.frames[n-2].img, img) {
+				r.frames = r.frames[:n-1]
+			} else {
+				last.img = img
+			}
+			return
+		}
+	}
+	r.frames = append(r.frames, recordedFrame{img, r.count})
+}
+
+// frameTime returns the start of a 60Hz frame in hundredths of a second
+func frameTime(frame int) int {
+	return (frame*100 + chip8.FramesPerSecond/2) / chip8.FramesPerSecond
+}
+
+// sameImage reports whether two display images have the same size and pixels
+func sameImage(a, b *image.Paletted) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+	for y := range a.Bounds().Dy() {
+		for x := range a.Bounds().Dx() {
+			if a.ColorIndexAt(a.Bounds().Min.X+x, a.Bounds().Min.Y+y)&0x3 != b.ColorIndexAt(b.Bounds().Min.X+x, b.Bounds().Min.Y+y)&0x3 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// WriteGIF writes the recording as a looping animated GIF, scaled and
+// coloured with palette. Low resolution frames of a recording which also has
+// high resolution ones are scaled up to the same size.
+func (r *Recorder) WriteGIF(w io.Writer, scale int, palette Palette) error {
+	if len(r.frames) == 0 {
+		return errors.New("no frames recorded")
+	}
+	if scale <= 0 {
+		return fmt.Errorf("invalid scale: %d", scale)
+	}
+
+	width := 0
+	for _, f := range r.frames {
+		width = max(width, f.img.Bounds().Dx())
+	}
+	var size image.Point
+	anim := &gif.GIF{}
+	for i, f := range r.frames {
+		end := r.count
+		if i+1 < len(r.frames) {
+			end = r.frames[i+1].start
+		}
+		img := Scale(f.img, scale*width/f.img.Bounds().Dx(), palette)
+		size.X = max(size.X, img.Bounds().Dx())
+		size.Y = max(size.Y, img.Bounds().Dy())
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, frameTime(end)-frameTime(f.start))
+	}
+	anim.Config = image.Config{ColorModel: palette.colorPalette(), Width: size.X, Height: size.Y}
+	return gif.EncodeAll(w, anim)
+}
@@ -1,15 +1,32 @@
 package chip8
 
 import (
+	"crypto/sha1"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"time"
 )
 
 const (
-	DisplayWidth  = 64
-	DisplayHeight = 32
+	// Display resolutions. CHIP-8 always uses the low resolution, SUPER-CHIP
+	// programs can switch to high resolution with 00FF.
+	LowResWidth    = 64
+	LowResHeight   = 32
+	HighResWidth   = 128
+	HighResHeight  = 64
+	RPLFlagCount   = 8  // Number of SUPER-CHIP RPL user flags (FX75/FX85)
+	BigSpriteWidth = 16 // Width and height of a DXY0 sprite in SUPER-CHIP
+
+	// XO-CHIP extensions
+	PlaneCount         = 2  // Number of display bit-planes
+	XOChipRPLFlagCount = 16 // Number of XO-CHIP RPL user flags (FX75/FX85)
+	AudioPatternSize   = 16 // Size in bytes of the XO-CHIP audio pattern buffer (F002)
+	DefaultPitch       = 64 // Pitch register value for a 4000Hz pattern playback rate
+	XOChipMemorySize   = 0x10000
+	DefaultMemorySize  = 0x1000
 
 	// Constants for memory addresses and limits
 	ProgramStartAddress = 0x200 // Starting address for most CHIP-8 programs
@@ -20,14 +37,26 @@ const (
 // Emulator represents a CHIP-8 emulator with all necessary components
 // for executing CHIP-8 programs.
 type Emulator struct {
-	// 4 kilobytes of RAM
+	// 4 kilobytes of RAM, or 64 kilobytes for XO-CHIP
+	// Only the first MemorySize() bytes are addressable
 	// Note: 0x000-0x1FF reserved for interpreter in early versions, so
 	// start accessible RAM from 0x200 to support older ROMs
-	Memory [4096]byte
+	Memory [XOChipMemorySize]byte
 
 	// Display
-	// 64x32 - pixels can be on/off
-	Display [DisplayWidth * DisplayHeight]bool
+	// 64x32 or 128x64 - each pixel holds one bit per plane (bit 0 = plane 1,
+	// bit 1 = plane 2), only XO-CHIP programs draw to plane 2
+	// Rows are DisplayWidth() pixels wide, so only the first
+	// DisplayWidth()*DisplayHeight() entries are in use
+	Display [HighResWidth * HighResHeight]byte
+
+	// Selected display planes (XO-CHIP)
+	// Bitmask set by FN01, drawing, clearing and scrolling only affect selected planes
+	Planes byte
+
+	// High resolution mode (SUPER-CHIP)
+	// Switched with 00FF (on) and 00FE (off)
+	HighRes bool
 
 	// Program Counter
 	// Points to current instruction in memory
@@ -53,55 +82,130 @@ type Emulator struct {
 
 	timerDelta time.Duration // timer to track time since last timer update
 
+	waitingForVBlank bool // display wait quirk, execution paused until next timer tick
+
+	// COSMAC VIP timing, see timing.go
+	instructionCycles int  // machine cycles taken by the last instruction
+	vipFrameCycles    int  // machine cycles used by the interpreter in the current frame
+	vblank            bool // set when the VIP clock runs the vertical blank interrupt
+
+	cycle uint64 // number of Step calls since Reset, see Cycle
+	frame uint64 // number of frames run since Reset, see Frame
+
+	// Progress through a frame run by StepFrame
+	inFrame    bool // a frame has started and not yet ended
+	frameSteps int  // instructions run in the frame
+
+	displayChanged bool // set when the display is drawn to, reported by RunFrame
+
 	// Registers
 	// General-purpose variable registers
 	Registers [RegisterCount]byte
 
 	// Keypad - 16 keys (0-F)
+	// Updated from queued input before each instruction, see keys.go
 	Keypad [16]bool
+	input  inputQueue
+	// Key pressed while FX0A waits, which completes when it is released, or -1
+	waitKey int
+
+	// RPL user flags (SUPER-CHIP)
+	// Saved and restored with FX75/FX85, these survive Reset like the HP48 flags did
+	// SUPER-CHIP has 8 flags, XO-CHIP has 16
+	RPLFlags [XOChipRPLFlagCount]byte
+
+	// Audio pattern buffer (XO-CHIP)
+	// 128 1-bit samples loaded by F002, played while the sound timer is active
+	AudioPattern [AudioPatternSize]byte
+
+	// Pitch register (XO-CHIP)
+	// Set by FX3A, pattern playback rate is 4000*2^((Pitch-64)/48) Hz
+	Pitch byte
 
 	// Config
-	Config *EmulatorConfig
+	Config     *EmulatorConfig
+	baseConfig EmulatorConfig // config ROM database entries are applied over, see BaseConfig
 
 	// Random number generator
-	rng *rand.Rand
-}
+	rng    *rand.Rand
+	rngSrc *rngSource // source of rng, tracks position for save states
 
-// EmulatorConfig contains configuration options for the CHIP-8 emulator.
-type EmulatorConfig struct {
-	LegacyShift     bool  // chip-48 and super-chip onwards is modern
-	LegacyJump      bool  // chip-48 and super-chip onwards is modern
-	LegacyStoreLoad bool  // legacy mode for older games from 1970s and 1980s
-	randSeed        int64 // seed for rand
+	romHash [sha1.Size]byte // SHA-1 of the loaded ROM, identifies the game in save states
+
+	// ROM settings database, see romdb.go
+	romDatabase *ROMDatabase
+	romInfo     *ROMInfo // entry matched by the loaded ROM
+
+	// Movie recording and playback, see movie.go
+	recording *movieRecorder
+	playback  *moviePlayer
+
+	// Memory access hooks, see memory.go
+	memoryObserver MemoryObserver
+	watchpoints    map[uint16]WatchKind
+	watchHit       *WatchpointError // first watchpoint hit by the current instruction
+	instructionPC  uint16           // address of the instruction being executed
+
+	// Execution trace hook, see trace.go
+	tracer Tracer
 }
 
-// EmulatorOption is a function that configs an Emulator
-type EmulatorOption func(*Emulator)
+// ErrProgramExit is returned (wrapped) by Step when the program executes
+// the SUPER-CHIP 00FD exit instruction.
+var ErrProgramExit = errors.New("program exited")
 
-// WithLegacyShift configures the emulator to use legacy shift behavior
-// When enabled, shift operations will copy VY to VX before shifting
-func WithLegacyShift(enabled bool) EmulatorOption {
-	return func(e *Emulator) {
-		e.Config.LegacyShift = enabled
+// Variant selects which instruction set extensions the emulator decodes.
+type Variant int
+
+const (
+	VariantChip8     Variant = iota // original CHIP-8 instruction set
+	VariantSuperChip                // SUPER-CHIP 1.1 (hi-res, scrolling, big font, RPL flags)
+	VariantXOChip                   // XO-CHIP (64KB memory, bit-planes, audio patterns), a superset of SUPER-CHIP
+)
+
+// String returns the short name of the variant, as accepted by ParseVariant
+func (v Variant) String() string {
+	switch v {
+	case VariantChip8:
+		return "chip8"
+	case VariantSuperChip:
+		return "schip"
+	case VariantXOChip:
+		return "xochip"
 	}
+	return fmt.Sprintf("Variant(%d)", int(v))
 }
 
-// WithLegacyJump configures the emulator to use legacy jump behavior
-// When enabled, the BNNN instruction (jump with offset) will jump to
-// address NNN + V0 instead of NNN + VX in modern implementations
-func WithLegacyJump(enabled bool) EmulatorOption {
-	return func(e *Emulator) {
-		e.Config.LegacyJump = enabled
+// ParseVariant returns the variant with the given short name
+func ParseVariant(name string) (Variant, error) {
+	for _, v := range []Variant{VariantChip8, VariantSuperChip, VariantXOChip} {
+		if v.String() == name {
+			return v, nil
+		}
 	}
+	return 0, fmt.Errorf("unknown variant: %q", name)
+}
+
+// EmulatorConfig contains configuration options for the CHIP-8 emulator.
+type EmulatorConfig struct {
+	Platform Platform // platform preset the variant and quirks were taken from
+	Variant  Variant  // instruction set extensions to decode
+	Quirks   Quirks   // behaviour differences between implementations
+	Timing   Timing   // how execution is paced against the timers
+	randSeed int64    // seed for rand
+
+	InstructionsPerFrame int // instructions executed by each RunFrame
 }
 
-// WithLegacyStoreLoad configures the emulator to use legacy store/load behavior
-// When enabled, the FX55/FX65 instructions will increment the I register
-// as they store/load each register, which was the behavior in older CHIP-8
-// implementations from the 1970s and 1980s
-func WithLegacyStoreLoad(enabled bool) EmulatorOption {
+// EmulatorOption is a function that configs an Emulator
+type EmulatorOption func(*Emulator)
+
+// WithVariant configures which instruction set the emulator decodes.
+// VariantSuperChip enables the SUPER-CHIP 1.1 opcodes and 128x64 mode,
+// VariantXOChip additionally enables 64KB memory, bit-planes and audio patterns
+func WithVariant(variant Variant) EmulatorOption {
 	return func(e *Emulator) {
-		e.Config.LegacyStoreLoad = enabled
+		e.Config.Variant = variant
 	}
 }
 
@@ -110,25 +214,24 @@ func WithLegacyStoreLoad(enabled bool) EmulatorOption {
 func WithSeed(seed int64) EmulatorOption {
 	return func(e *Emulator) {
 		e.Config.randSeed = seed
-		e.rng.Seed(seed)
+		e.rngSrc.Seed(seed)
 	}
 }
 
 // New creates and initializes a new CHIP-8 emulator with the provided options.
 func New(options ...EmulatorOption) *Emulator {
+	seed := time.Now().UnixNano()
 	e := &Emulator{
-		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
-		Config: &EmulatorConfig{
-			// Default configuration values
-			LegacyShift:     false,
-			LegacyJump:      true,
-			LegacyStoreLoad: false,
-		},
+		rngSrc: newRNGSource(seed),
+		Config: &EmulatorConfig{randSeed: seed, InstructionsPerFrame: DefaultInstructionsPerFrame},
 	}
+	e.rng = rand.New(e.rngSrc)
+	e.Config.SetPlatform(DefaultPlatform)
 
 	for _, option := range options {
 		option(e)
 	}
+	e.baseConfig = *e.Config
 
 	e.Reset()
 	return e
@@ -143,77 +246,109 @@ func (e *Emulator) LoadROMFromPath(romPath string) error {
 		return fmt.Errorf("failed to read ROM file: %w", err)
 	}
 
-	if len(romData) > len(e.Memory)-ProgramStartAddress {
-		return fmt.Errorf("ROM too large: %dB (max is %dB)", len(romData), len(e.Memory)-ProgramStartAddress)
-	}
-
-	// Load ROM into memory starting at 0x200
-	copy(e.Memory[ProgramStartAddress:], romData)
-	return nil
+	return e.LoadROMFromData(romData)
 }
 
 // LoadROMFromData loads a CHIP-8 ROM from a byte slice into the emulator's memory
 // starting at address ProgramStartAddress (usually 0x200). Returns an error if the ROM
-// is too large to fit in memory.
+// is too large to fit in memory. With WithROMDatabase, the config is replaced by
+// BaseConfig with the settings of any matching database entry applied, and the
+// size is checked against the memory they give. A rejected ROM leaves the
+// config unchanged.
 func (e *Emulator) LoadROMFromData(romData []byte) error {
-	if len(romData) > len(e.Memory)-ProgramStartAddress {
-		return fmt.Errorf("ROM too large: %dB (max is %dB)", len(romData), len(e.Memory)-ProgramStartAddress)
+	hash := sha1.Sum(romData)
+	config, info := e.romConfig(hash)
+	if maxSize := config.memorySize() - ProgramStartAddress; len(romData) > maxSize {
+		return fmt.Errorf("ROM too large: %dB (max is %dB)", len(romData), maxSize)
 	}
+	*e.Config = config
+	e.romInfo = info
 
+	// Load ROM into memory starting at 0x200
 	copy(e.Memory[ProgramStartAddress:], romData)
+	e.romHash = hash
 	return nil
 }
 
-// Run starts the emulator's main execution loop in a separate goroutine.
-// It continuously fetches, decodes, and executes instructions at the rate
-// specified by the emulator's clock speed.
-func (e *Emulator) Run(cyclesPerSecond int) <-chan error {
-	clock := time.NewTicker(time.Second / time.Duration(cyclesPerSecond))
-
-	errCh := make(chan error, 1)
-
-	go func() {
-		for range clock.C {
-			if err := e.Step(time.Second / time.Duration(cyclesPerSecond)); err != nil {
-				errCh <- err
-				return
-			}
-		}
-	}()
+// MemorySize returns the number of addressable bytes of memory for the configured variant
+func (e *Emulator) MemorySize() int {
+	return e.Config.memorySize()
+}
 
-	return errCh
+func (c *EmulatorConfig) memorySize() int {
+	if c.Variant == VariantXOChip {
+		return XOChipMemorySize
+	}
+	return DefaultMemorySize
 }
 
-func validateReadAddress(address uint16, offset uint16) error {
-	if address+offset > 0xFFF {
+func (e *Emulator) validateReadAddress(address uint16, offset uint16) error {
+	if int(address)+int(offset) >= e.MemorySize() {
 		return fmt.Errorf("memory access out of bounds: (0x%04X)", address)
 	}
 	return nil
 }
 
-func validateWriteAddress(address uint16, offset uint16) error {
+func (e *Emulator) validateWriteAddress(address uint16, offset uint16) error {
 	if address < ProgramStartAddress {
 		return fmt.Errorf("memory access in reserved space: (0x%04X)", address)
 	}
-	return validateReadAddress(address, offset)
+	return e.validateReadAddress(address, offset)
 }
 
 // Step executes a single instruction cycle of the emulator.
 // This includes fetching the next opcode, decoding it, and executing
-// the corresponding operation.
+// the corresponding operation. If the instruction hit a watchpoint, it
+// completes and a *WatchpointError is returned.
+//
+// With TimingVIP, deltaTime is ignored and the timers advance by the
+// instruction's COSMAC VIP machine cycles, see InstructionCycles.
 func (e *Emulator) Step(deltaTime time.Duration) error {
-	if err := validateReadAddress(e.PC, 1); err != nil {
+	e.cycle++
+	e.instructionCycles = 0
+	e.applyInput()
+
+	if e.waitingForVBlank {
+		if e.Config.Timing == TimingVIP {
+			e.waitForVIPVBlank()
+		} else {
+			e.UpdateTimers(deltaTime)
+		}
+		return nil
+	}
+
+	if err := e.validateReadAddress(e.PC, 1); err != nil {
 		return fmt.Errorf("failed to read opcode: %w", err)
 	}
-	opcode := uint16(e.Memory[e.PC])<<8 | uint16(e.Memory[e.PC+1])
+	e.instructionPC = e.PC
+	e.watchHit = nil
+	opcode := e.readOpcode(e.PC)
 	e.PC += 2
 
-	e.UpdateTimers(deltaTime)
+	if e.Config.Timing != TimingVIP {
+		e.UpdateTimers(deltaTime)
+	}
 
+	cycles := e.vipCycles(opcode)
 	err := e.executeOpcode(opcode)
+	if isSkip(opcode) && e.PC != e.instructionPC+2 {
+		cycles += vipSkipCycles
+	}
+	e.instructionCycles = cycles
+	if e.Config.Timing == TimingVIP {
+		e.runVIPClock(cycles)
+	}
+	if e.tracer != nil && (err == nil || errors.Is(err, ErrProgramExit)) {
+		e.trace(opcode)
+	}
 	if err != nil {
 		return fmt.Errorf("error executing opcode: %w", err)
 	}
+	if e.watchHit != nil {
+		hit := e.watchHit
+		e.watchHit = nil
+		return hit
+	}
 	return nil
 }
 
@@ -226,11 +361,11 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 
 	switch opcode & 0xF000 {
 	case 0x0000:
-		switch opcode {
-		case 0x00E0:
+		switch {
+		case opcode == 0x00E0:
 			// 00E0: Clear screen
 			e.clearDisplay()
-		case 0x00EE:
+		case opcode == 0x00EE:
 			// Return from subroutine
 			if e.SP == 0 {
 				// stack is empty
@@ -240,6 +375,30 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 			e.SP--
 			// Set PC to the address from the stack
 			e.PC = e.Stack[e.SP]
+		case !e.superChip():
+			// 0NNN: Call machine code routine, not supported so ignored
+		case opcode&0xFFF0 == 0x00C0:
+			// 00CN: Scroll display down N pixels (SUPER-CHIP)
+			e.scrollVertical(e.scrollDistance(int(n)))
+		case opcode&0xFFF0 == 0x00D0 && e.xoChip():
+			// 00DN: Scroll display up N pixels (XO-CHIP)
+			e.scrollVertical(-e.scrollDistance(int(n)))
+		case opcode == 0x00FB:
+			// 00FB: Scroll display right 4 pixels (SUPER-CHIP)
+			e.scrollHorizontal(e.scrollDistance(4))
+		case opcode == 0x00FC:
+			// 00FC: Scroll display left 4 pixels (SUPER-CHIP)
+			e.scrollHorizontal(-e.scrollDistance(4))
+		case opcode == 0x00FD:
+			// 00FD: Exit interpreter (SUPER-CHIP)
+			e.PC -= 2 // stay on the exit instruction if stepped again
+			return ErrProgramExit
+		case opcode == 0x00FE:
+			// 00FE: Switch to low resolution (SUPER-CHIP)
+			e.setHighRes(false)
+		case opcode == 0x00FF:
+			// 00FF: Switch to high resolution (SUPER-CHIP)
+			e.setHighRes(true)
 		}
 	case 0x1000:
 		// 1NNN: Jump
@@ -258,18 +417,39 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 	case 0x3000:
 		// 3XNN: Skip next instruction if VX equals NN
 		if e.Registers[x] == byte(nn) {
-			e.PC += 2
+			e.skipNextInstruction()
 		}
 	case 0x4000:
 		// 4XNN: Skip next instruction if VX not equal to NN
 		if e.Registers[x] != byte(nn) {
-			e.PC += 2
+			e.skipNextInstruction()
 		}
 	case 0x5000:
-		// 5XY0: Skip next instruction if VX equal to VY
-		if n == 0 && e.Registers[x] == e.Registers[y] {
-			e.PC += 2
-		} else if n != 0 {
+		switch {
+		case n == 0:
+			// 5XY0: Skip next instruction if VX equal to VY
+			if e.Registers[x] == e.Registers[y] {
+				e.skipNextInstruction()
+			}
+		case n == 2 && e.xoChip():
+			// 5XY2: Store VX-VY at address I, I is unchanged (XO-CHIP)
+			count := uint16(max(x, y) - min(x, y))
+			if err := e.validateWriteAddress(e.I, count); err != nil {
+				return fmt.Errorf("failed to store register range: %w", err)
+			}
+			for i, reg := range registerRange(x, y) {
+				e.writeMemory(e.I+uint16(i), e.Registers[reg])
+			}
+		case n == 3 && e.xoChip():
+			// 5XY3: Load VX-VY from address I, I is unchanged (XO-CHIP)
+			count := uint16(max(x, y) - min(x, y))
+			if err := e.validateReadAddress(e.I, count); err != nil {
+				return fmt.Errorf("failed to load register range: %w", err)
+			}
+			for i, reg := range registerRange(x, y) {
+				e.Registers[reg] = e.readMemory(e.I+uint16(i), AccessRead)
+			}
+		default:
 			return fmt.Errorf("unknown opcode: 0x%X", opcode)
 		}
 	case 0x6000:
@@ -286,12 +466,15 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 		case 0x1:
 			// 8XY1: Set VX to bitwise VX OR VY
 			e.Registers[x] |= e.Registers[y]
+			e.resetVF()
 		case 0x2:
 			// 8XY2: Set VX to bitwise VX AND VY
 			e.Registers[x] &= e.Registers[y]
+			e.resetVF()
 		case 0x3:
 			// 8XY3: Set VX to bitwise VX XOR VY
 			e.Registers[x] ^= e.Registers[y]
+			e.resetVF()
 		case 0x4:
 			// 8XY4: Add VY to VX with carry
 			sum := uint16(e.Registers[x]) + uint16(e.Registers[y])
@@ -312,7 +495,7 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 		case 0x6:
 			// 8XY6: legacy - Set VX to VY shifted 1 bit to right, VF is set to bit shifted out
 			//       modern - Shift VX 1 bit to right, VF is set to bit shifted out
-			if e.Config.LegacyShift {
+			if e.Config.Quirks.ShiftUsesVY {
 				e.Registers[x] = e.Registers[y]
 			}
 			// Check rightmost bit before shift
@@ -329,7 +512,7 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 		case 0xE:
 			// 8XYE: legacy - Set VX to VY shifted 1 bit to left, VF is set to bit shifted out
 			//       modern - Shift VX 1 bit to left, VF is set to bit shifted out
-			if e.Config.LegacyShift {
+			if e.Config.Quirks.ShiftUsesVY {
 				e.Registers[x] = e.Registers[y]
 			}
 			// Check leftmost bit before shift
@@ -341,7 +524,7 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 	case 0x9000:
 		// 9XY0: Skip next instruction if VX not equal to VY
 		if n == 0 && e.Registers[x] != e.Registers[y] {
-			e.PC += 2
+			e.skipNextInstruction()
 		} else if n != 0 {
 			return fmt.Errorf("unknown opcode: 0x%X", opcode)
 		}
@@ -350,7 +533,7 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 		e.I = nnn
 	case 0xB000:
 		// BNNN: Jump with offset
-		if e.Config.LegacyJump {
+		if e.Config.Quirks.JumpUsesV0 {
 			// jump to address NNN + value in V0
 			e.PC = (nnn + uint16(e.Registers[0])) & 0x0FFF
 		} else {
@@ -362,34 +545,80 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 		e.Registers[x] = byte(e.rng.Int()) & nn
 	case 0xD000:
 		// DXYN: Display
-		e.drawSprite(int(e.Registers[x]), int(e.Registers[y]), int(n))
+		// DXY0: Display 16x16 sprite (SUPER-CHIP), 8x16 in low resolution on the HP48
+		if n == 0 && e.superChip() {
+			width := BigSpriteWidth
+			if !e.HighRes && e.Config.Quirks.LowResBigSprite8x16 {
+				width = 8
+			}
+			e.drawSprite(int(e.Registers[x]), int(e.Registers[y]), width, BigSpriteWidth)
+		} else {
+			e.drawSprite(int(e.Registers[x]), int(e.Registers[y]), 8, int(n))
+		}
+		// Original interpreter waited for the vertical blank interrupt before drawing,
+		// limiting programs to one sprite per frame
+		e.waitingForVBlank = e.Config.Quirks.DisplayWait
 	case 0xE000:
 		switch nn {
 		case 0x9E:
 			// EX9E Skip if key X is pressed
-			if e.Keypad[e.Registers[x]] {
-				e.PC += 2
+			if e.Keypad[e.Registers[x]&0x0F] {
+				e.skipNextInstruction()
 			}
 		case 0xA1:
 			// EXA1 Skip if key X is not pressed
-			if !e.Keypad[e.Registers[x]] {
-				e.PC += 2
+			if !e.Keypad[e.Registers[x]&0x0F] {
+				e.skipNextInstruction()
 			}
 		}
 	case 0xF000:
 		switch nn {
+		case 0x00:
+			// F000 NNNN: Set I to the 16-bit address in the following two bytes (XO-CHIP)
+			if x != 0 || !e.xoChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			if err := e.validateReadAddress(e.PC, 1); err != nil {
+				return fmt.Errorf("failed to read long address: %w", err)
+			}
+			e.I = e.readOpcode(e.PC)
+			e.PC += 2
+		case 0x01:
+			// FN01: Select display planes N (XO-CHIP)
+			if !e.xoChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			e.Planes = x & 0x3
+		case 0x02:
+			// F002: Load 16 byte audio pattern from address I (XO-CHIP)
+			if x != 0 || !e.xoChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			if err := e.validateReadAddress(e.I, AudioPatternSize-1); err != nil {
+				return fmt.Errorf("failed to load audio pattern: %w", err)
+			}
+			for i := range uint16(AudioPatternSize) {
+				e.AudioPattern[i] = e.readMemory(e.I+i, AccessRead)
+			}
 		case 0x07:
 			// FX07 Set VX to current value of delay timer
 			e.Registers[x] = e.DelayTimer
 		case 0x0A:
-			// 0xFX0A Wait for a key press and set VX to key
-			e.PC -= 2 // If no key pressed we need to repeat instruction
-			for key, pressed := range e.Keypad {
-				if pressed {
-					e.Registers[x] = byte(key)
-					e.PC += 2
-					break
+			// 0xFX0A Wait for a key to be pressed and released, and set VX to the key
+			// The original interpreter waited for the release, so a held key
+			// doesn't satisfy several FX0A in a row
+			e.PC -= 2 // repeat the instruction until the key is released
+			if e.waitKey < 0 {
+				for key, pressed := range e.Keypad {
+					if pressed {
+						e.waitKey = key
+						break
+					}
 				}
+			} else if !e.Keypad[e.waitKey] {
+				e.Registers[x] = byte(e.waitKey)
+				e.waitKey = -1
+				e.PC += 2
 			}
 		case 0x15:
 			// 0xFX15 Set delay timer to VX
@@ -399,48 +628,79 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 			e.SoundTimer = e.Registers[x]
 		case 0x1E:
 			// 0xFX1E Add VX to I
-			// Note: this didn't affect VF on overflow (I > 0x0FFF) in original chip-8, but did in some later versions
-			// At least one known game requires it, but unlikely that anything relies on it not happening so
-			// implementing it this way
-			if (e.I+uint16(e.Registers[x]))&0xF000 != 0 {
-				e.Registers[0xF] = 1
-			} else {
-				e.Registers[0xF] = 0
+			// Note: this didn't affect VF on overflow (I > 0x0FFF) in original chip-8, but did in the
+			// Amiga interpreter, and at least one known game (Spacefight 2091!) requires it
+			if e.Config.Quirks.IndexOverflowVF {
+				if (e.I+uint16(e.Registers[x]))&0xF000 != 0 {
+					e.Registers[0xF] = 1
+				} else {
+					e.Registers[0xF] = 0
+				}
 			}
 			e.I += uint16(e.Registers[x])
 		case 0x29:
 			// 0xFX29 Set I to address of font for hex char in VX
 			e.I = FontStartAddress + uint16(e.Registers[x]&0x0F)*FontSpriteHeight
+		case 0x30:
+			// 0xFX30 Set I to address of big font for hex char in VX (SUPER-CHIP)
+			if !e.superChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			e.I = BigFontStartAddress + uint16(e.Registers[x]&0x0F)*BigFontSpriteHeight
+		case 0x3A:
+			// 0xFX3A Set audio pattern pitch to VX (XO-CHIP)
+			if !e.xoChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			e.Pitch = e.Registers[x]
 		case 0x33:
 			// 0xFX33 Take number in VX, convert to three decimal digits, and store at address in I, I+1, I+2
-			if err := validateWriteAddress(e.I, 2); err != nil {
+			if err := e.validateWriteAddress(e.I, 2); err != nil {
 				return fmt.Errorf("decimalise register: %w", err)
 			}
-			e.Memory[e.I] = e.Registers[x] / 100
-			e.Memory[e.I+1] = (e.Registers[x] % 100) / 10
-			e.Memory[e.I+2] = e.Registers[x] % 10
+			e.writeMemory(e.I, e.Registers[x]/100)
+			e.writeMemory(e.I+1, (e.Registers[x]%100)/10)
+			e.writeMemory(e.I+2, e.Registers[x]%10)
 		case 0x55:
 			// 0xFX55 Store V0-VX at address I
-			if err := validateWriteAddress(e.I, uint16(x)); err != nil {
+			if err := e.validateWriteAddress(e.I, uint16(x)); err != nil {
 				return fmt.Errorf("failed to store registers: %w", err)
 			}
 			for i := range uint16(x + 1) {
-				e.Memory[e.I+i] = e.Registers[i]
+				e.writeMemory(e.I+i, e.Registers[i])
 			}
-			if e.Config.LegacyStoreLoad {
+			if e.Config.Quirks.StoreLoadIncrementsI {
 				e.I = e.I + uint16(x) + 1
 			}
 		case 0x65:
 			// 0xFX65 Load memory from address I into V0-VX
-			if err := validateReadAddress(e.I, uint16(x)); err != nil {
+			if err := e.validateReadAddress(e.I, uint16(x)); err != nil {
 				return fmt.Errorf("failed to load into registers: %w", err)
 			}
 			for i := range uint16(x + 1) {
-				e.Registers[i] = e.Memory[e.I+i]
+				e.Registers[i] = e.readMemory(e.I+i, AccessRead)
 			}
-			if e.Config.LegacyStoreLoad {
+			if e.Config.Quirks.StoreLoadIncrementsI {
 				e.I = e.I + uint16(x) + 1
 			}
+		case 0x75:
+			// 0xFX75 Store V0-VX in RPL user flags (SUPER-CHIP)
+			if !e.superChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			if int(x) >= e.rplFlagCount() {
+				return fmt.Errorf("failed to save flags: V%X exceeds %d RPL flags", x, e.rplFlagCount())
+			}
+			copy(e.RPLFlags[:x+1], e.Registers[:x+1])
+		case 0x85:
+			// 0xFX85 Load V0-VX from RPL user flags (SUPER-CHIP)
+			if !e.superChip() {
+				return fmt.Errorf("unknown opcode: 0x%X", opcode)
+			}
+			if int(x) >= e.rplFlagCount() {
+				return fmt.Errorf("failed to load flags: V%X exceeds %d RPL flags", x, e.rplFlagCount())
+			}
+			copy(e.Registers[:x+1], e.RPLFlags[:x+1])
 		default:
 			return fmt.Errorf("unknown opcode: 0x%X", opcode)
 		}
@@ -454,7 +714,9 @@ func (e *Emulator) executeOpcode(opcode uint16) error {
 // Reset resets the emulator to its initial state, clearing memory, registers,
 // and resetting the program counter to the starting address (0x200).
 func (e *Emulator) Reset() {
-	e.clearDisplay()
+	for i := range e.Display {
+		e.Display[i] = 0
+	}
 	for i := range e.Memory {
 		e.Memory[i] = 0
 	}
@@ -467,6 +729,15 @@ func (e *Emulator) Reset() {
 	for i := range e.Keypad {
 		e.Keypad[i] = false
 	}
+	e.input.clear()
+	e.waitKey = -1
+
+	e.HighRes = false
+	e.Planes = 1
+	e.Pitch = DefaultPitch
+	for i := range e.AudioPattern {
+		e.AudioPattern[i] = 0
+	}
 
 	// Reset program counter to start of program memory
 	e.PC = ProgramStartAddress
@@ -476,33 +747,93 @@ func (e *Emulator) Reset() {
 	e.DelayTimer = 0
 	e.SoundTimer = 0
 	e.timerDelta = 0
+	e.waitingForVBlank = false
+	e.instructionCycles = 0
+	e.vipFrameCycles = 0
+	e.cycle = 0
+	e.frame = 0
+	e.inFrame = false
+	e.romHash = [sha1.Size]byte{}
+	e.romInfo = nil
 
 	e.loadFontData()
 }
 
+// ROMHash returns the SHA-1 of the loaded ROM, which identifies the game
+func (e *Emulator) ROMHash() [sha1.Size]byte {
+	return e.romHash
+}
+
+// Cycle returns the number of cycles executed since the emulator was reset,
+// counting cycles spent waiting for the display
+func (e *Emulator) Cycle() uint64 {
+	return e.cycle
+}
+
+// Seed returns the seed of the random number generator used by CXNN
+func (e *Emulator) Seed() int64 {
+	return e.Config.randSeed
+}
+
 // Prints the emulator display and variables to console
 func (e *Emulator) Print() {
-	e.printDisplay()
+	snapshot := e.Snapshot()
+	snapshot.Print()
+}
 
-	fmt.Printf("PC: 0x%04x\n", e.PC)
-	fmt.Printf("I : 0x%04x\n", e.I)
-	fmt.Println("===Registers===")
-	for i := range e.Registers {
-		fmt.Printf("Reg %2d: 0x%02x\n", i, e.Registers[i])
+// Fprint writes the emulator display and variables to w
+func (e *Emulator) Fprint(w io.Writer) error {
+	snapshot := e.Snapshot()
+	return snapshot.Fprint(w)
+}
+
+// superChip reports whether SUPER-CHIP instructions should be decoded
+func (e *Emulator) superChip() bool {
+	return e.Config.Variant >= VariantSuperChip
+}
+
+// resetVF clears VF after the 8XY1/8XY2/8XY3 logic instructions if the VF reset quirk is enabled
+func (e *Emulator) resetVF() {
+	if e.Config.Quirks.VFReset {
+		e.Registers[0xF] = 0
+	}
+}
+
+// xoChip reports whether XO-CHIP instructions should be decoded
+func (e *Emulator) xoChip() bool {
+	return e.Config.Variant >= VariantXOChip
+}
+
+// rplFlagCount returns the number of RPL user flags available to FX75/FX85
+func (e *Emulator) rplFlagCount() int {
+	if e.xoChip() {
+		return XOChipRPLFlagCount
 	}
+	return RPLFlagCount
+}
+
+// skipNextInstruction advances the program counter past the next instruction.
+// In XO-CHIP the F000 NNNN long load is 4 bytes, so it is skipped entirely.
+// The opcode is peeked directly, as the skipped instruction isn't fetched.
+func (e *Emulator) skipNextInstruction() {
+	if e.xoChip() && int(e.PC)+1 < e.MemorySize() && e.Memory[e.PC] == 0xF0 && e.Memory[e.PC+1] == 0x00 {
+		e.PC += 2
+	}
+	e.PC += 2
 }
 
-// UpdateTimers decrements the delay and sound timers if they are greater than zero.
-// This should be called at a rate of 60Hz according to the CHIP-8 specification.
-func (e *Emulator) UpdateTimers(deltaTime time.Duration) {
-	e.timerDelta += deltaTime
-	if e.timerDelta > time.Second/60.0 {
-		e.timerDelta = 0.0
-		if e.DelayTimer > 0 {
-			e.DelayTimer--
+// registerRange returns the register indexes from x to y inclusive, in descending
+// order if x is greater than y, as used by the XO-CHIP 5XY2/5XY3 instructions
+func registerRange(x, y byte) []byte {
+	regs := make([]byte, 0, RegisterCount)
+	if x <= y {
+		for r := x; r <= y; r++ {
+			regs = append(regs, r)
 		}
-		if e.SoundTimer > 0 {
-			e.SoundTimer--
+	} else {
+		for r := int(x); r >= int(y); r-- {
+			regs = append(regs, byte(r))
 		}
 	}
+	return regs
 }
@@ -1,7 +1,10 @@
 package chip8
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/bdeatock/chip8-emulator/asm"
 )
 
 func TestOpcodes(t *testing.T) {
@@ -11,14 +14,14 @@ func TestOpcodes(t *testing.T) {
 		e.Memory[0x200] = 0x00
 		e.Memory[0x201] = 0xE0
 
-		e.Display[0] = true
-		e.Display[10] = true
-		e.Display[100] = true
+		e.Display[0] = 1
+		e.Display[10] = 1
+		e.Display[100] = 1
 
 		e.Step(0)
 
 		for i, pixel := range e.Display {
-			if pixel {
+			if pixel != 0 {
 				t.Errorf("Pixel at position %d should be cleared", i)
 			}
 		}
@@ -104,7 +107,7 @@ func TestOpcodes(t *testing.T) {
 
 		e.Step(0)
 
-		if !e.Display[10*DisplayWidth+5] {
+		if e.Display[10*e.DisplayWidth()+5] == 0 {
 			t.Errorf("Sprite should be drawn at (5,10)")
 		}
 	})
@@ -430,7 +433,7 @@ func TestOpcodes(t *testing.T) {
 
 		// Test legacy behavior (set VX to VY then shift)
 		e = New(
-			WithLegacyShift(true),
+			WithQuirks(Quirks{ShiftUsesVY: true}),
 		)
 		e.Memory[0x200] = 0x8A
 		e.Memory[0x201] = 0xB6
@@ -526,7 +529,7 @@ func TestOpcodes(t *testing.T) {
 
 		// Test legacy behavior (set VX to VY then shift)
 		e = New(
-			WithLegacyShift(true),
+			WithQuirks(Quirks{ShiftUsesVY: true}),
 		)
 		e.Memory[0x200] = 0x8A
 		e.Memory[0x201] = 0xBE
@@ -546,7 +549,7 @@ func TestOpcodes(t *testing.T) {
 	t.Run("FX55 - Store registers V0-VX (modern mode)", func(t *testing.T) {
 		// Create emulator with modern store/load behavior
 		e := New(
-			WithLegacyStoreLoad(false),
+			WithQuirks(Quirks{StoreLoadIncrementsI: false}),
 		)
 
 		// 0xF355 - store registers V0-V3 at address I
@@ -584,7 +587,7 @@ func TestOpcodes(t *testing.T) {
 	t.Run("FX55 - Store registers V0-VX (legacy mode)", func(t *testing.T) {
 		// Create emulator with legacy store/load behavior
 		e := New(
-			WithLegacyStoreLoad(true),
+			WithQuirks(Quirks{StoreLoadIncrementsI: true}),
 		)
 
 		// 0xF355 - store registers V0-V3 at address I
@@ -621,7 +624,7 @@ func TestOpcodes(t *testing.T) {
 	t.Run("FX65 - Load registers V0-VX (modern mode)", func(t *testing.T) {
 		// Create emulator with modern store/load behavior
 		e := New(
-			WithLegacyStoreLoad(false),
+			WithQuirks(Quirks{StoreLoadIncrementsI: false}),
 		)
 
 		// 0xF365 - load registers V0-V3 from address I
@@ -659,7 +662,7 @@ func TestOpcodes(t *testing.T) {
 	t.Run("FX65 - Load registers V0-VX (legacy mode)", func(t *testing.T) {
 		// Create emulator with legacy store/load behavior
 		e := New(
-			WithLegacyStoreLoad(true),
+			WithQuirks(Quirks{StoreLoadIncrementsI: true}),
 		)
 
 		// 0xF365 - load registers V0-V3 from address I
@@ -735,7 +738,9 @@ func TestOpcodes(t *testing.T) {
 	})
 
 	t.Run("FX1E - Add VX to I", func(t *testing.T) {
-		e := New()
+		e := New(
+			WithQuirks(Quirks{IndexOverflowVF: true}),
+		)
 		// 0xFA1E - add value in register A to I
 		e.Memory[0x200] = 0xFA
 		e.Memory[0x201] = 0x1E
@@ -766,6 +771,18 @@ func TestOpcodes(t *testing.T) {
 		if e.Registers[0xF] != 1 {
 			t.Errorf("VF should be 1 when overflow occurs, got %d", e.Registers[0xF])
 		}
+
+		// Test VF is unaffected without the overflow quirk
+		e.Config.Quirks.IndexOverflowVF = false
+		e.PC = 0x200
+		e.I = 0x8000
+		e.Registers[0xF] = 0x5
+
+		e.Step(0)
+
+		if e.Registers[0xF] != 0x5 {
+			t.Errorf("VF should be unchanged without overflow quirk, got %d", e.Registers[0xF])
+		}
 	})
 
 	t.Run("CXNN - Random with mask", func(t *testing.T) {
@@ -867,9 +884,9 @@ func TestOpcodes(t *testing.T) {
 		}
 	})
 
-	t.Run("FX0A - Wait for key press", func(t *testing.T) {
+	t.Run("FX0A - Wait for key press and release", func(t *testing.T) {
 		e := New()
-		// 0xFA0A - wait for key press and store in register A
+		// 0xFA0A - wait for key press and release and store in register A
 		e.Memory[0x200] = 0xFA
 		e.Memory[0x201] = 0x0A
 
@@ -880,28 +897,37 @@ func TestOpcodes(t *testing.T) {
 			t.Errorf("PC should remain at 0x200 when no key is pressed, got 0x%04X", e.PC)
 		}
 
-		// Press a key and run cycle again
+		// Press a key, the instruction repeats until it is released
 		e.Keypad[0x7] = true
 		e.Step(0)
+		e.Step(0)
+
+		if e.PC != 0x200 {
+			t.Errorf("PC should remain at 0x200 while the key is held, got 0x%04X", e.PC)
+		}
+
+		e.Keypad[0x7] = false
+		e.Step(0)
 
 		if e.PC != 0x202 {
-			t.Errorf("PC should advance to 0x202 after key press, got 0x%04X", e.PC)
+			t.Errorf("PC should advance to 0x202 after key release, got 0x%04X", e.PC)
 		}
 
 		if e.Registers[0xA] != 0x7 {
 			t.Errorf("Register A should contain key value 0x7, got 0x%02X", e.Registers[0xA])
 		}
 
-		// Test with a different key
+		// Test with a different key, pressed and released between instructions
 		e.PC = 0x200
-		e.Keypad[0x7] = false
 		e.Step(0) // No key pressed, PC stays at 0x200
 
-		e.Keypad[0xC] = true
+		e.PressKey(0xC)
+		e.ReleaseKey(0xC)
+		e.Step(0)
 		e.Step(0)
 
 		if e.PC != 0x202 {
-			t.Errorf("PC should advance to 0x202 after key press, got 0x%04X", e.PC)
+			t.Errorf("PC should advance to 0x202 after key press and release, got 0x%04X", e.PC)
 		}
 
 		if e.Registers[0xA] != 0xC {
@@ -912,11 +938,12 @@ func TestOpcodes(t *testing.T) {
 	t.Run("Key press and release functions", func(t *testing.T) {
 		e := New()
 
-		// Test pressing a valid key
+		// Test pressing a valid key, applied before the next instruction
 		err := e.PressKey(0x5)
 		if err != nil {
 			t.Errorf("PressKey returned unexpected error: %v", err)
 		}
+		e.Step(0)
 		if !e.Keypad[0x5] {
 			t.Errorf("Keypad[5] should be true after pressing key 5")
 		}
@@ -932,6 +959,7 @@ func TestOpcodes(t *testing.T) {
 		if err != nil {
 			t.Errorf("ReleaseKey returned unexpected error: %v", err)
 		}
+		e.Step(0)
 		if e.Keypad[0x5] {
 			t.Errorf("Keypad[5] should be false after releasing key 5")
 		}
@@ -943,3 +971,306 @@ func TestOpcodes(t *testing.T) {
 		}
 	})
 }
+
+func TestSuperChipOpcodes(t *testing.T) {
+	t.Run("00FF and 00FE - Switch resolution", func(t *testing.T) {
+		e := New(WithVariant(VariantSuperChip))
+		// 0x00FF - high resolution, 0x00FE - low resolution
+		e.Memory[0x200] = 0x00
+		e.Memory[0x201] = 0xFF
+		e.Memory[0x202] = 0x00
+		e.Memory[0x203] = 0xFE
+		e.Display[0] = 1
+
+		e.Step(0)
+
+		if !e.HighRes || e.DisplayWidth() != HighResWidth || e.DisplayHeight() != HighResHeight {
+			t.Errorf("Should be in 128x64 mode, got %dx%d", e.DisplayWidth(), e.DisplayHeight())
+		}
+		if e.Display[0] != 0 {
+			t.Errorf("Display should be cleared when switching resolution")
+		}
+
+		e.Step(0)
+
+		if e.HighRes || e.DisplayWidth() != LowResWidth || e.DisplayHeight() != LowResHeight {
+			t.Errorf("Should be in 64x32 mode, got %dx%d", e.DisplayWidth(), e.DisplayHeight())
+		}
+	})
+
+	t.Run("00FF - Ignored without SUPER-CHIP", func(t *testing.T) {
+		e := New(WithVariant(VariantChip8))
+		e.Memory[0x200] = 0x00
+		e.Memory[0x201] = 0xFF
+
+		if err := e.Step(0); err != nil {
+			t.Errorf("0NNN should be ignored in CHIP-8 mode, got error: %v", err)
+		}
+		if e.HighRes {
+			t.Errorf("Should remain in low resolution in CHIP-8 mode")
+		}
+	})
+
+	t.Run("00FD - Exit", func(t *testing.T) {
+		e := New(WithVariant(VariantSuperChip))
+		e.Memory[0x200] = 0x00
+		e.Memory[0x201] = 0xFD
+
+		err := e.Step(0)
+
+		if !errors.Is(err, ErrProgramExit) {
+			t.Errorf("Step should return ErrProgramExit, got %v", err)
+		}
+		if e.PC != 0x200 {
+			t.Errorf("PC should stay on exit instruction, got 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("DXY0 - Draw 16x16 sprite", func(t *testing.T) {
+		e := New(WithVariant(VariantSuperChip))
+		// 0x00FF - high resolution, 0xD120 - draw 16x16 sprite at (V1, V2)
+		e.Memory[0x200] = 0x00
+		e.Memory[0x201] = 0xFF
+		e.Memory[0x202] = 0xD1
+		e.Memory[0x203] = 0x20
+		e.I = 0x300
+		// Row 0 is 0x8001 (leftmost and rightmost pixel), row 15 is 0xFFFF
+		e.Memory[0x300] = 0x80
+		e.Memory[0x301] = 0x01
+		e.Memory[0x31E] = 0xFF
+		e.Memory[0x31F] = 0xFF
+		e.Registers[1] = 100
+		e.Registers[2] = 40
+
+		e.Step(0)
+		e.Step(0)
+
+		width := e.DisplayWidth()
+		if e.Display[40*width+100] == 0 || e.Display[40*width+115] == 0 {
+			t.Errorf("Top row corners of 16x16 sprite should be set")
+		}
+		if e.Display[40*width+101] != 0 {
+			t.Errorf("Pixel at (101,40) should be clear")
+		}
+		for x := 100; x < 116; x++ {
+			if e.Display[55*width+x] == 0 {
+				t.Errorf("Pixel at (%d,55) should be set", x)
+			}
+		}
+	})
+
+	t.Run("FX30 - Set I to big font address", func(t *testing.T) {
+		e := New(WithVariant(VariantSuperChip))
+		// 0xFA30 - Set I to location of big sprite for digit in VA
+		e.Memory[0x200] = 0xFA
+		e.Memory[0x201] = 0x30
+		e.Registers[0xA] = 0x7
+
+		e.Step(0)
+
+		if e.I != BigFontStartAddress+7*BigFontSpriteHeight {
+			t.Errorf("I should be 0x%04X, got 0x%04X", BigFontStartAddress+7*BigFontSpriteHeight, e.I)
+		}
+		if e.Memory[e.I] != 0xFF {
+			t.Errorf("Big font data should be loaded at I, got 0x%02X", e.Memory[e.I])
+		}
+	})
+
+	t.Run("FX75 and FX85 - Save and load RPL flags", func(t *testing.T) {
+		e := New(WithVariant(VariantSuperChip))
+		// 0xF375 - save V0-V3 to flags, 0xF385 - load V0-V3 from flags
+		e.Memory[0x200] = 0xF3
+		e.Memory[0x201] = 0x75
+		e.Memory[0x202] = 0xF3
+		e.Memory[0x203] = 0x85
+		e.Registers[0] = 0x11
+		e.Registers[3] = 0x44
+
+		e.Step(0)
+
+		if e.RPLFlags[0] != 0x11 || e.RPLFlags[3] != 0x44 {
+			t.Errorf("RPL flags should hold saved registers, got %v", e.RPLFlags)
+		}
+
+		e.Registers[0] = 0
+		e.Registers[3] = 0
+		e.Step(0)
+
+		if e.Registers[0] != 0x11 || e.Registers[3] != 0x44 {
+			t.Errorf("Registers should be restored from RPL flags, got V0=0x%02X V3=0x%02X", e.Registers[0], e.Registers[3])
+		}
+
+		// 0xF875 - V8 is beyond the 8 SUPER-CHIP flags
+		e.Memory[0x204] = 0xF8
+		e.Memory[0x205] = 0x75
+		if err := e.Step(0); err == nil {
+			t.Errorf("Saving more than %d flags should return an error", RPLFlagCount)
+		}
+	})
+}
+
+func TestXOChipOpcodes(t *testing.T) {
+	t.Run("F000 NNNN - Long index load", func(t *testing.T) {
+		e := New(WithVariant(VariantXOChip))
+		// 0xF000 0xE123 - set I to 0xE123
+		e.Memory[0x200] = 0xF0
+		e.Memory[0x201] = 0x00
+		e.Memory[0x202] = 0xE1
+		e.Memory[0x203] = 0x23
+
+		e.Step(0)
+
+		if e.I != 0xE123 {
+			t.Errorf("I should be 0xE123, got 0x%04X", e.I)
+		}
+		if e.PC != 0x204 {
+			t.Errorf("PC should skip the 4 byte instruction to 0x204, got 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("Skip over F000 NNNN", func(t *testing.T) {
+		e := New(WithVariant(VariantXOChip))
+		// 0x3000 - skip if V0 == 0, followed by long load
+		e.Memory[0x200] = 0x30
+		e.Memory[0x201] = 0x00
+		e.Memory[0x202] = 0xF0
+		e.Memory[0x203] = 0x00
+
+		e.Step(0)
+
+		if e.PC != 0x206 {
+			t.Errorf("PC should skip the whole long load to 0x206, got 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("5XY2 and 5XY3 - Store and load register range", func(t *testing.T) {
+		e := New(WithVariant(VariantXOChip))
+		// 0x5242 - store V2-V4 at I, 0x5423 - load V4-V2 (reversed) from I
+		e.Memory[0x200] = 0x52
+		e.Memory[0x201] = 0x42
+		e.Memory[0x202] = 0x54
+		e.Memory[0x203] = 0x23
+		e.I = 0x300
+		e.Registers[2] = 0x22
+		e.Registers[3] = 0x33
+		e.Registers[4] = 0x44
+
+		e.Step(0)
+
+		if e.Memory[0x300] != 0x22 || e.Memory[0x301] != 0x33 || e.Memory[0x302] != 0x44 {
+			t.Errorf("Memory should hold V2-V4, got % X", e.Memory[0x300:0x303])
+		}
+		if e.I != 0x300 {
+			t.Errorf("I should be unchanged, got 0x%04X", e.I)
+		}
+
+		e.Step(0)
+
+		if e.Registers[4] != 0x22 || e.Registers[3] != 0x33 || e.Registers[2] != 0x44 {
+			t.Errorf("Registers V4-V2 should be loaded in reverse order, got V2=0x%02X V3=0x%02X V4=0x%02X",
+				e.Registers[2], e.Registers[3], e.Registers[4])
+		}
+	})
+
+	t.Run("FN01 - Select planes", func(t *testing.T) {
+		e := New(WithVariant(VariantXOChip))
+		// 0xF301 - select both planes
+		e.Memory[0x200] = 0xF3
+		e.Memory[0x201] = 0x01
+
+		e.Step(0)
+
+		if e.Planes != 3 {
+			t.Errorf("Planes should be 3, got %d", e.Planes)
+		}
+	})
+
+	t.Run("F002 and FX3A - Audio pattern and pitch", func(t *testing.T) {
+		e := New(WithVariant(VariantXOChip))
+		// 0xF002 - load audio pattern from I, 0xF53A - set pitch to V5
+		e.Memory[0x200] = 0xF0
+		e.Memory[0x201] = 0x02
+		e.Memory[0x202] = 0xF5
+		e.Memory[0x203] = 0x3A
+		e.I = 0x300
+		for i := range AudioPatternSize {
+			e.Memory[0x300+i] = byte(i)
+		}
+		e.Registers[5] = 112
+
+		if e.Pitch != DefaultPitch {
+			t.Errorf("Pitch should default to %d, got %d", DefaultPitch, e.Pitch)
+		}
+
+		e.Step(0)
+		e.Step(0)
+
+		for i, sample := range e.AudioPattern {
+			if sample != byte(i) {
+				t.Errorf("Audio pattern byte %d should be 0x%02X, got 0x%02X", i, i, sample)
+			}
+		}
+		if e.Pitch != 112 {
+			t.Errorf("Pitch should be 112, got %d", e.Pitch)
+		}
+	})
+
+	t.Run("Memory size", func(t *testing.T) {
+		e := New(WithVariant(VariantXOChip))
+		rom := make([]byte, XOChipMemorySize-ProgramStartAddress)
+
+		if err := e.LoadROMFromData(rom); err != nil {
+			t.Errorf("XO-CHIP should accept a %dB ROM, got error: %v", len(rom), err)
+		}
+
+		e = New(WithVariant(VariantSuperChip))
+		if err := e.LoadROMFromData(rom); err == nil {
+			t.Errorf("SUPER-CHIP should reject a %dB ROM", len(rom))
+		}
+	})
+}
+
+func TestProgram(t *testing.T) {
+	t.Run("Subroutine loop", func(t *testing.T) {
+		e := New()
+		// Sum 1 to 10 with a subroutine, store the BCD of the result and halt
+		rom := asm.MustAssemble(`
+			ld   v0, 0      ; sum
+			ld   v1, 10     ; counter
+		loop:
+			call accumulate
+			sne  v1, 0
+			jp   done
+			jp   loop
+		accumulate:
+			add  v0, v1
+			ld   v2, 1
+			sub  v1, v2
+			ret
+		done:
+			ld   i, result
+			ld   b, v0
+		halt:
+			jp   halt
+		result:
+			:byte 0, 0, 0
+		`)
+		if err := e.LoadROMFromData(rom); err != nil {
+			t.Fatalf("LoadROMFromData returned error: %v", err)
+		}
+
+		for range 100 {
+			if err := e.Step(0); err != nil {
+				t.Fatalf("Step returned error: %v", err)
+			}
+		}
+
+		if e.Registers[0] != 55 {
+			t.Errorf("V0 should be 55, got %d", e.Registers[0])
+		}
+		result := e.Memory[e.I : e.I+3]
+		if result[0] != 0 || result[1] != 5 || result[2] != 5 {
+			t.Errorf("BCD of 55 should be [0 5 5], got %v", result)
+		}
+	})
+}
@@ -1,59 +1,216 @@
 package chip8
 
-import "fmt"
-
-// printDisplay renders the current state of the CHIP-8 display to the console.
-func (e *Emulator) printDisplay() {
-	for y := range 32 {
-		fmt.Print("|")
-		for x := range DisplayWidth {
-			if e.Display[y*DisplayWidth+x] {
-				fmt.Print("██")
-			} else {
-				fmt.Print("  ")
-			}
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// DisplayPalette holds the colours used by DisplayImage, indexed by the plane
+// bits of a pixel: off, plane 1, plane 2, both planes
+var DisplayPalette = color.Palette{
+	color.Gray{Y: 0x00},
+	color.Gray{Y: 0xFF},
+	color.Gray{Y: 0x55},
+	color.Gray{Y: 0xAA},
+}
+
+// DisplayWidth returns the width in pixels of the active display resolution
+func (e *Emulator) DisplayWidth() int {
+	width, _ := displaySize(e.HighRes)
+	return width
+}
+
+// DisplayHeight returns the height in pixels of the active display resolution
+func (e *Emulator) DisplayHeight() int {
+	_, height := displaySize(e.HighRes)
+	return height
+}
+
+// displaySize returns the size in pixels of the low or high resolution display
+func displaySize(highRes bool) (int, int) {
+	if highRes {
+		return HighResWidth, HighResHeight
+	}
+	return LowResWidth, LowResHeight
+}
+
+// DisplayImage returns a copy of the active display resolution as an image,
+// one image pixel per display pixel. Each pixel's colour index is its plane bits.
+func (e *Emulator) DisplayImage() *image.Paletted {
+	return displayImage(&e.Display, e.HighRes)
+}
+
+func displayImage(display *[HighResWidth * HighResHeight]byte, highRes bool) *image.Paletted {
+	width, height := displaySize(highRes)
+	img := image.NewPaletted(image.Rect(0, 0, width, height), DisplayPalette)
+	for y := range height {
+		for x := range width {
+			img.SetColorIndex(x, y, display[y*width+x]&0x3)
+		}
+	}
+	return img
+}
+
+// printDisplay renders a CHIP-8 display as text.
+// Low resolution pixels are printed two characters wide to keep them roughly square.
+// Pixels with only plane 2 set are shaded so XO-CHIP planes can be told apart.
+func printDisplay(out *strings.Builder, display *[HighResWidth * HighResHeight]byte, highRes bool) {
+	glyphs := [4]string{"  ", "██", "░░", "▓▓"}
+	if highRes {
+		glyphs = [4]string{" ", "█", "░", "▓"}
+	}
+
+	width, height := displaySize(highRes)
+	for y := range height {
+		out.WriteString("|")
+		for x := range width {
+			out.WriteString(glyphs[display[y*width+x]&0x3])
 		}
-		fmt.Print("|\n")
+		out.WriteString("|\n")
 	}
 }
 
-// Draws sprite with specified height at specified coordinates.
-// Sprite is read from address pointed to by Index register.
-func (e *Emulator) drawSprite(xPos, yPos, height int) {
+// Draws sprite with specified width and height at specified coordinates.
+// Sprite is read from address pointed to by Index register, width/8 bytes per row.
+// Parts of the sprite beyond the screen edge are clipped, or wrapped to the
+// opposite edge with the WrapSprites quirk.
+// When several planes are selected, the sprite data for each plane follows
+// the previous one in memory.
+func (e *Emulator) drawSprite(xPos, yPos, width, height int) {
+	displayWidth := e.DisplayWidth()
+	displayHeight := e.DisplayHeight()
+	bytesPerRow := width / 8
+	address := e.I
+
 	// Wrap coordinates
-	xPos = xPos % DisplayWidth
-	yPos = yPos % DisplayHeight
+	xPos = xPos % displayWidth
+	yPos = yPos % displayHeight
 
 	// Reset collision flag
 	e.Registers[0xF] = 0
 
-	for row := range height {
-		if yPos+row >= DisplayHeight {
-			break
+	for plane := range byte(PlaneCount) {
+		planeMask := byte(1) << plane
+		if e.Planes&planeMask == 0 {
+			continue
 		}
 
-		sprite := e.Memory[e.I+uint16(row)]
+		for row := range height {
+			pixelY := yPos + row
+			if pixelY >= displayHeight {
+				if !e.Config.Quirks.WrapSprites {
+					break
+				}
+				pixelY %= displayHeight
+			}
+
+			var sprite byte
+			for col := range width {
+				if col%8 == 0 {
+					sprite = e.readMemory(address+uint16(row*bytesPerRow+col/8), AccessRead)
+				}
+				pixelX := xPos + col
+				if pixelX >= displayWidth {
+					if !e.Config.Quirks.WrapSprites {
+						break
+					}
+					pixelX %= displayWidth
+				}
 
-		for col := range 8 {
-			if sprite&(128>>col) > 0 && xPos+col < DisplayWidth {
-				if !e.flipPixel(xPos+col, yPos+row) {
-					// If pixel turned off, set collision flag
-					e.Registers[0xF] = 1
+				if sprite&(128>>(col%8)) > 0 {
+					if !e.flipPixel(pixelX, pixelY, planeMask) {
+						// If pixel turned off, set collision flag
+						e.Registers[0xF] = 1
+					}
 				}
 			}
 		}
+		address += uint16(height * bytesPerRow)
 	}
 }
 
-// Flips a pixel at coord, and returns the resulting state of pixel
-func (e *Emulator) flipPixel(x int, y int) bool {
-	e.Display[y*DisplayWidth+x] = !e.Display[y*DisplayWidth+x]
+// Flips a pixel at coord on the given plane, and returns the resulting state of pixel
+func (e *Emulator) flipPixel(x int, y int, planeMask byte) bool {
+	i := y*e.DisplayWidth() + x
+	e.Display[i] ^= planeMask
+	e.displayChanged = true
 
-	return e.Display[y*DisplayWidth+x]
+	return e.Display[i]&planeMask != 0
 }
 
+// clearDisplay clears the selected planes
 func (e *Emulator) clearDisplay() {
+	e.displayChanged = true
+	for i := range e.Display {
+		e.Display[i] &^= e.Planes
+	}
+}
+
+// setHighRes switches display resolution, clearing all planes
+func (e *Emulator) setHighRes(highRes bool) {
+	e.HighRes = highRes
+	e.displayChanged = true
 	for i := range e.Display {
-		e.Display[i] = false
+		e.Display[i] = 0
+	}
+}
+
+// scrollVertical moves the selected planes down dy pixels (up if negative),
+// blank rows are scrolled in on the opposite side
+func (e *Emulator) scrollVertical(dy int) {
+	e.displayChanged = true
+	width := e.DisplayWidth()
+	height := e.DisplayHeight()
+	if dy > 0 {
+		for y := height - 1; y >= 0; y-- {
+			for x := range width {
+				e.scrollPixel(y*width+x, x, y-dy)
+			}
+		}
+	} else {
+		for y := range height {
+			for x := range width {
+				e.scrollPixel(y*width+x, x, y-dy)
+			}
+		}
+	}
+}
+
+// scrollHorizontal moves the selected planes right by dx pixels (left if negative),
+// blank columns are scrolled in on the opposite side
+func (e *Emulator) scrollHorizontal(dx int) {
+	e.displayChanged = true
+	width := e.DisplayWidth()
+	for y := range e.DisplayHeight() {
+		if dx > 0 {
+			for x := width - 1; x >= 0; x-- {
+				e.scrollPixel(y*width+x, x-dx, y)
+			}
+		} else {
+			for x := range width {
+				e.scrollPixel(y*width+x, x-dx, y)
+			}
+		}
+	}
+}
+
+// scrollDistance returns the number of display pixels a scroll of n pixels
+// moves. With the LowResHalfScroll quirk, n is in high resolution pixels, so
+// a low resolution display scrolls half as far, dropping any half pixel.
+func (e *Emulator) scrollDistance(n int) int {
+	if !e.HighRes && e.Config.Quirks.LowResHalfScroll {
+		return n / 2
+	}
+	return n
+}
+
+// scrollPixel replaces the selected planes of the pixel at index dst with
+// those of the pixel at (srcX, srcY), or clears them if the source is off screen
+func (e *Emulator) scrollPixel(dst int, srcX int, srcY int) {
+	var src byte
+	if srcX >= 0 && srcX < e.DisplayWidth() && srcY >= 0 && srcY < e.DisplayHeight() {
+		src = e.Display[srcY*e.DisplayWidth()+srcX]
 	}
+	e.Display[dst] = e.Display[dst]&^e.Planes | src&e.Planes
 }
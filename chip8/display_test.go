@@ -7,14 +7,14 @@ import (
 func TestClearDisplay(t *testing.T) {
 	e := New()
 
-	e.Display[0] = true
-	e.Display[10] = true
+	e.Display[0] = 1
+	e.Display[10] = 1
 
 	e.clearDisplay()
 
 	// Check all pixels are cleared
 	for i, pixel := range e.Display {
-		if pixel {
+		if pixel != 0 {
 			t.Errorf("Pixel at position %d is still set after clearDisplay()", i)
 		}
 	}
@@ -23,26 +23,26 @@ func TestClearDisplay(t *testing.T) {
 func TestFlipPixel(t *testing.T) {
 	e := New()
 
-	initialState := e.Display[10*DisplayWidth+5]
-	if initialState != false {
+	initialState := e.Display[10*e.DisplayWidth()+5]
+	if initialState != 0 {
 		t.Errorf("Expected initial pixel state to be false")
 	}
 
-	result := e.flipPixel(5, 10)
+	result := e.flipPixel(5, 10, 1)
 	if !result {
 		t.Errorf("flipPixel should return true after flipping from false")
 	}
 
-	if !e.Display[10*DisplayWidth+5] {
+	if e.Display[10*e.DisplayWidth()+5] == 0 {
 		t.Errorf("Pixel should be true after flipping from false")
 	}
 
-	result = e.flipPixel(5, 10)
+	result = e.flipPixel(5, 10, 1)
 	if result {
 		t.Errorf("flipPixel should return false after flipping from true")
 	}
 
-	if e.Display[10*DisplayWidth+5] {
+	if e.Display[10*e.DisplayWidth()+5] != 0 {
 		t.Errorf("Pixel should be false after flipping from true")
 	}
 }
@@ -59,25 +59,25 @@ func TestDrawSprite(t *testing.T) {
 		e.Memory[0x301] = 0x80 // 10000000
 		e.Memory[0x302] = 0x80 // 10000000
 
-		e.drawSprite(5, 10, 3)
+		e.drawSprite(5, 10, 8, 3)
 
-		if !e.Display[10*DisplayWidth+5] {
+		if e.Display[10*e.DisplayWidth()+5] == 0 {
 			t.Errorf("Pixel at (5,10) should be set")
 		}
-		if !e.Display[11*DisplayWidth+5] {
+		if e.Display[11*e.DisplayWidth()+5] == 0 {
 			t.Errorf("Pixel at (5,11) should be set")
 		}
-		if !e.Display[12*DisplayWidth+5] {
+		if e.Display[12*e.DisplayWidth()+5] == 0 {
 			t.Errorf("Pixel at (5,12) should be set")
 		}
 
 		// Check that all other pixels are still clear
-		for y := range DisplayHeight {
-			for x := range DisplayWidth {
+		for y := range e.DisplayHeight() {
+			for x := range e.DisplayWidth() {
 				if (y == 10 && x == 5) || (y == 11 && x == 5) || (y == 12 && x == 5) {
 					continue
 				}
-				if e.Display[y*DisplayWidth+x] {
+				if e.Display[y*e.DisplayWidth()+x] != 0 {
 					t.Errorf("Pixel at (%d,%d) should be clear", x, y)
 				}
 			}
@@ -87,9 +87,9 @@ func TestDrawSprite(t *testing.T) {
 			t.Errorf("Collision flag should not be set")
 		}
 
-		e.drawSprite(5, 10, 3)
+		e.drawSprite(5, 10, 8, 3)
 
-		if e.Display[10*DisplayWidth+5] {
+		if e.Display[10*e.DisplayWidth()+5] != 0 {
 			t.Errorf("Pixel at (5,10) should be unset after second draw")
 		}
 
@@ -104,17 +104,17 @@ func TestDrawSprite(t *testing.T) {
 		e.Memory[0x300] = 0x80 // 10000000
 
 		// Draw at X=68 (which should wrap to X=4)
-		e.drawSprite(68, 5, 1)
+		e.drawSprite(68, 5, 8, 1)
 
-		if !e.Display[5*DisplayWidth+4] {
+		if e.Display[5*e.DisplayWidth()+4] == 0 {
 			t.Errorf("Pixel at (4,5) should be set (wrapped from X=68)")
 		}
 
 		// Draw at Y=34 (which should wrap to Y=2)
 		e.clearDisplay()
-		e.drawSprite(3, 34, 1)
+		e.drawSprite(3, 34, 8, 1)
 
-		if !e.Display[2*DisplayWidth+3] {
+		if e.Display[2*e.DisplayWidth()+3] == 0 {
 			t.Errorf("Pixel at (3,2) should be set (wrapped from Y=34)")
 		}
 	})
@@ -123,19 +123,131 @@ func TestDrawSprite(t *testing.T) {
 		e.clearDisplay()
 		e.I = 0x300
 		e.Memory[0x300] = 0xFF
-		e.drawSprite(DisplayWidth-2, 5, 1)
+		e.drawSprite(e.DisplayWidth()-2, 5, 8, 1)
 
-		if !e.Display[5*DisplayWidth+(DisplayWidth-2)] {
-			t.Errorf("Pixel at (%d,5) should be set", DisplayWidth-2)
+		if e.Display[5*e.DisplayWidth()+(e.DisplayWidth()-2)] == 0 {
+			t.Errorf("Pixel at (%d,5) should be set", e.DisplayWidth()-2)
 		}
-		if !e.Display[5*DisplayWidth+(DisplayWidth-1)] {
-			t.Errorf("Pixel at (%d,5) should be set", DisplayWidth-1)
+		if e.Display[5*e.DisplayWidth()+(e.DisplayWidth()-1)] == 0 {
+			t.Errorf("Pixel at (%d,5) should be set", e.DisplayWidth()-1)
 		}
 
 		for x := range 6 {
-			if e.Display[5*DisplayWidth+x] {
+			if e.Display[5*e.DisplayWidth()+x] != 0 {
 				t.Errorf("Pixel at (%d,5) should NOT be set (sprite should not wrap)", x)
 			}
 		}
 	})
 }
+
+func TestScroll(t *testing.T) {
+	e := New(WithVariant(VariantSuperChip))
+
+	t.Run("Scroll down", func(t *testing.T) {
+		e.clearDisplay()
+		width := e.DisplayWidth()
+		e.Display[0*width+3] = 1
+		e.Display[(e.DisplayHeight()-1)*width+3] = 1
+
+		e.scrollVertical(2)
+
+		if e.Display[0*width+3] != 0 {
+			t.Errorf("Pixel at (3,0) should be scrolled away")
+		}
+		if e.Display[2*width+3] == 0 {
+			t.Errorf("Pixel at (3,2) should be set after scrolling down 2")
+		}
+		for i, pixel := range e.Display {
+			if pixel != 0 && i != 2*width+3 {
+				t.Errorf("Pixel at index %d should be clear, bottom row should scroll off screen", i)
+			}
+		}
+	})
+
+	t.Run("Scroll right and left", func(t *testing.T) {
+		e.setHighRes(true)
+		width := e.DisplayWidth()
+		e.Display[5*width+0] = 1
+		e.Display[5*width+width-1] = 1
+
+		e.scrollHorizontal(4)
+
+		if e.Display[5*width+4] == 0 || e.Display[5*width+0] != 0 {
+			t.Errorf("Pixel should move from (0,5) to (4,5) after scrolling right")
+		}
+		if e.Display[5*width+width-1] != 0 {
+			t.Errorf("Rightmost pixel should scroll off screen")
+		}
+
+		e.scrollHorizontal(-4)
+
+		if e.Display[5*width+0] == 0 {
+			t.Errorf("Pixel at (0,5) should be set after scrolling back")
+		}
+
+		e.scrollHorizontal(-4)
+
+		for i, pixel := range e.Display {
+			if pixel != 0 {
+				t.Errorf("Pixel at index %d should be clear, leftmost column should scroll off screen", i)
+			}
+		}
+	})
+}
+
+func TestPlanes(t *testing.T) {
+	e := New(WithVariant(VariantXOChip))
+
+	t.Run("Draw to both planes", func(t *testing.T) {
+		e.Planes = 3
+		e.clearDisplay()
+		// Plane 1 sprite data followed by plane 2 sprite data
+		e.I = 0x300
+		e.Memory[0x300] = 0xC0 // 11000000
+		e.Memory[0x301] = 0x80 // 10000000
+
+		e.drawSprite(0, 0, 8, 1)
+
+		if e.Display[0] != 3 {
+			t.Errorf("Pixel at (0,0) should be on in both planes, got %d", e.Display[0])
+		}
+		if e.Display[1] != 1 {
+			t.Errorf("Pixel at (1,0) should be on in plane 1 only, got %d", e.Display[1])
+		}
+	})
+
+	t.Run("Clear and scroll only selected plane", func(t *testing.T) {
+		e.Planes = 2
+		e.scrollHorizontal(4)
+
+		if e.Display[0] != 1 || e.Display[4] != 2 {
+			t.Errorf("Only plane 2 should scroll, got (0,0)=%d (4,0)=%d", e.Display[0], e.Display[4])
+		}
+
+		e.clearDisplay()
+
+		if e.Display[0] != 1 || e.Display[1] != 1 || e.Display[4] != 0 {
+			t.Errorf("Only plane 2 should be cleared, got (0,0)=%d (1,0)=%d (4,0)=%d", e.Display[0], e.Display[1], e.Display[4])
+		}
+	})
+}
+
+func TestDisplayImage(t *testing.T) {
+	e := New()
+	e.Display[0] = 1
+	e.Display[LowResWidth+1] = 3
+
+	img := e.DisplayImage()
+
+	if img.Bounds().Dx() != LowResWidth || img.Bounds().Dy() != LowResHeight {
+		t.Errorf("Image should be %dx%d, got %v", LowResWidth, LowResHeight, img.Bounds())
+	}
+	if img.ColorIndexAt(0, 0) != 1 || img.ColorIndexAt(1, 1) != 3 || img.ColorIndexAt(1, 0) != 0 {
+		t.Errorf("Image pixels should match display planes")
+	}
+
+	e.HighRes = true
+	if img := e.DisplayImage(); img.Bounds().Dx() != HighResWidth {
+		t.Errorf("Hi-res image should be %d wide, got %d", HighResWidth, img.Bounds().Dx())
+	}
+}
@@ -20,6 +20,27 @@ var fontData = []byte{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
+// Big font data for hexadecimal digits 0-F (SUPER-CHIP), 8x10 pixels each.
+// SUPER-CHIP 1.1 only shipped digits 0-9, A-F follow the XO-CHIP font
+var bigFontData = []byte{
+	0xFF, 0xFF, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, // 0
+	0x18, 0x78, 0x78, 0x18, 0x18, 0x18, 0x18, 0x18, 0xFF, 0xFF, // 1
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // 2
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 3
+	0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0x03, 0x03, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 5
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 6
+	0xFF, 0xFF, 0x03, 0x03, 0x06, 0x0C, 0x18, 0x18, 0x18, 0x18, // 7
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 8
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
 // Address where font data begins in memory.
 // The fontData needs to fit within 0x000-0x200 of RAM
 const FontStartAddress = 0x50
@@ -27,13 +48,20 @@ const FontStartAddress = 0x50
 // Height of each character in bytes.
 const FontSpriteHeight = 5
 
+// Address where big font data begins in memory, directly after the small font.
+const BigFontStartAddress = FontStartAddress + 16*FontSpriteHeight
+
+// Height of each big font character in bytes.
+const BigFontSpriteHeight = 10
+
 // loadFontData copies the built-in font data into the emulator's memory
-// starting at the FontAddress location
+// starting at the FontStartAddress and BigFontStartAddress locations
 func (e *Emulator) loadFontData() {
 	// Check if font data will fit in memory
-	if FontStartAddress+len(fontData) > ProgramStartAddress {
+	if BigFontStartAddress+len(bigFontData) > ProgramStartAddress {
 		panic("Font data exceeds memory bounds (0x000-0x200)")
 	}
 
 	copy(e.Memory[FontStartAddress:], fontData)
+	copy(e.Memory[BigFontStartAddress:], bigFontData)
 }
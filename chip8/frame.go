@@ -0,0 +1,107 @@
+package chip8
+
+import "time"
+
+// Timing of the delay and sound timers, which RunFrame ticks once per frame
+const (
+	FramesPerSecond = 60
+	FrameDuration   = time.Second / FramesPerSecond
+
+	// DefaultInstructionsPerFrame runs about 700 instructions per second
+	DefaultInstructionsPerFrame = 11
+)
+
+// WithInstructionsPerFrame sets how many instructions RunFrame executes
+func WithInstructionsPerFrame(instructions int) EmulatorOption {
+	return func(e *Emulator) {
+		e.Config.InstructionsPerFrame = instructions
+	}
+}
+
+// RunFrame runs one 60Hz frame: Config.InstructionsPerFrame instructions,
+// then a single tick of the delay and sound timers. With the display wait
+// quirk a draw ends the frame's instructions early, as on the COSMAC VIP.
+// With TimingVIP, instructions run until the vertical blank interrupt instead.
+// Returns whether the display changed during the frame. On an error from
+// Step, including a *WatchpointError, the rest of the frame is abandoned
+// and the timers are not ticked.
+func (e *Emulator) RunFrame() (bool, error) {
+	for {
+		ended, err := e.StepFrame()
+		if err != nil || ended {
+			return e.displayChanged, err
+		}
+	}
+}
+
+// StepFrame runs the next instruction of a frame exactly as RunFrame would,
+// ending the frame once its instructions have run. Returns whether the frame
+// ended. A frame can end without running an instruction, while the display
+// wait quirk waits for the vertical blank. A frame left unfinished is
+// finished by the next StepFrame or RunFrame, but not kept by SaveState.
+func (e *Emulator) StepFrame() (bool, error) {
+	if !e.inFrame {
+		e.movieFrame()
+		e.displayChanged = false
+		e.vblank = false
+		e.frameSteps = 0
+		e.inFrame = true
+	}
+
+	if e.Config.Timing == TimingVIP {
+		if err := e.Step(0); err != nil {
+			e.inFrame = false
+			return false, err
+		}
+		if !e.vblank {
+			return false, nil
+		}
+		e.inFrame = false
+		e.frame++
+		return true, nil
+	}
+
+	instructions := max(1, e.Config.InstructionsPerFrame)
+	if e.frameSteps < instructions && !e.waitingForVBlank {
+		if err := e.Step(0); err != nil {
+			e.inFrame = false
+			return false, err
+		}
+		e.frameSteps++
+		if e.frameSteps < instructions && !e.waitingForVBlank {
+			return false, nil
+		}
+	}
+
+	e.tickTimers()
+	e.inFrame = false
+	e.frame++
+	return true, nil
+}
+
+// Frame returns the number of frames run by RunFrame or StepFrame since the emulator was reset
+func (e *Emulator) Frame() uint64 {
+	return e.frame
+}
+
+// UpdateTimers advances the delay and sound timers by deltaTime, ticking
+// them once for every 1/60s passed. The remainder carries over to the next call.
+func (e *Emulator) UpdateTimers(deltaTime time.Duration) {
+	e.timerDelta += deltaTime
+	for e.timerDelta >= FrameDuration {
+		e.timerDelta -= FrameDuration
+		e.tickTimers()
+	}
+}
+
+// tickTimers decrements the delay and sound timers if they are greater than
+// zero, and ends any wait for the vertical blank
+func (e *Emulator) tickTimers() {
+	e.waitingForVBlank = false
+	if e.DelayTimer > 0 {
+		e.DelayTimer--
+	}
+	if e.SoundTimer > 0 {
+		e.SoundTimer--
+	}
+}
@@ -0,0 +1,140 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+)
+
+func TestRunFrame(t *testing.T) {
+	t.Run("Runs instructions per frame and ticks timers once", func(t *testing.T) {
+		e := New(WithInstructionsPerFrame(10))
+		e.LoadROMFromData(asm.MustAssemble(`
+		loop:
+			add v0, 1
+			jp  loop
+		`))
+		e.DelayTimer = 5
+
+		changed, err := e.RunFrame()
+		if err != nil {
+			t.Fatalf("RunFrame returned error: %v", err)
+		}
+		if e.Registers[0] != 5 || e.Cycle() != 10 || e.Frame() != 1 {
+			t.Errorf("Expected V0 = 5 after 10 cycles and 1 frame, got V0 = %d, %d cycles, %d frames", e.Registers[0], e.Cycle(), e.Frame())
+		}
+		if e.DelayTimer != 4 {
+			t.Errorf("Delay timer should tick once per frame, got %d", e.DelayTimer)
+		}
+		if changed {
+			t.Errorf("Display should not have changed")
+		}
+	})
+
+	t.Run("Reports display changes", func(t *testing.T) {
+		e := New()
+		e.LoadROMFromData(asm.MustAssemble(`
+			ld  i, 0x300
+			drw v0, v0, 1
+		wait:
+			jp  wait
+		`))
+		e.Memory[0x300] = 0x80
+
+		if changed, _ := e.RunFrame(); !changed {
+			t.Errorf("First frame draws, and should report the display changed")
+		}
+		if changed, _ := e.RunFrame(); changed {
+			t.Errorf("Second frame doesn't draw, and should not report a change")
+		}
+	})
+
+	t.Run("Display wait ends the frame", func(t *testing.T) {
+		e := New(WithPlatform(PlatformCosmacVIP), WithInstructionsPerFrame(10))
+		e.LoadROMFromData(asm.MustAssemble(`
+		loop:
+			drw v0, v0, 1
+			add v1, 1
+			jp  loop
+		`))
+
+		for range 3 {
+			e.RunFrame()
+		}
+		// each frame draws, then waits for the next frame
+		if e.Registers[1] != 2 {
+			t.Errorf("Expected 3 draws and V1 = 2 after 3 frames, got V1 = %d", e.Registers[1])
+		}
+	})
+}
+
+func TestUpdateTimers(t *testing.T) {
+	e := New()
+	e.DelayTimer = 10
+	e.SoundTimer = 1
+
+	// the remainder of each update carries over to the next
+	e.UpdateTimers(FrameDuration * 3 / 2)
+	e.UpdateTimers(FrameDuration * 3 / 2)
+	if e.DelayTimer != 7 {
+		t.Errorf("Two updates of 1.5 frames should tick 3 times, got delay timer %d", e.DelayTimer)
+	}
+	if e.SoundTimer != 0 {
+		t.Errorf("Sound timer should stop at 0, got %d", e.SoundTimer)
+	}
+
+	e.DelayTimer = 100
+	for range 120 {
+		e.UpdateTimers(FrameDuration / 2)
+	}
+	if e.DelayTimer != 40 {
+		t.Errorf("A second of half frame updates should tick 60 times, got delay timer %d", e.DelayTimer)
+	}
+}
+
+func TestStepFrame(t *testing.T) {
+	source := `
+		ld  v0, 30
+		ld  dt, v0
+	loop:
+		ld  v1, dt
+		drw v2, v2, 1
+		add v2, 1
+		jp  loop
+	`
+	for _, tt := range []struct {
+		name    string
+		options []EmulatorOption
+	}{
+		{"Fixed timing", []EmulatorOption{WithInstructionsPerFrame(7)}},
+		{"Display wait", []EmulatorOption{WithPlatform(PlatformCosmacVIP), WithInstructionsPerFrame(7)}},
+		{"VIP timing", []EmulatorOption{WithPlatform(PlatformCosmacVIP), WithTiming(TimingVIP)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := New(tt.options...)
+			frames.LoadROMFromData(asm.MustAssemble(source))
+			steps := New(tt.options...)
+			steps.LoadROMFromData(asm.MustAssemble(source))
+
+			for frame := range 20 {
+				if _, err := frames.RunFrame(); err != nil {
+					t.Fatalf("RunFrame returned error: %v", err)
+				}
+				for {
+					ended, err := steps.StepFrame()
+					if err != nil {
+						t.Fatalf("StepFrame returned error: %v", err)
+					}
+					if ended {
+						break
+					}
+				}
+				if steps.Cycle() != frames.Cycle() || steps.Frame() != frames.Frame() ||
+					steps.DelayTimer != frames.DelayTimer || steps.Registers != frames.Registers {
+					t.Fatalf("Frame %d: StepFrame should match RunFrame, got cycle %d frame %d DT %d, expected cycle %d frame %d DT %d",
+						frame, steps.Cycle(), steps.Frame(), steps.DelayTimer, frames.Cycle(), frames.Frame(), frames.DelayTimer)
+				}
+			}
+		})
+	}
+}
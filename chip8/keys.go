@@ -1,19 +1,94 @@
 package chip8
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
 
-func (e *Emulator) PressKey(key byte) error {
-	if key > 0xF {
-		return fmt.Errorf("invalid key: %X", key)
+// InputEvent is a key press or release, queued until the next instruction
+type InputEvent struct {
+	Key     byte
+	Pressed bool
+	Time    time.Time // when the key changed, queued events are applied in time order
+}
+
+// inputQueue buffers key events from any goroutine until the emulator
+// reaches an instruction boundary
+type inputQueue struct {
+	mu     sync.Mutex
+	events []InputEvent
+}
+
+// QueueInput queues a key event, to be applied before the next instruction.
+// It is safe to call from any goroutine, including while Run is executing.
+// Live input is ignored while a movie is playing, and recorded if a movie
+// is being recorded.
+func (e *Emulator) QueueInput(event InputEvent) error {
+	if event.Key > 0xF {
+		return fmt.Errorf("invalid key: %X", event.Key)
 	}
-	e.Keypad[key] = true
+	e.input.push(event)
 	return nil
 }
 
+// PressKey queues a press of key, see QueueInput
+func (e *Emulator) PressKey(key byte) error {
+	return e.QueueInput(InputEvent{Key: key, Pressed: true, Time: time.Now()})
+}
+
+// ReleaseKey queues a release of key, see QueueInput
 func (e *Emulator) ReleaseKey(key byte) error {
-	if key > 0xF {
-		return fmt.Errorf("invalid key: %X", key)
+	return e.QueueInput(InputEvent{Key: key, Pressed: false, Time: time.Now()})
+}
+
+// push adds an event after any queued at the same time or earlier
+func (q *inputQueue) push(event InputEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := len(q.events)
+	for i > 0 && q.events[i-1].Time.After(event.Time) {
+		i--
 	}
-	e.Keypad[key] = false
-	return nil
+	q.events = slices.Insert(q.events, i, event)
+}
+
+// clear drops all queued events
+func (q *inputQueue) clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.events = q.events[:0]
+}
+
+// applyInput updates Keypad from the queued events at an instruction
+// boundary. Application stops before an event for a key already changed at
+// this boundary, so a key pressed and released between two instructions is
+// still seen held by one of them. While a movie plays, live input is
+// dropped and the movie's events are applied instead.
+func (e *Emulator) applyInput() {
+	q := &e.input
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if e.playback != nil {
+		q.events = q.events[:0]
+		e.replayInput()
+		return
+	}
+
+	var changed uint16
+	applied := 0
+	for _, event := range q.events {
+		if changed&(1<<event.Key) != 0 {
+			break
+		}
+		applied++
+		if e.Keypad[event.Key] != event.Pressed {
+			e.recordKey(event.Key, event.Pressed)
+			e.Keypad[event.Key] = event.Pressed
+			changed |= 1 << event.Key
+		}
+	}
+	q.events = q.events[:copy(q.events, q.events[applied:])]
 }
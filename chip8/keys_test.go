@@ -0,0 +1,116 @@
+package chip8
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+)
+
+// keyCounter adds 1 to V0 for every loop where key 5 is held
+const keyCounter = `
+	ld v1, 5
+loop:
+	sknp v1
+	add  v0, 1
+	jp   loop
+`
+
+func TestInputQueue(t *testing.T) {
+	t.Run("Press and release between instructions is seen", func(t *testing.T) {
+		e := New()
+		e.LoadROMFromData(asm.MustAssemble(keyCounter))
+		e.Step(0)
+
+		e.PressKey(5)
+		e.ReleaseKey(5)
+		for range 9 {
+			e.Step(0)
+		}
+		if e.Registers[0] != 1 {
+			t.Errorf("Key should be seen held for one loop, counted %d", e.Registers[0])
+		}
+	})
+
+	t.Run("Events are applied in time order", func(t *testing.T) {
+		e := New()
+		start := time.Now()
+		e.QueueInput(InputEvent{Key: 3, Pressed: false, Time: start.Add(time.Millisecond)})
+		e.QueueInput(InputEvent{Key: 3, Pressed: true, Time: start})
+
+		e.Step(0)
+		if !e.Keypad[3] {
+			t.Error("Earlier press should be applied first")
+		}
+		e.Step(0)
+		if e.Keypad[3] {
+			t.Error("Later release should be applied on the next instruction")
+		}
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		e := New()
+		if err := e.QueueInput(InputEvent{Key: 0x10, Pressed: true}); err == nil {
+			t.Error("QueueInput should return error for invalid key 0x10")
+		}
+	})
+
+	t.Run("Replays a press and release within a frame", func(t *testing.T) {
+		e := New(WithSeed(1), WithInstructionsPerFrame(9))
+		rom := asm.MustAssemble(keyCounter)
+		e.LoadROMFromData(rom)
+		if err := e.StartRecording(); err != nil {
+			t.Fatalf("StartRecording returned error: %v", err)
+		}
+		e.RunFrame()
+		e.PressKey(5)
+		e.ReleaseKey(5)
+		e.RunFrame()
+		e.RunFrame()
+		movie := e.StopRecording()
+		if e.Registers[0] != 1 {
+			t.Fatalf("Key should be seen held for one loop, counted %d", e.Registers[0])
+		}
+
+		var buf bytes.Buffer
+		movie.Write(&buf)
+		movie, _ = ReadMovie(&buf)
+		replay := New(WithInstructionsPerFrame(9))
+		replay.LoadROMFromData(rom)
+		if err := replay.PlayMovie(movie); err != nil {
+			t.Fatalf("PlayMovie returned error: %v", err)
+		}
+		for range 3 {
+			replay.RunFrame()
+		}
+		if replay.Registers[0] != 1 {
+			t.Errorf("Replay should see the key held for one loop, counted %d", replay.Registers[0])
+		}
+	})
+
+	t.Run("Input from other goroutines while running", func(t *testing.T) {
+		e := New()
+		e.LoadROMFromData(asm.MustAssemble(keyCounter))
+		r := e.Run(context.Background())
+
+		var wg sync.WaitGroup
+		for key := range byte(4) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 20 {
+					e.PressKey(key)
+					e.ReleaseKey(key)
+				}
+			}()
+		}
+		wg.Wait()
+		waitForFrame(t, r, 2)
+		if err := r.Stop(); err != nil {
+			t.Errorf("Stop returned error: %v", err)
+		}
+	})
+}
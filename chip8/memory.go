@@ -0,0 +1,131 @@
+package chip8
+
+import "fmt"
+
+// AccessKind identifies how a program touched memory
+type AccessKind int
+
+const (
+	AccessFetch AccessKind = iota // instruction fetch, including the F000 NNNN long address
+	AccessRead                    // data read, e.g. FX65, sprite data for DXYN
+	AccessWrite                   // data write, e.g. FX33, FX55
+)
+
+func (k AccessKind) String() string {
+	switch k {
+	case AccessFetch:
+		return "fetch"
+	case AccessRead:
+		return "read"
+	case AccessWrite:
+		return "write"
+	}
+	return fmt.Sprintf("AccessKind(%d)", int(k))
+}
+
+// MemoryAccess describes a single byte of memory read or written by the program
+type MemoryAccess struct {
+	Kind    AccessKind
+	Address uint16
+	Value   byte   // byte read, or the new value written
+	PC      uint16 // address of the instruction that made the access
+}
+
+func (a MemoryAccess) String() string {
+	return fmt.Sprintf("%s of 0x%02X at 0x%04X by instruction at 0x%04X", a.Kind, a.Value, a.Address, a.PC)
+}
+
+// MemoryObserver is called for every memory access made while executing instructions
+type MemoryObserver func(MemoryAccess)
+
+// WatchKind selects which accesses trigger a watchpoint
+type WatchKind int
+
+const (
+	WatchRead      WatchKind = 1 << iota // data reads
+	WatchWrite                           // data writes
+	WatchReadWrite = WatchRead | WatchWrite
+)
+
+// WatchpointError is returned by Step, after the instruction has completed,
+// when the instruction accessed a watched address
+type WatchpointError struct {
+	Access MemoryAccess
+}
+
+func (e *WatchpointError) Error() string {
+	return fmt.Sprintf("watchpoint hit: %s", e.Access)
+}
+
+// WithMemoryObserver sets a callback fired on every memory access, see SetMemoryObserver
+func WithMemoryObserver(observer MemoryObserver) EmulatorOption {
+	return func(e *Emulator) {
+		e.SetMemoryObserver(observer)
+	}
+}
+
+// SetMemoryObserver sets a callback fired on every memory access made by
+// executing instructions, replacing any previous observer. Pass nil to remove it.
+func (e *Emulator) SetMemoryObserver(observer MemoryObserver) {
+	e.memoryObserver = observer
+}
+
+// Watch sets a watchpoint on an address, replacing any existing watchpoint there
+func (e *Emulator) Watch(address uint16, kind WatchKind) {
+	if e.watchpoints == nil {
+		e.watchpoints = make(map[uint16]WatchKind)
+	}
+	e.watchpoints[address] = kind
+}
+
+// Unwatch removes the watchpoint on an address
+func (e *Emulator) Unwatch(address uint16) {
+	delete(e.watchpoints, address)
+}
+
+// Watchpoints returns the watched addresses and what triggers each of them
+func (e *Emulator) Watchpoints() map[uint16]WatchKind {
+	watchpoints := make(map[uint16]WatchKind, len(e.watchpoints))
+	for address, kind := range e.watchpoints {
+		watchpoints[address] = kind
+	}
+	return watchpoints
+}
+
+// readMemory returns the byte at address. All memory reads made by
+// instructions go through here so they can be observed and watched.
+func (e *Emulator) readMemory(address uint16, kind AccessKind) byte {
+	value := e.Memory[address]
+	e.observe(MemoryAccess{Kind: kind, Address: address, Value: value, PC: e.instructionPC})
+	return value
+}
+
+// writeMemory stores value at address. All memory writes made by
+// instructions go through here so they can be observed and watched.
+func (e *Emulator) writeMemory(address uint16, value byte) {
+	e.Memory[address] = value
+	e.observe(MemoryAccess{Kind: AccessWrite, Address: address, Value: value, PC: e.instructionPC})
+}
+
+func (e *Emulator) observe(access MemoryAccess) {
+	if e.memoryObserver != nil {
+		e.memoryObserver(access)
+	}
+	if e.watchHit != nil || len(e.watchpoints) == 0 {
+		return
+	}
+
+	kind, ok := e.watchpoints[access.Address]
+	switch {
+	case !ok:
+	case access.Kind == AccessRead && kind&WatchRead != 0,
+		access.Kind == AccessWrite && kind&WatchWrite != 0:
+		// only the first hit is reported, the instruction still runs to completion
+		e.watchHit = &WatchpointError{Access: access}
+	}
+}
+
+// readOpcode returns the two byte big-endian opcode at address
+func (e *Emulator) readOpcode(address uint16) uint16 {
+	return uint16(e.readMemory(address, AccessFetch))<<8 | uint16(e.readMemory(address+1, AccessFetch))
+}
@@ -0,0 +1,139 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryObserver(t *testing.T) {
+	t.Run("Reports fetches, reads and writes", func(t *testing.T) {
+		var accesses []MemoryAccess
+		e := New(WithMemoryObserver(func(a MemoryAccess) {
+			accesses = append(accesses, a)
+		}))
+		// 0xF133 - store BCD of V1 at I
+		e.Memory[0x200] = 0xF1
+		e.Memory[0x201] = 0x33
+		e.Registers[1] = 123
+		e.I = 0x300
+
+		e.Step(0)
+
+		expected := []MemoryAccess{
+			{Kind: AccessFetch, Address: 0x200, Value: 0xF1, PC: 0x200},
+			{Kind: AccessFetch, Address: 0x201, Value: 0x33, PC: 0x200},
+			{Kind: AccessWrite, Address: 0x300, Value: 1, PC: 0x200},
+			{Kind: AccessWrite, Address: 0x301, Value: 2, PC: 0x200},
+			{Kind: AccessWrite, Address: 0x302, Value: 3, PC: 0x200},
+		}
+		if len(accesses) != len(expected) {
+			t.Fatalf("Expected %d accesses, got %d: %v", len(expected), len(accesses), accesses)
+		}
+		for i := range expected {
+			if accesses[i] != expected[i] {
+				t.Errorf("Access %d should be %v, got %v", i, expected[i], accesses[i])
+			}
+		}
+	})
+
+	t.Run("Skipped instructions are not fetched", func(t *testing.T) {
+		for _, variant := range []Variant{VariantChip8, VariantXOChip} {
+			var fetches []uint16
+			e := New(WithVariant(variant), WithMemoryObserver(func(a MemoryAccess) {
+				if a.Kind == AccessFetch {
+					fetches = append(fetches, a.Address)
+				}
+			}))
+			// 0x3000 - skip if V0 == 0, over an XO-CHIP F000 NNNN long load
+			e.Memory[0x200] = 0x30
+			e.Memory[0x201] = 0x00
+			e.Memory[0x202] = 0xF0
+			e.Memory[0x203] = 0x00
+
+			e.Step(0)
+
+			if len(fetches) != 2 || fetches[0] != 0x200 || fetches[1] != 0x201 {
+				t.Errorf("%v: skip should only fetch its own opcode, got fetches at %X", variant, fetches)
+			}
+		}
+	})
+
+	t.Run("Sprite data is read once per byte", func(t *testing.T) {
+		reads := 0
+		e := New(WithMemoryObserver(func(a MemoryAccess) {
+			if a.Kind == AccessRead {
+				reads++
+			}
+		}))
+		// 0xD015 - draw 5 byte sprite
+		e.Memory[0x200] = 0xD0
+		e.Memory[0x201] = 0x15
+		e.I = FontStartAddress
+
+		e.Step(0)
+
+		if reads != 5 {
+			t.Errorf("Drawing a 5 byte sprite should read 5 bytes, got %d", reads)
+		}
+	})
+}
+
+func TestWatchpoints(t *testing.T) {
+	t.Run("Write watchpoint halts after instruction", func(t *testing.T) {
+		e := New()
+		// 0xF255 - store V0-V2 at I
+		e.Memory[0x200] = 0xF2
+		e.Memory[0x201] = 0x55
+		e.Registers = [RegisterCount]byte{0xA, 0xB, 0xC}
+		e.I = 0x300
+		e.Watch(0x301, WatchWrite)
+
+		err := e.Step(0)
+
+		var hit *WatchpointError
+		if !errors.As(err, &hit) {
+			t.Fatalf("Step should return WatchpointError, got %v", err)
+		}
+		expected := MemoryAccess{Kind: AccessWrite, Address: 0x301, Value: 0xB, PC: 0x200}
+		if hit.Access != expected {
+			t.Errorf("Watchpoint access should be %v, got %v", expected, hit.Access)
+		}
+		if e.Memory[0x302] != 0xC || e.PC != 0x202 {
+			t.Errorf("Instruction should complete before halting")
+		}
+	})
+
+	t.Run("Read watchpoint ignores writes", func(t *testing.T) {
+		e := New()
+		// 0xF055 - store V0 at I, 0xF065 - load V0 from I
+		copy(e.Memory[0x200:], []byte{0xF0, 0x55, 0xF0, 0x65})
+		e.I = 0x300
+		e.Config.Quirks.StoreLoadIncrementsI = false
+		e.Watch(0x300, WatchRead)
+
+		if err := e.Step(0); err != nil {
+			t.Errorf("Write should not trigger read watchpoint, got %v", err)
+		}
+		var hit *WatchpointError
+		if err := e.Step(0); !errors.As(err, &hit) || hit.Access.Kind != AccessRead {
+			t.Errorf("Read should trigger read watchpoint, got %v", err)
+		}
+	})
+
+	t.Run("Unwatch", func(t *testing.T) {
+		e := New()
+		// 0xF055 - store V0 at I
+		e.Memory[0x200] = 0xF0
+		e.Memory[0x201] = 0x55
+		e.I = 0x300
+		e.Watch(0x300, WatchReadWrite)
+		e.Unwatch(0x300)
+
+		if err := e.Step(0); err != nil {
+			t.Errorf("Removed watchpoint should not trigger, got %v", err)
+		}
+		if len(e.Watchpoints()) != 0 {
+			t.Errorf("Watchpoints should be empty, got %v", e.Watchpoints())
+		}
+	})
+}
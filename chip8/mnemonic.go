@@ -0,0 +1,129 @@
+package chip8
+
+import "fmt"
+
+// Mnemonic returns the assembly for an instruction in the common Cowgod
+// syntax accepted by the asm package, e.g. "LD V1, 0x05" or "DRW V0, V1, 5".
+// Unlike GetCurrentOpcode it depends only on the instruction, not on
+// emulator state. long is the address following an F000 opcode. Opcodes
+// which are not valid for the variant give "???".
+func Mnemonic(opcode uint16, long uint16, variant Variant) string {
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+	nn := opcode & 0x00FF
+	nnn := opcode & 0x0FFF
+
+	superChip := variant >= VariantSuperChip
+	xoChip := variant >= VariantXOChip
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opcode == 0x00E0:
+			return "CLS"
+		case opcode == 0x00EE:
+			return "RET"
+		case !superChip:
+			return fmt.Sprintf("SYS 0x%03X", nnn)
+		case opcode&0xFFF0 == 0x00C0:
+			return fmt.Sprintf("SCD %d", n)
+		case opcode&0xFFF0 == 0x00D0 && xoChip:
+			return fmt.Sprintf("SCU %d", n)
+		case opcode == 0x00FB:
+			return "SCR"
+		case opcode == 0x00FC:
+			return "SCL"
+		case opcode == 0x00FD:
+			return "EXIT"
+		case opcode == 0x00FE:
+			return "LOW"
+		case opcode == 0x00FF:
+			return "HIGH"
+		}
+	case 0x1000:
+		return fmt.Sprintf("JP 0x%03X", nnn)
+	case 0x2000:
+		return fmt.Sprintf("CALL 0x%03X", nnn)
+	case 0x3000:
+		return fmt.Sprintf("SE V%X, 0x%02X", x, nn)
+	case 0x4000:
+		return fmt.Sprintf("SNE V%X, 0x%02X", x, nn)
+	case 0x5000:
+		switch {
+		case n == 0:
+			return fmt.Sprintf("SE V%X, V%X", x, y)
+		case n == 2 && xoChip:
+			return fmt.Sprintf("SAVE V%X, V%X", x, y)
+		case n == 3 && xoChip:
+			return fmt.Sprintf("LOAD V%X, V%X", x, y)
+		}
+	case 0x6000:
+		return fmt.Sprintf("LD V%X, 0x%02X", x, nn)
+	case 0x7000:
+		return fmt.Sprintf("ADD V%X, 0x%02X", x, nn)
+	case 0x8000:
+		names := map[uint16]string{
+			0x0: "LD", 0x1: "OR", 0x2: "AND", 0x3: "XOR", 0x4: "ADD",
+			0x5: "SUB", 0x6: "SHR", 0x7: "SUBN", 0xE: "SHL",
+		}
+		if name, ok := names[n]; ok {
+			return fmt.Sprintf("%s V%X, V%X", name, x, y)
+		}
+	case 0x9000:
+		if n == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", x, y)
+		}
+	case 0xA000:
+		return fmt.Sprintf("LD I, 0x%03X", nnn)
+	case 0xB000:
+		return fmt.Sprintf("JP V0, 0x%03X", nnn)
+	case 0xC000:
+		return fmt.Sprintf("RND V%X, 0x%02X", x, nn)
+	case 0xD000:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n)
+	case 0xE000:
+		switch nn {
+		case 0x9E:
+			return fmt.Sprintf("SKP V%X", x)
+		case 0xA1:
+			return fmt.Sprintf("SKNP V%X", x)
+		}
+	case 0xF000:
+		switch {
+		case opcode == 0xF000 && xoChip:
+			return fmt.Sprintf("LD I, LONG 0x%04X", long)
+		case nn == 0x01 && x <= 3 && xoChip:
+			return fmt.Sprintf("PLANE %d", x)
+		case opcode == 0xF002 && xoChip:
+			return "AUDIO"
+		case nn == 0x07:
+			return fmt.Sprintf("LD V%X, DT", x)
+		case nn == 0x0A:
+			return fmt.Sprintf("LD V%X, K", x)
+		case nn == 0x15:
+			return fmt.Sprintf("LD DT, V%X", x)
+		case nn == 0x18:
+			return fmt.Sprintf("LD ST, V%X", x)
+		case nn == 0x1E:
+			return fmt.Sprintf("ADD I, V%X", x)
+		case nn == 0x29:
+			return fmt.Sprintf("LD F, V%X", x)
+		case nn == 0x30 && superChip:
+			return fmt.Sprintf("LD HF, V%X", x)
+		case nn == 0x33:
+			return fmt.Sprintf("LD B, V%X", x)
+		case nn == 0x3A && xoChip:
+			return fmt.Sprintf("PITCH V%X", x)
+		case nn == 0x55:
+			return fmt.Sprintf("LD [I], V%X", x)
+		case nn == 0x65:
+			return fmt.Sprintf("LD V%X, [I]", x)
+		case nn == 0x75 && superChip:
+			return fmt.Sprintf("LD R, V%X", x)
+		case nn == 0x85 && superChip:
+			return fmt.Sprintf("LD V%X, R", x)
+		}
+	}
+	return "???"
+}
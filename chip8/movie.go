@@ -0,0 +1,264 @@
+package chip8
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Movie file format identification
+const (
+	movieMagic   = "C8MV"
+	MovieVersion = 3 // Version of the movie format written by Movie.Write
+
+	// maxStateSize is larger than any save state, to reject corrupt sizes
+	maxStateSize = 1 << 20
+)
+
+var (
+	// ErrMovieFormat is returned by ReadMovie when the data is not a movie
+	ErrMovieFormat = errors.New("not a movie file")
+	// ErrMovieVersion is returned by ReadMovie when the movie was written by
+	// an incompatible version of the format
+	ErrMovieVersion = errors.New("unsupported movie version")
+)
+
+// Movie is a recording of key input, which replays a run of the emulator
+// exactly. It starts from a snapshot of the emulator, which includes the
+// configuration, seed and position of the random number generator. Movies
+// count frames, so the emulator must be run with RunFrame or StepFrame while
+// recording or playing one.
+type Movie struct {
+	Platform             Platform
+	Seed                 int64 // seed of the random number generator when recording started
+	InstructionsPerFrame int
+	ROMHash              [sha1.Size]byte
+	Length               uint64     // number of frames recorded
+	Events               []KeyEvent // key changes in cycle order
+	Start                []byte     // save state the movie starts from
+}
+
+// KeyEvent is a key press or release, applied before the instruction that
+// first saw it when recorded
+type KeyEvent struct {
+	Cycle   uint64 // instruction number, counted from the start of the movie
+	Key     byte
+	Pressed bool
+}
+
+// movieHeader is the fixed size start of a movie file, followed by the
+// start state and the events
+type movieHeader struct {
+	Magic                [4]byte
+	Version              uint16
+	Platform             uint8
+	Seed                 int64
+	InstructionsPerFrame uint32
+	ROMHash              [sha1.Size]byte
+	Length               uint64
+	StartSize            uint32
+	EventCount           uint32
+}
+
+// movieEvent is the file representation of a KeyEvent
+type movieEvent struct {
+	Cycle   uint64
+	Key     uint8
+	Pressed bool
+}
+
+// movieRecorder records key events into a movie
+type movieRecorder struct {
+	movie      *Movie
+	start      uint64 // emulator frame the movie started on
+	startCycle uint64 // emulator cycle the movie started on
+}
+
+// moviePlayer replays a movie's events
+type moviePlayer struct {
+	movie      *Movie
+	start      uint64 // emulator frame the movie started on
+	startCycle uint64 // emulator cycle the movie started on
+	next       int    // index of next event
+}
+
+// StartRecording begins recording key input into a new movie, starting from
+// the current state
+func (e *Emulator) StartRecording() error {
+	if e.Config.InstructionsPerFrame <= 0 {
+		return fmt.Errorf("invalid instructions per frame: %d", e.Config.InstructionsPerFrame)
+	}
+	if e.playback != nil {
+		return fmt.Errorf("cannot record while playing a movie")
+	}
+
+	var start bytes.Buffer
+	if err := e.SaveState(&start); err != nil {
+		return fmt.Errorf("failed to save movie start state: %w", err)
+	}
+	e.recording = &movieRecorder{
+		movie: &Movie{
+			Platform:             e.Config.Platform,
+			Seed:                 e.Config.randSeed,
+			InstructionsPerFrame: e.Config.InstructionsPerFrame,
+			ROMHash:              e.romHash,
+			Start:                start.Bytes(),
+		},
+		start:      e.frame,
+		startCycle: e.cycle,
+	}
+	return nil
+}
+
+// StopRecording ends recording and returns the movie, or nil if not recording
+func (e *Emulator) StopRecording() *Movie {
+	if e.recording == nil {
+		return nil
+	}
+	movie := e.recording.movie
+	movie.Length = e.frame - e.recording.start
+	e.recording = nil
+	return movie
+}
+
+// Recording reports whether key input is being recorded
+func (e *Emulator) Recording() bool {
+	return e.recording != nil
+}
+
+// PlayMovie restores the movie's start state and replays its key input over
+// the following Length frames, setting Config.InstructionsPerFrame to match
+// the recording. The movie's ROM must be loaded. While playing, PressKey and
+// ReleaseKey are ignored.
+func (e *Emulator) PlayMovie(movie *Movie) error {
+	if e.recording != nil {
+		return fmt.Errorf("cannot play a movie while recording")
+	}
+	if movie.InstructionsPerFrame <= 0 {
+		return fmt.Errorf("%w: invalid instructions per frame %d", ErrMovieFormat, movie.InstructionsPerFrame)
+	}
+	if err := e.LoadState(bytes.NewReader(movie.Start)); err != nil {
+		return fmt.Errorf("failed to load movie start state: %w", err)
+	}
+	e.Config.InstructionsPerFrame = movie.InstructionsPerFrame
+	e.input.clear()
+	e.playback = &moviePlayer{movie: movie, start: e.frame, startCycle: e.cycle}
+	return nil
+}
+
+// Playing reports whether a movie is being played
+func (e *Emulator) Playing() bool {
+	return e.playback != nil
+}
+
+// StopPlayback ends movie playback, returning control of the keypad
+func (e *Emulator) StopPlayback() {
+	e.playback = nil
+}
+
+// movieFrame ends playback before the first frame after the movie
+func (e *Emulator) movieFrame() {
+	if p := e.playback; p != nil && e.frame-p.start >= p.movie.Length {
+		// movie over, continue with live input
+		e.playback = nil
+	}
+}
+
+// replayInput applies the movie events recorded at the current instruction
+// boundary to Keypad, so they are seen by the same instruction as when recorded
+func (e *Emulator) replayInput() {
+	p := e.playback
+	cycle := e.cycle - p.startCycle
+	for p.next < len(p.movie.Events) && p.movie.Events[p.next].Cycle <= cycle {
+		event := p.movie.Events[p.next]
+		e.Keypad[event.Key&0xF] = event.Pressed
+		p.next++
+	}
+}
+
+// recordKey records a key change applied to the keypad at the current
+// instruction boundary, if recording
+func (e *Emulator) recordKey(key byte, pressed bool) {
+	if r := e.recording; r != nil {
+		r.movie.Events = append(r.movie.Events, KeyEvent{Cycle: e.cycle - r.startCycle, Key: key, Pressed: pressed})
+	}
+}
+
+// Write writes the movie to w
+func (m *Movie) Write(w io.Writer) error {
+	header := movieHeader{
+		Version:              MovieVersion,
+		Platform:             uint8(m.Platform),
+		Seed:                 m.Seed,
+		InstructionsPerFrame: uint32(m.InstructionsPerFrame),
+		ROMHash:              m.ROMHash,
+		Length:               m.Length,
+		StartSize:            uint32(len(m.Start)),
+		EventCount:           uint32(len(m.Events)),
+	}
+	copy(header.Magic[:], movieMagic)
+
+	events := make([]movieEvent, len(m.Events))
+	for i, event := range m.Events {
+		events[i] = movieEvent{Cycle: event.Cycle, Key: event.Key, Pressed: event.Pressed}
+	}
+
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("failed to write movie header: %w", err)
+	}
+	if _, err := w.Write(m.Start); err != nil {
+		return fmt.Errorf("failed to write movie start state: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, events); err != nil {
+		return fmt.Errorf("failed to write movie events: %w", err)
+	}
+	return nil
+}
+
+// ReadMovie reads a movie written by Movie.Write
+func ReadMovie(r io.Reader) (*Movie, error) {
+	var header movieHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read movie header: %w", err)
+	}
+	if string(header.Magic[:]) != movieMagic {
+		return nil, ErrMovieFormat
+	}
+	if header.Version != MovieVersion {
+		return nil, fmt.Errorf("%w: %d (expected %d)", ErrMovieVersion, header.Version, MovieVersion)
+	}
+
+	if header.StartSize > maxStateSize {
+		return nil, fmt.Errorf("%w: start state size %d", ErrMovieFormat, header.StartSize)
+	}
+	start := make([]byte, header.StartSize)
+	if _, err := io.ReadFull(r, start); err != nil {
+		return nil, fmt.Errorf("failed to read movie start state: %w", err)
+	}
+
+	// read events one at a time, so a corrupt count can't allocate huge amounts of memory
+	var events []KeyEvent
+	for range header.EventCount {
+		var event movieEvent
+		if err := binary.Read(r, binary.BigEndian, &event); err != nil {
+			return nil, fmt.Errorf("failed to read movie events: %w", err)
+		}
+		if event.Key > 0xF {
+			return nil, fmt.Errorf("%w: invalid key %X", ErrMovieFormat, event.Key)
+		}
+		events = append(events, KeyEvent{Cycle: event.Cycle, Key: event.Key, Pressed: event.Pressed})
+	}
+
+	return &Movie{
+		Platform:             Platform(header.Platform),
+		Seed:                 header.Seed,
+		InstructionsPerFrame: int(header.InstructionsPerFrame),
+		ROMHash:              header.ROMHash,
+		Length:               header.Length,
+		Events:               events,
+		Start:                start,
+	}, nil
+}
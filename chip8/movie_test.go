@@ -0,0 +1,148 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// movieTestROM draws a random sprite at a position moved by keys 4 and 6
+// and waits on the delay timer between frames
+var movieTestROM = []byte{
+	0x60, 0x00, // 0x200: V0 = 0
+	0x64, 0x04, // 0x202: V4 = 4
+	0x66, 0x06, // 0x204: V6 = 6
+	0xE4, 0xA1, // 0x206: skip if key V4 not pressed
+	0x70, 0xFF, // 0x208: V0 -= 1
+	0xE6, 0xA1, // 0x20A: skip if key V6 not pressed
+	0x70, 0x01, // 0x20C: V0 += 1
+	0xC1, 0x0F, // 0x20E: V1 = random & 0x0F
+	0xF1, 0x29, // 0x210: I = font for V1
+	0xD0, 0x15, // 0x212: draw at V0, V0
+	0x62, 0x01, // 0x214: V2 = 1
+	0xF2, 0x15, // 0x216: delay = V2
+	0xF2, 0x07, // 0x218: V2 = delay
+	0x32, 0x00, // 0x21A: skip if V2 == 0
+	0x12, 0x18, // 0x21C: jump 0x218
+	0x12, 0x06, // 0x21E: jump 0x206
+}
+
+func TestMovie(t *testing.T) {
+	record := func(t *testing.T) (*Emulator, *Movie) {
+		t.Helper()
+		e := New(WithSeed(42))
+		if err := e.LoadROMFromData(movieTestROM); err != nil {
+			t.Fatalf("LoadROMFromData returned error: %v", err)
+		}
+		// run a while before recording, so the movie doesn't start from reset
+		for range 5 {
+			e.RunFrame()
+		}
+		if err := e.StartRecording(); err != nil {
+			t.Fatalf("StartRecording returned error: %v", err)
+		}
+		for frame := range 200 {
+			switch frame {
+			case 10:
+				e.PressKey(6)
+			case 40:
+				e.ReleaseKey(6)
+				e.PressKey(4)
+			case 45:
+				e.ReleaseKey(4)
+			}
+			e.RunFrame()
+		}
+		return e, e.StopRecording()
+	}
+
+	t.Run("Replay is exact", func(t *testing.T) {
+		recorded, movie := record(t)
+		if movie.Length != 200 || len(movie.Events) != 4 || movie.Seed != 42 {
+			t.Fatalf("Movie should have 200 frames, 4 events and seed 42, got %d, %d, %d", movie.Length, len(movie.Events), movie.Seed)
+		}
+
+		var buf bytes.Buffer
+		if err := movie.Write(&buf); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		loaded, err := ReadMovie(&buf)
+		if err != nil {
+			t.Fatalf("ReadMovie returned error: %v", err)
+		}
+
+		e := New(WithInstructionsPerFrame(3))
+		e.LoadROMFromData(movieTestROM)
+		if err := e.PlayMovie(loaded); err != nil {
+			t.Fatalf("PlayMovie returned error: %v", err)
+		}
+		for range movie.Length {
+			// live input is ignored during playback
+			e.PressKey(4)
+			e.RunFrame()
+		}
+
+		if e.Display != recorded.Display || e.Registers != recorded.Registers || e.PC != recorded.PC {
+			t.Errorf("Replayed state differs from recording")
+		}
+		if e.Cycle() != recorded.Cycle() {
+			t.Errorf("Cycle should be %d, got %d", recorded.Cycle(), e.Cycle())
+		}
+
+		e.RunFrame()
+		if e.Playing() {
+			t.Errorf("Playback should end after the movie length")
+		}
+	})
+
+	t.Run("Input during a frame replays at the same instruction", func(t *testing.T) {
+		// counts instructions in V0 until key 5 is pressed
+		rom := []byte{
+			0x61, 0x05, // 0x200: V1 = 5
+			0x70, 0x01, // 0x202: V0 += 1
+			0xE1, 0xA1, // 0x204: skip if key V1 not pressed
+			0x12, 0x06, // 0x206: jump 0x206
+			0x12, 0x02, // 0x208: jump 0x202
+		}
+		e := New()
+		e.LoadROMFromData(rom)
+		if err := e.StartRecording(); err != nil {
+			t.Fatalf("StartRecording returned error: %v", err)
+		}
+		e.RunFrame()
+		for range 5 {
+			e.StepFrame()
+		}
+		e.PressKey(5)
+		for range 3 {
+			e.RunFrame()
+		}
+		movie := e.StopRecording()
+		recorded := e.Registers[0]
+
+		e = New()
+		e.LoadROMFromData(rom)
+		if err := e.PlayMovie(movie); err != nil {
+			t.Fatalf("PlayMovie returned error: %v", err)
+		}
+		for range movie.Length {
+			e.RunFrame()
+		}
+		if e.Registers[0] != recorded {
+			t.Errorf("V0 should be %d as recorded, got %d", recorded, e.Registers[0])
+		}
+	})
+
+	t.Run("Invalid movies", func(t *testing.T) {
+		_, movie := record(t)
+		if _, err := ReadMovie(bytes.NewReader(movie.Start)); !errors.Is(err, ErrMovieFormat) {
+			t.Errorf("Save state should return ErrMovieFormat, got %v", err)
+		}
+
+		e := New()
+		e.LoadROMFromData([]byte{0x12, 0x00})
+		if err := e.PlayMovie(movie); !errors.Is(err, ErrStateROMMismatch) {
+			t.Errorf("Playing a movie for another ROM should return ErrStateROMMismatch, got %v", err)
+		}
+	})
+}
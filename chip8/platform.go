@@ -0,0 +1,180 @@
+package chip8
+
+import "fmt"
+
+// Quirks are the behaviour differences between CHIP-8 implementations.
+// Each platform preset sets all of them at once, see Platform.
+type Quirks struct {
+	ShiftUsesVY          bool // 8XY6/8XYE copy VY into VX before shifting
+	JumpUsesV0           bool // BNNN jumps to NNN + V0, rather than XNN + VX
+	StoreLoadIncrementsI bool // FX55/FX65 leave I pointing past the last register
+	VFReset              bool // 8XY1/8XY2/8XY3 reset VF to 0
+	DisplayWait          bool // DXYN waits for the 60Hz vertical blank before continuing
+	WrapSprites          bool // sprites wrap around the screen edges instead of being clipped
+	IndexOverflowVF      bool // FX1E sets VF when I overflows past 0x0FFF
+	LowResBigSprite8x16  bool // DXY0 draws an 8x16 sprite in low resolution, rather than 16x16
+	LowResHalfScroll     bool // scrolling in low resolution moves half as far, as the HP48 scrolls by high resolution pixels
+}
+
+// Platform is a named preset of instruction set and quirks, matching a
+// historical or widely emulated CHIP-8 implementation.
+type Platform int
+
+const (
+	PlatformCosmacVIP       Platform = iota // original CHIP-8 interpreter on the COSMAC VIP (1977)
+	PlatformChip48                          // CHIP-48 on the HP48 calculator (1990)
+	PlatformSuperChip10                     // SUPER-CHIP 1.0 on the HP48 (1991)
+	PlatformSuperChip11                     // SUPER-CHIP 1.1 on the HP48 (1991)
+	PlatformModernSuperChip                 // SUPER-CHIP as implemented by modern emulators such as Octo
+	PlatformXOChip                          // XO-CHIP as defined by Octo (2014)
+	PlatformModernChip8                     // CHIP-8 as implemented by modern emulators
+)
+
+// Profile describes the instruction set and quirks of a platform preset
+type Profile struct {
+	Name        string // short name, as accepted by ParsePlatform
+	Description string
+	Variant     Variant
+	Quirks      Quirks
+}
+
+var profiles = map[Platform]Profile{
+	PlatformCosmacVIP: {
+		Name:        "vip",
+		Description: "COSMAC VIP",
+		Variant:     VariantChip8,
+		Quirks: Quirks{
+			ShiftUsesVY:          true,
+			JumpUsesV0:           true,
+			StoreLoadIncrementsI: true,
+			VFReset:              true,
+			DisplayWait:          true,
+		},
+	},
+	PlatformChip48: {
+		Name:        "chip48",
+		Description: "CHIP-48",
+		Variant:     VariantChip8,
+		Quirks: Quirks{
+			StoreLoadIncrementsI: true,
+		},
+	},
+	PlatformSuperChip10: {
+		Name:        "schip10",
+		Description: "SUPER-CHIP 1.0",
+		Variant:     VariantSuperChip,
+		Quirks: Quirks{
+			StoreLoadIncrementsI: true,
+			LowResBigSprite8x16:  true,
+			LowResHalfScroll:     true,
+		},
+	},
+	PlatformSuperChip11: {
+		Name:        "schip11",
+		Description: "SUPER-CHIP 1.1",
+		Variant:     VariantSuperChip,
+		Quirks: Quirks{
+			LowResBigSprite8x16: true,
+			LowResHalfScroll:    true,
+		},
+	},
+	PlatformModernChip8: {
+		Name:        "chip8",
+		Description: "Modern CHIP-8",
+		Variant:     VariantChip8,
+		Quirks: Quirks{
+			ShiftUsesVY:          true,
+			JumpUsesV0:           true,
+			StoreLoadIncrementsI: true,
+		},
+	},
+	PlatformModernSuperChip: {
+		Name:        "schip",
+		Description: "Modern SUPER-CHIP",
+		Variant:     VariantSuperChip,
+		Quirks:      Quirks{},
+	},
+	PlatformXOChip: {
+		Name:        "xochip",
+		Description: "XO-CHIP",
+		Variant:     VariantXOChip,
+		Quirks: Quirks{
+			ShiftUsesVY:          true,
+			JumpUsesV0:           true,
+			StoreLoadIncrementsI: true,
+			WrapSprites:          true,
+		},
+	},
+}
+
+// DefaultPlatform is the platform used by New when WithPlatform is not given
+const DefaultPlatform = PlatformModernSuperChip
+
+// Platforms returns all platform presets, oldest first
+func Platforms() []Platform {
+	return []Platform{
+		PlatformCosmacVIP,
+		PlatformChip48,
+		PlatformSuperChip10,
+		PlatformSuperChip11,
+		PlatformModernChip8,
+		PlatformModernSuperChip,
+		PlatformXOChip,
+	}
+}
+
+// Profile returns the instruction set and quirks of the platform
+func (p Platform) Profile() Profile {
+	return profiles[p]
+}
+
+// String returns the short name of the platform, as accepted by ParsePlatform
+func (p Platform) String() string {
+	if profile, ok := profiles[p]; ok {
+		return profile.Name
+	}
+	return fmt.Sprintf("Platform(%d)", int(p))
+}
+
+// ParsePlatform returns the platform with the given short name
+func ParsePlatform(name string) (Platform, error) {
+	for _, p := range Platforms() {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown platform: %q", name)
+}
+
+// PlatformNames returns the short names of all platforms, for use in help text
+func PlatformNames() []string {
+	names := make([]string, 0, len(profiles))
+	for _, p := range Platforms() {
+		names = append(names, p.String())
+	}
+	return names
+}
+
+// WithPlatform configures the emulator to behave like the given platform,
+// setting the instruction set variant and every quirk at once.
+func WithPlatform(platform Platform) EmulatorOption {
+	return func(e *Emulator) {
+		e.Config.SetPlatform(platform)
+	}
+}
+
+// WithQuirks overrides the quirks of the configured platform.
+// Options are applied in order, so this should follow WithPlatform.
+func WithQuirks(quirks Quirks) EmulatorOption {
+	return func(e *Emulator) {
+		e.Config.Quirks = quirks
+	}
+}
+
+// SetPlatform sets the instruction set variant and quirks to the platform preset
+func (c *EmulatorConfig) SetPlatform(platform Platform) {
+	profile := platform.Profile()
+	c.Platform = platform
+	c.Variant = profile.Variant
+	c.Quirks = profile.Quirks
+}
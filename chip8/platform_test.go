@@ -0,0 +1,207 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlatforms(t *testing.T) {
+	t.Run("Parse platform names", func(t *testing.T) {
+		for _, platform := range Platforms() {
+			parsed, err := ParsePlatform(platform.String())
+			if err != nil {
+				t.Errorf("ParsePlatform(%q) returned error: %v", platform.String(), err)
+			}
+			if parsed != platform {
+				t.Errorf("ParsePlatform(%q) should return %v, got %v", platform.String(), platform, parsed)
+			}
+		}
+
+		if _, err := ParsePlatform("gameboy"); err == nil {
+			t.Errorf("ParsePlatform should return error for unknown platform")
+		}
+	})
+
+	t.Run("WithPlatform sets variant and quirks", func(t *testing.T) {
+		e := New(WithPlatform(PlatformXOChip))
+
+		if e.Config.Platform != PlatformXOChip {
+			t.Errorf("Platform should be %v, got %v", PlatformXOChip, e.Config.Platform)
+		}
+		if e.Config.Variant != VariantXOChip {
+			t.Errorf("Variant should be %v, got %v", VariantXOChip, e.Config.Variant)
+		}
+		if e.Config.Quirks != PlatformXOChip.Profile().Quirks {
+			t.Errorf("Quirks should match XO-CHIP profile, got %+v", e.Config.Quirks)
+		}
+	})
+
+	t.Run("Presets differ", func(t *testing.T) {
+		seen := make(map[Profile]Platform)
+		for _, platform := range Platforms() {
+			profile := platform.Profile()
+			profile.Name, profile.Description = "", ""
+			if other, ok := seen[profile]; ok {
+				t.Errorf("%v has the same variant and quirks as %v", platform, other)
+			}
+			seen[profile] = platform
+		}
+	})
+
+	t.Run("Default platform", func(t *testing.T) {
+		e := New()
+
+		if e.Config.Platform != DefaultPlatform {
+			t.Errorf("Platform should default to %v, got %v", DefaultPlatform, e.Config.Platform)
+		}
+	})
+}
+
+func TestQuirks(t *testing.T) {
+	t.Run("VF reset", func(t *testing.T) {
+		e := New(
+			WithQuirks(Quirks{VFReset: true}),
+		)
+		// 0x8AB1 - set register A to A OR B
+		e.Memory[0x200] = 0x8A
+		e.Memory[0x201] = 0xB1
+		e.Registers[0xF] = 0x5
+
+		e.Step(0)
+
+		if e.Registers[0xF] != 0 {
+			t.Errorf("VF should be reset to 0 after 8XY1, got %d", e.Registers[0xF])
+		}
+	})
+
+	t.Run("Jump uses VX", func(t *testing.T) {
+		e := New(
+			WithQuirks(Quirks{JumpUsesV0: false}),
+		)
+		// 0xB320 - jump to 0x320 + V3
+		e.Memory[0x200] = 0xB3
+		e.Memory[0x201] = 0x20
+		e.Registers[0] = 0x1
+		e.Registers[3] = 0x4
+
+		e.Step(0)
+
+		if e.PC != 0x324 {
+			t.Errorf("PC should be 0x324, got 0x%04X", e.PC)
+		}
+
+		e = New(
+			WithQuirks(Quirks{JumpUsesV0: true}),
+		)
+		e.Memory[0x200] = 0xB3
+		e.Memory[0x201] = 0x20
+		e.Registers[0] = 0x1
+		e.Registers[3] = 0x4
+
+		e.Step(0)
+
+		if e.PC != 0x321 {
+			t.Errorf("PC should be 0x321 with jump quirk, got 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("Display wait", func(t *testing.T) {
+		e := New(
+			WithQuirks(Quirks{DisplayWait: true}),
+		)
+		// 0xD001 - draw, 0x6A42 - set VA
+		e.Memory[0x200] = 0xD0
+		e.Memory[0x201] = 0x01
+		e.Memory[0x202] = 0x6A
+		e.Memory[0x203] = 0x42
+
+		e.Step(time.Millisecond)
+		e.Step(time.Millisecond)
+
+		if e.PC != 0x202 || e.Registers[0xA] != 0 {
+			t.Errorf("Execution should wait for vertical blank after drawing, PC=0x%04X", e.PC)
+		}
+
+		e.Step(time.Second / 30)
+		e.Step(time.Millisecond)
+
+		if e.Registers[0xA] != 0x42 {
+			t.Errorf("Execution should continue after vertical blank, PC=0x%04X", e.PC)
+		}
+	})
+
+	t.Run("Wrap sprites", func(t *testing.T) {
+		e := New(
+			WithQuirks(Quirks{WrapSprites: true}),
+		)
+		e.I = 0x300
+		e.Memory[0x300] = 0xFF
+		e.Memory[0x301] = 0xFF
+
+		e.drawSprite(e.DisplayWidth()-2, e.DisplayHeight()-1, 8, 2)
+
+		width := e.DisplayWidth()
+		if e.Display[(e.DisplayHeight()-1)*width+width-1] == 0 {
+			t.Errorf("Pixel at bottom right should be set")
+		}
+		if e.Display[(e.DisplayHeight()-1)*width+5] == 0 {
+			t.Errorf("Pixel at (5,%d) should be set (wrapped horizontally)", e.DisplayHeight()-1)
+		}
+		if e.Display[0*width+5] == 0 {
+			t.Errorf("Pixel at (5,0) should be set (wrapped in both directions)")
+		}
+	})
+
+	t.Run("Low resolution DXY0 draws 8x16", func(t *testing.T) {
+		for _, quirk := range []bool{false, true} {
+			e := New(
+				WithVariant(VariantSuperChip),
+				WithQuirks(Quirks{LowResBigSprite8x16: quirk}),
+			)
+			// 0xD000 - draw a big sprite of set pixels at (0,0)
+			e.Memory[0x200] = 0xD0
+			e.Memory[0x201] = 0x00
+			e.I = 0x300
+			for i := range 32 {
+				e.Memory[0x300+i] = 0xFF
+			}
+
+			e.Step(0)
+
+			width := e.DisplayWidth()
+			if e.Display[15*width+7] == 0 {
+				t.Errorf("Quirk %v: pixel at (7,15) should be set", quirk)
+			}
+			if set := e.Display[8] != 0; set == quirk {
+				t.Errorf("Quirk %v: pixel at (8,0) should be set only for a 16x16 sprite", quirk)
+			}
+		}
+	})
+
+	t.Run("Low resolution half scroll", func(t *testing.T) {
+		for _, highRes := range []bool{false, true} {
+			e := New(
+				WithVariant(VariantSuperChip),
+				WithQuirks(Quirks{LowResHalfScroll: true}),
+			)
+			e.setHighRes(highRes)
+			// 0x00FB - scroll right, 0x00C3 - scroll down 3
+			e.Memory[0x200] = 0x00
+			e.Memory[0x201] = 0xFB
+			e.Memory[0x202] = 0x00
+			e.Memory[0x203] = 0xC3
+			e.Display[0] = 1
+
+			e.Step(0)
+			e.Step(0)
+
+			x, y := 2, 1
+			if highRes {
+				x, y = 4, 3
+			}
+			if e.Display[y*e.DisplayWidth()+x] == 0 {
+				t.Errorf("High resolution %v: pixel should scroll from (0,0) to (%d,%d)", highRes, x, y)
+			}
+		}
+	})
+}
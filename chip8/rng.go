@@ -0,0 +1,59 @@
+package chip8
+
+import "math/rand"
+
+// Size and tap of the feedback register of math/rand's generator
+const (
+	rngLen = 607
+	rngTap = 273
+)
+
+// rngSource produces the same sequence as rand.NewSource, the additive lagged
+// Fibonacci generator of math/rand, but with its state accessible so it can be
+// saved and restored in constant time
+type rngSource struct {
+	tap  int
+	feed int
+	vec  [rngLen]int64
+}
+
+func newRNGSource(seed int64) *rngSource {
+	s := &rngSource{}
+	s.Seed(seed)
+	return s
+}
+
+// Seed sets the state math/rand would seed. Every value of the register is
+// replaced once in rngLen steps, which bring tap and feed back to where they
+// started, so the state after rngLen values from rand.NewSource can be
+// stepped back to the seeded state.
+func (s *rngSource) Seed(seed int64) {
+	src := rand.NewSource(seed).(rand.Source64)
+	s.tap, s.feed = 0, rngLen-rngTap
+	for range rngLen {
+		s.advance()
+		s.vec[s.feed] = int64(src.Uint64())
+	}
+	for range rngLen {
+		s.vec[s.feed] -= s.vec[s.tap]
+		s.tap = (s.tap + 1) % rngLen
+		s.feed = (s.feed + 1) % rngLen
+	}
+}
+
+// advance moves tap and feed to the next value
+func (s *rngSource) advance() {
+	s.tap = (s.tap + rngLen - 1) % rngLen
+	s.feed = (s.feed + rngLen - 1) % rngLen
+}
+
+func (s *rngSource) Uint64() uint64 {
+	s.advance()
+	x := s.vec[s.feed] + s.vec[s.tap]
+	s.vec[s.feed] = x
+	return uint64(x)
+}
+
+func (s *rngSource) Int63() int64 {
+	return int64(s.Uint64() &^ (1 << 63))
+}
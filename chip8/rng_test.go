@@ -0,0 +1,30 @@
+package chip8
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRNGSource(t *testing.T) {
+	t.Run("Matches math/rand", func(t *testing.T) {
+		for _, seed := range []int64{0, 1, -1, 1234, 5678, 1<<31 - 1, 1 << 40, -1 << 62} {
+			ours := newRNGSource(seed)
+			theirs := rand.NewSource(seed).(rand.Source64)
+			for i := range 2 * rngLen {
+				if got, want := ours.Uint64(), theirs.Uint64(); got != want {
+					t.Fatalf("Seed %d: value %d should be 0x%X, got 0x%X", seed, i, want, got)
+				}
+			}
+		}
+	})
+
+	t.Run("Reseeding restarts the sequence", func(t *testing.T) {
+		s := newRNGSource(42)
+		first := s.Int63()
+		s.Int63()
+		s.Seed(42)
+		if got := s.Int63(); got != first {
+			t.Errorf("First value after reseeding should be %d, got %d", first, got)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package chip8
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ROMInfo is the settings a ROM database gives for a ROM
+type ROMInfo struct {
+	Title    string
+	File     string   // file name the ROM is distributed as
+	Source   string   // database the entry came from, "built-in" or a file path
+	Platform Platform // first platform listed for the ROM which the emulator supports
+	Quirks   Quirks   // platform quirks, with any overrides listed for the ROM
+	TickRate int      // instructions per frame, 0 if not given
+
+	// Keys maps control names, such as up, down, left, right, a and b, to the
+	// CHIP-8 keys the ROM uses for them
+	Keys map[string]byte
+	// Colors are the display colours, indexed by XO-CHIP bit-plane combination.
+	// Nil if not given.
+	Colors []color.RGBA
+}
+
+// ROMDatabase looks up ROM settings by the SHA-1 of the ROM. It reads the
+// programs.json format of the community CHIP-8 database, a list of programs
+// each with a "roms" object keyed by SHA-1 hash, see
+// https://github.com/chip-8/chip-8-database
+type ROMDatabase struct {
+	roms map[[sha1.Size]byte]ROMInfo
+}
+
+// BuiltinROMSource is the ROMInfo source of entries from the built-in database
+const BuiltinROMSource = "built-in"
+
+//go:embed romdb.json
+var builtinROMDatabase []byte
+
+// databasePlatforms maps the platform IDs of the community database to the
+// preset with the same instruction set and quirks
+var databasePlatforms = map[string]Platform{
+	"originalChip8": PlatformCosmacVIP,
+	"hybridVIP":     PlatformCosmacVIP,
+	"modernChip8":   PlatformModernChip8,
+	"chip48":        PlatformChip48,
+	"superchip1":    PlatformSuperChip10,
+	"superchip":     PlatformSuperChip11,
+	"xochip":        PlatformXOChip,
+}
+
+// databaseQuirks maps the quirk names of the community database to quirk
+// flags, some of which are enabled when the database quirk is disabled
+var databaseQuirks = map[string]struct {
+	flag     func(q *Quirks) *bool
+	inverted bool
+}{
+	"shift":                 {func(q *Quirks) *bool { return &q.ShiftUsesVY }, true},
+	"memoryLeaveIUnchanged": {func(q *Quirks) *bool { return &q.StoreLoadIncrementsI }, true},
+	"wrap":                  {func(q *Quirks) *bool { return &q.WrapSprites }, false},
+	"jump":                  {func(q *Quirks) *bool { return &q.JumpUsesV0 }, true},
+	"vblank":                {func(q *Quirks) *bool { return &q.DisplayWait }, false},
+	"logic":                 {func(q *Quirks) *bool { return &q.VFReset }, false},
+}
+
+// databaseProgram is a program in the community database format. Fields the
+// emulator doesn't use are ignored.
+type databaseProgram struct {
+	Title string                 `json:"title"`
+	ROMs  map[string]databaseROM `json:"roms"`
+}
+
+type databaseROM struct {
+	File            string                     `json:"file"`
+	Platforms       []string                   `json:"platforms"`
+	QuirkyPlatforms map[string]map[string]bool `json:"quirkyPlatforms"`
+	TickRate        int                        `json:"tickrate"`
+	Keys            map[string]int             `json:"keys"`
+	Colors          struct {
+		Pixels []string `json:"pixels"`
+	} `json:"colors"`
+}
+
+// BuiltinROMDatabase returns the database of settings embedded in the emulator
+func BuiltinROMDatabase() *ROMDatabase {
+	db, err := ReadROMDatabase(bytes.NewReader(builtinROMDatabase), BuiltinROMSource)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in ROM database: %v", err))
+	}
+	return db
+}
+
+// LoadROMDatabase returns the built-in database, with the entries of the
+// database file at path taking precedence. The file is optional, a path
+// which doesn't exist gives only the built-in entries.
+func LoadROMDatabase(path string) (*ROMDatabase, error) {
+	db := BuiltinROMDatabase()
+	if path == "" {
+		return db, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ROM database: %w", err)
+	}
+	defer f.Close()
+
+	override, err := ReadROMDatabase(f, path)
+	if err != nil {
+		return nil, err
+	}
+	db.Merge(override)
+	return db, nil
+}
+
+// UserROMDatabasePath returns the path of the user's ROM database file in
+// their config directory, or "" if there isn't one
+func UserROMDatabasePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chip8-emulator", "roms.json")
+}
+
+// ReadROMDatabase reads a database in the community programs.json format.
+// Entries record source as where they came from.
+func ReadROMDatabase(r io.Reader, source string) (*ROMDatabase, error) {
+	var programs []databaseProgram
+	if err := json.NewDecoder(r).Decode(&programs); err != nil {
+		return nil, fmt.Errorf("failed to parse ROM database %s: %w", source, err)
+	}
+
+	db := &ROMDatabase{roms: make(map[[sha1.Size]byte]ROMInfo)}
+	for _, program := range programs {
+		for hash, rom := range program.ROMs {
+			var key [sha1.Size]byte
+			if len(hash) != hex.EncodedLen(len(key)) {
+				return nil, fmt.Errorf("ROM database %s: %s: invalid SHA-1 %q", source, program.Title, hash)
+			}
+			if _, err := hex.Decode(key[:], []byte(hash)); err != nil {
+				return nil, fmt.Errorf("ROM database %s: %s: invalid SHA-1 %q", source, program.Title, hash)
+			}
+			info, err := rom.info(program.Title, source)
+			if err != nil {
+				return nil, fmt.Errorf("ROM database %s: %s: %w", source, program.Title, err)
+			}
+			db.roms[key] = info
+		}
+	}
+	return db, nil
+}
+
+// info converts a database entry to the emulator's settings
+func (rom databaseROM) info(title, source string) (ROMInfo, error) {
+	info := ROMInfo{
+		Title:    title,
+		File:     rom.File,
+		Source:   source,
+		Platform: DefaultPlatform,
+		Quirks:   DefaultPlatform.Profile().Quirks,
+		TickRate: rom.TickRate,
+	}
+	if rom.TickRate < 0 {
+		return info, fmt.Errorf("invalid tickrate: %d", rom.TickRate)
+	}
+
+	// platforms are listed in order of preference, use the first supported
+	for _, id := range rom.Platforms {
+		p, ok := databasePlatforms[id]
+		if !ok {
+			continue
+		}
+		info.Platform = p
+		info.Quirks = p.Profile().Quirks
+		for name, enabled := range rom.QuirkyPlatforms[id] {
+			quirk, ok := databaseQuirks[name]
+			if !ok {
+				continue // e.g. memoryIncrementByX, which the emulator doesn't distinguish
+			}
+			*quirk.flag(&info.Quirks) = enabled != quirk.inverted
+		}
+		break
+	}
+
+	if len(rom.Keys) > 0 {
+		info.Keys = make(map[string]byte, len(rom.Keys))
+		for name, key := range rom.Keys {
+			if key < 0 || key > 0xF {
+				return info, fmt.Errorf("invalid key for %s: %d", name, key)
+			}
+			info.Keys[name] = byte(key)
+		}
+	}
+
+	for _, pixel := range rom.Colors.Pixels {
+		c, err := parseHexColor(pixel)
+		if err != nil {
+			return info, err
+		}
+		info.Colors = append(info.Colors, c)
+	}
+	return info, nil
+}
+
+// parseHexColor parses a #RRGGBB colour
+func parseHexColor(s string) (color.RGBA, error) {
+	var rgb [3]byte
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid colour: %q", s)
+	}
+	if _, err := hex.Decode(rgb[:], []byte(s[1:])); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour: %q", s)
+	}
+	return color.RGBA{rgb[0], rgb[1], rgb[2], 0xFF}, nil
+}
+
+// Merge adds the entries of other, replacing those for the same ROMs
+func (db *ROMDatabase) Merge(other *ROMDatabase) {
+	for hash, info := range other.roms {
+		db.roms[hash] = info
+	}
+}
+
+// Lookup returns the settings for the ROM with the given SHA-1
+func (db *ROMDatabase) Lookup(hash [sha1.Size]byte) (ROMInfo, bool) {
+	info, ok := db.roms[hash]
+	return info, ok
+}
+
+// Len returns the number of ROMs in the database
+func (db *ROMDatabase) Len() int {
+	return len(db.roms)
+}
+
+// WithROMDatabase makes LoadROMFromPath and LoadROMFromData look up each ROM
+// in the database, applying the platform, quirks and tick rate of a matching
+// entry over BaseConfig. The entry is available from ROMInfo for the frontend
+// to apply the rest, such as key mapping and colours.
+func WithROMDatabase(db *ROMDatabase) EmulatorOption {
+	return func(e *Emulator) {
+		e.romDatabase = db
+	}
+}
+
+// ROMInfo returns the database entry matched by the loaded ROM, if any
+func (e *Emulator) ROMInfo() (ROMInfo, bool) {
+	if e.romInfo == nil {
+		return ROMInfo{}, false
+	}
+	return *e.romInfo, true
+}
+
+// BaseConfig returns the config each ROM's database entry is applied over,
+// initially the config set by the options. Changes take effect when the next
+// ROM is loaded, so a frontend applying a setting chosen by the user should
+// change both BaseConfig and Config.
+func (e *Emulator) BaseConfig() *EmulatorConfig {
+	return &e.baseConfig
+}
+
+// romConfig looks up the ROM with the given hash, returning the config to
+// run it with, and the entry if there is one. Without a database the config
+// is unchanged, otherwise it is the base config with any entry applied.
+func (e *Emulator) romConfig(hash [sha1.Size]byte) (EmulatorConfig, *ROMInfo) {
+	if e.romDatabase == nil {
+		return *e.Config, nil
+	}
+	config := e.baseConfig
+	config.randSeed = e.Config.randSeed // the seed belongs to the random number generator's state
+	info, ok := e.romDatabase.Lookup(hash)
+	if !ok {
+		return config, nil
+	}
+	config.SetPlatform(info.Platform)
+	config.Quirks = info.Quirks
+	if info.TickRate > 0 {
+		config.InstructionsPerFrame = info.TickRate
+	}
+	return config, &info
+}
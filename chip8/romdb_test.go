@@ -0,0 +1,226 @@
+package chip8
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testROM is a ROM listed in testDatabase
+var testROM = []byte{0x12, 0x00}
+
+func testDatabase() string {
+	hash := sha1.Sum(testROM)
+	return `[{
+		"title": "Test ROM",
+		"roms": {
+			"` + hex.EncodeToString(hash[:]) + `": {
+				"file": "test.ch8",
+				"platforms": ["megachip8", "xochip", "originalChip8"],
+				"quirkyPlatforms": {"xochip": {"wrap": false, "shift": true, "memoryIncrementByX": true}},
+				"tickrate": 200,
+				"keys": {"up": 5, "a": 6},
+				"colors": {"pixels": ["#000000", "#ff8000"], "buzzer": "#990000"}
+			}
+		}
+	}]`
+}
+
+func TestROMDatabase(t *testing.T) {
+	t.Run("Built-in database", func(t *testing.T) {
+		db := BuiltinROMDatabase()
+		if db.Len() == 0 {
+			t.Fatal("Built-in database should not be empty")
+		}
+		rom, err := os.ReadFile("../site/roms/tetris")
+		if err != nil {
+			t.Fatalf("Failed to read ROM: %v", err)
+		}
+		info, ok := db.Lookup(sha1.Sum(rom))
+		if !ok {
+			t.Fatal("Built-in database should list the bundled Tetris ROM")
+		}
+		if info.Title != "Tetris" || info.Source != BuiltinROMSource {
+			t.Errorf("Entry should be built-in Tetris, got %q from %q", info.Title, info.Source)
+		}
+	})
+
+	t.Run("Reads the community format", func(t *testing.T) {
+		db, err := ReadROMDatabase(strings.NewReader(testDatabase()), "test")
+		if err != nil {
+			t.Fatalf("ReadROMDatabase returned error: %v", err)
+		}
+		info, ok := db.Lookup(sha1.Sum(testROM))
+		if !ok {
+			t.Fatal("Lookup should find the test ROM")
+		}
+
+		if info.Platform != PlatformXOChip {
+			t.Errorf("Platform should be the first supported, %v, got %v", PlatformXOChip, info.Platform)
+		}
+		want := PlatformXOChip.Profile().Quirks
+		want.WrapSprites = false
+		want.ShiftUsesVY = false
+		if info.Quirks != want {
+			t.Errorf("Quirks should be %+v, got %+v", want, info.Quirks)
+		}
+		if info.TickRate != 200 {
+			t.Errorf("TickRate should be 200, got %d", info.TickRate)
+		}
+		if info.Keys["up"] != 5 || info.Keys["a"] != 6 {
+			t.Errorf("Keys should map up to 5 and a to 6, got %v", info.Keys)
+		}
+		if len(info.Colors) != 2 || info.Colors[1] != (color.RGBA{0xFF, 0x80, 0x00, 0xFF}) {
+			t.Errorf("Colors should be parsed, got %v", info.Colors)
+		}
+	})
+
+	t.Run("Database platforms map to presets", func(t *testing.T) {
+		for id, want := range map[string]Platform{
+			"originalChip8": PlatformCosmacVIP,
+			"modernChip8":   PlatformModernChip8,
+			"superchip":     PlatformSuperChip11,
+		} {
+			db, err := ReadROMDatabase(strings.NewReader(`[{"title": "Test", "roms": {"`+strings.Repeat("0", 40)+`": {"platforms": ["`+id+`"]}}}]`), "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, _ := db.Lookup([sha1.Size]byte{})
+			if info.Platform != want || info.Quirks != want.Profile().Quirks {
+				t.Errorf("%s should be %v with its quirks, got %v with %+v", id, want, info.Platform, info.Quirks)
+			}
+		}
+	})
+
+	t.Run("Invalid entries", func(t *testing.T) {
+		for _, db := range []string{
+			`{"title": "Not a list"}`,
+			`[{"title": "Bad hash", "roms": {"1234": {}}}]`,
+			`[{"title": "Long hash", "roms": {"` + strings.Repeat("0", 42) + `": {}}}]`,
+			`[{"title": "Bad key", "roms": {"` + strings.Repeat("0", 40) + `": {"keys": {"a": 16}}}}]`,
+			`[{"title": "Bad colour", "roms": {"` + strings.Repeat("0", 40) + `": {"colors": {"pixels": ["red"]}}}}]`,
+		} {
+			if _, err := ReadROMDatabase(strings.NewReader(db), "test"); err == nil {
+				t.Errorf("ReadROMDatabase should return error for %s", db)
+			}
+		}
+	})
+
+	t.Run("User file overrides built-in entries", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "roms.json")
+		if err := os.WriteFile(path, []byte(testDatabase()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		db, err := LoadROMDatabase(path)
+		if err != nil {
+			t.Fatalf("LoadROMDatabase returned error: %v", err)
+		}
+		if db.Len() != BuiltinROMDatabase().Len()+1 {
+			t.Errorf("Database should have the built-in entries and the user's, got %d", db.Len())
+		}
+		if info, ok := db.Lookup(sha1.Sum(testROM)); !ok || info.Source != path {
+			t.Errorf("Test ROM should come from %s, got %+v", path, info)
+		}
+
+		if _, err := LoadROMDatabase(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+			t.Errorf("A missing user file should be ignored, got %v", err)
+		}
+	})
+
+	t.Run("Loading a ROM applies its entry", func(t *testing.T) {
+		db, _ := ReadROMDatabase(strings.NewReader(testDatabase()), "test")
+		e := New(WithROMDatabase(db))
+		if err := e.LoadROMFromData(testROM); err != nil {
+			t.Fatalf("LoadROMFromData returned error: %v", err)
+		}
+
+		info, ok := e.ROMInfo()
+		if !ok || info.Title != "Test ROM" {
+			t.Fatalf("ROMInfo should return the matched entry, got %+v", info)
+		}
+		if e.Config.Platform != PlatformXOChip || e.Config.Variant != VariantXOChip {
+			t.Errorf("Platform should be set from the entry, got %v", e.Config.Platform)
+		}
+		if e.Config.Quirks != info.Quirks {
+			t.Errorf("Quirks should be set from the entry, got %+v", e.Config.Quirks)
+		}
+		if e.Config.InstructionsPerFrame != 200 {
+			t.Errorf("InstructionsPerFrame should be set from the tick rate, got %d", e.Config.InstructionsPerFrame)
+		}
+
+		e.Reset()
+		if err := e.LoadROMFromData([]byte{0x00, 0xE0}); err != nil {
+			t.Fatalf("LoadROMFromData returned error: %v", err)
+		}
+		if _, ok := e.ROMInfo(); ok {
+			t.Error("ROMInfo should not match an unknown ROM")
+		}
+		if *e.Config != *e.BaseConfig() || e.Config.Platform != DefaultPlatform || e.Config.InstructionsPerFrame != DefaultInstructionsPerFrame {
+			t.Errorf("Config should be restored to the base config for an unknown ROM, got %+v", *e.Config)
+		}
+	})
+
+	t.Run("Entry is applied over the base config", func(t *testing.T) {
+		db, _ := ReadROMDatabase(strings.NewReader(testDatabase()), "test")
+		e := New(WithROMDatabase(db), WithPlatform(PlatformSuperChip11), WithTiming(TimingVIP))
+		e.BaseConfig().InstructionsPerFrame = 30
+		e.Config.InstructionsPerFrame = 50 // not kept, only the base is
+
+		e.LoadROMFromData([]byte{0x00, 0xE0})
+		if e.Config.Platform != PlatformSuperChip11 || e.Config.InstructionsPerFrame != 30 {
+			t.Errorf("Unknown ROM should use the base config, got %v at %d instructions per frame", e.Config.Platform, e.Config.InstructionsPerFrame)
+		}
+
+		e.Reset()
+		e.LoadROMFromData(testROM)
+		if e.Config.Platform != PlatformXOChip || e.Config.Timing != TimingVIP {
+			t.Errorf("Entry should set the platform and keep the base timing, got %v and %v", e.Config.Platform, e.Config.Timing)
+		}
+		if e.BaseConfig().Platform != PlatformSuperChip11 {
+			t.Errorf("Entry should not change the base config, got %v", e.BaseConfig().Platform)
+		}
+	})
+
+	t.Run("Entry is applied before the size check", func(t *testing.T) {
+		rom := make([]byte, DefaultMemorySize)
+		hash := sha1.Sum(rom)
+		db, err := ReadROMDatabase(strings.NewReader(`[{"title": "Big", "roms": {"`+hex.EncodeToString(hash[:])+`": {"platforms": ["xochip"]}}}]`), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := New(WithROMDatabase(db))
+		if err := e.LoadROMFromData(rom); err != nil {
+			t.Errorf("ROM should fit in XO-CHIP memory, got %v", err)
+		}
+	})
+
+	t.Run("Rejected ROM leaves the config unchanged", func(t *testing.T) {
+		rom := make([]byte, DefaultMemorySize)
+		hash := sha1.Sum(rom)
+		entries := strings.TrimSuffix(testDatabase(), "]") +
+			`, {"title": "Too big", "roms": {"` + hex.EncodeToString(hash[:]) + `": {"platforms": ["schip"], "tickrate": 30}}}]`
+		db, err := ReadROMDatabase(strings.NewReader(entries), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := New(WithROMDatabase(db))
+		if err := e.LoadROMFromData(testROM); err != nil {
+			t.Fatalf("LoadROMFromData returned error: %v", err)
+		}
+		config := *e.Config
+
+		if err := e.LoadROMFromData(rom); err == nil {
+			t.Fatal("ROM should be too large for SUPER-CHIP memory")
+		}
+		if *e.Config != config {
+			t.Errorf("Config should be unchanged, got %+v, expected %+v", *e.Config, config)
+		}
+		if info, ok := e.ROMInfo(); !ok || info.Title != "Test ROM" {
+			t.Errorf("ROMInfo should still be the loaded ROM's entry, got %+v", info)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package chip8
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runner controls an emulator running frames in its own goroutine, see Run.
+// Its methods are safe to call from any goroutine. While it runs, the
+// emulator must only be accessed through the Runner, with Do or Snapshot,
+// apart from queueing key input with PressKey, ReleaseKey and QueueInput.
+type Runner struct {
+	emulator *Emulator
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu     sync.Mutex // held while a frame runs
+	paused bool
+	err    error // error which stopped the runner, set before done is closed
+}
+
+// Run starts running the emulator in a separate goroutine, calling RunFrame
+// 60 times a second until ctx is cancelled, Stop is called, or a frame
+// returns an error, including ErrProgramExit and a *WatchpointError.
+func (e *Emulator) Run(ctx context.Context) *Runner {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Runner{
+		emulator: e,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go r.run(ctx)
+	return r
+}
+
+func (r *Runner) run(ctx context.Context) {
+	defer close(r.done)
+	defer r.cancel()
+
+	clock := time.NewTicker(FrameDuration)
+	defer clock.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.stop(ctx.Err())
+			return
+		case <-clock.C:
+		}
+
+		r.mu.Lock()
+		var err error
+		if !r.paused {
+			_, err = r.emulator.RunFrame()
+		}
+		r.mu.Unlock()
+		if err != nil {
+			r.stop(err)
+			return
+		}
+	}
+}
+
+func (r *Runner) stop(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Pause stops running frames until Resume is called. A frame already
+// running completes first.
+func (r *Runner) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = true
+}
+
+// Resume continues running frames after Pause
+func (r *Runner) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = false
+}
+
+// Paused reports whether the runner is paused
+func (r *Runner) Paused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused
+}
+
+// SetSpeed sets the number of instructions run per second, as the nearest
+// number of instructions per frame. It has no effect with TimingVIP.
+func (r *Runner) SetSpeed(instructionsPerSecond int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emulator.Config.InstructionsPerFrame = max(1, (instructionsPerSecond+FramesPerSecond/2)/FramesPerSecond)
+}
+
+// Do calls f with the emulator between frames, e.g. to press keys or load a state
+func (r *Runner) Do(f func(e *Emulator)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r.emulator)
+}
+
+// Snapshot returns a copy of the emulator state between frames
+func (r *Runner) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.emulator.Snapshot()
+}
+
+// Done returns a channel which is closed once the runner has stopped
+func (r *Runner) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait blocks until the runner has stopped, returning the error from the
+// frame which stopped it, or the context's error if it was cancelled
+func (r *Runner) Wait() error {
+	<-r.done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Stop stops the runner and waits for its goroutine to exit. It returns the
+// error from a frame which had already stopped it, or nil.
+func (r *Runner) Stop() error {
+	r.cancel()
+	err := r.Wait()
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
+
+// Snapshot is a copy of the state of an emulator which can be read while
+// the emulator keeps running
+type Snapshot struct {
+	Display    [HighResWidth * HighResHeight]byte
+	HighRes    bool
+	PC         uint16
+	I          uint16
+	Stack      [StackSize]uint16
+	SP         uint8
+	DelayTimer uint8
+	SoundTimer uint8
+	Registers  [RegisterCount]byte
+	Cycle      uint64
+	Frame      uint64
+}
+
+// Snapshot returns a copy of the display and registers
+func (e *Emulator) Snapshot() Snapshot {
+	return Snapshot{
+		Display:    e.Display,
+		HighRes:    e.HighRes,
+		PC:         e.PC,
+		I:          e.I,
+		Stack:      e.Stack,
+		SP:         e.SP,
+		DelayTimer: e.DelayTimer,
+		SoundTimer: e.SoundTimer,
+		Registers:  e.Registers,
+		Cycle:      e.cycle,
+		Frame:      e.frame,
+	}
+}
+
+// DisplayImage returns the display as an image, see Emulator.DisplayImage
+func (s *Snapshot) DisplayImage() *image.Paletted {
+	return displayImage(&s.Display, s.HighRes)
+}
+
+// Print prints the display and registers to the console
+func (s *Snapshot) Print() {
+	s.Fprint(os.Stdout)
+}
+
+// Fprint writes the display and registers to w
+func (s *Snapshot) Fprint(w io.Writer) error {
+	var out strings.Builder
+	printDisplay(&out, &s.Display, s.HighRes)
+
+	fmt.Fprintf(&out, "PC: 0x%04x\n", s.PC)
+	fmt.Fprintf(&out, "I : 0x%04x\n", s.I)
+	fmt.Fprintln(&out, "===Registers===")
+	for i := range s.Registers {
+		fmt.Fprintf(&out, "Reg %2d: 0x%02x\n", i, s.Registers[i])
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,125 @@
+package chip8
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+)
+
+// waitForFrame polls the runner until it has run frame frames
+func waitForFrame(t *testing.T, r *Runner, frame uint64) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		snapshot := r.Snapshot()
+		if snapshot.Frame >= frame {
+			return snapshot
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for frame %d, at frame %d", frame, snapshot.Frame)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRun(t *testing.T) {
+	loop := asm.MustAssemble(`
+	loop:
+		add v0, 1
+		ld  i, sprite
+		drw v1, v1, 1
+		jp  loop
+	sprite:
+		:byte 0x80
+	`)
+
+	t.Run("Pause, resume and stop", func(t *testing.T) {
+		e := New(WithInstructionsPerFrame(4))
+		e.LoadROMFromData(loop)
+		r := e.Run(context.Background())
+
+		// read the state from other goroutines while frames run
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					snapshot := r.Snapshot()
+					_ = snapshot.DisplayImage()
+					r.Do(func(e *Emulator) { e.PressKey(5) })
+				}
+			}()
+		}
+		wg.Wait()
+		waitForFrame(t, r, 2)
+
+		r.Pause()
+		if !r.Paused() {
+			t.Error("Runner should report it is paused")
+		}
+		paused := r.Snapshot()
+		time.Sleep(5 * FrameDuration)
+		if frame := r.Snapshot().Frame; frame != paused.Frame {
+			t.Errorf("No frames should run while paused, went from %d to %d", paused.Frame, frame)
+		}
+
+		r.SetSpeed(480)
+		r.Resume()
+		snapshot := waitForFrame(t, r, paused.Frame+1)
+		if snapshot.Cycle-paused.Cycle < 8 {
+			t.Errorf("Expected 8 instructions per frame after SetSpeed(480), ran %d in %d frames",
+				snapshot.Cycle-paused.Cycle, snapshot.Frame-paused.Frame)
+		}
+
+		if err := r.Stop(); err != nil {
+			t.Errorf("Stop returned error: %v", err)
+		}
+		select {
+		case <-r.Done():
+		default:
+			t.Error("Done should be closed after Stop")
+		}
+		stopped := e.Snapshot()
+		time.Sleep(3 * FrameDuration)
+		if e.Frame() != stopped.Frame {
+			t.Error("No frames should run after Stop")
+		}
+	})
+
+	t.Run("Context cancellation", func(t *testing.T) {
+		e := New()
+		e.LoadROMFromData(loop)
+		ctx, cancel := context.WithCancel(context.Background())
+		r := e.Run(ctx)
+		waitForFrame(t, r, 1)
+
+		cancel()
+		if err := r.Wait(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait should return context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Stops on program exit", func(t *testing.T) {
+		e := New()
+		e.LoadROMFromData(asm.MustAssemble(`
+			ld v0, 1
+			exit
+		`))
+		r := e.Run(context.Background())
+
+		if err := r.Wait(); !errors.Is(err, ErrProgramExit) {
+			t.Errorf("Wait should return ErrProgramExit, got %v", err)
+		}
+		if err := r.Stop(); !errors.Is(err, ErrProgramExit) {
+			t.Errorf("Stop should return the error which stopped the runner, got %v", err)
+		}
+		if snapshot := r.Snapshot(); snapshot.Registers[0] != 1 {
+			t.Errorf("Snapshot should show V0 = 1, got %d", snapshot.Registers[0])
+		}
+	})
+}
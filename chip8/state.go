@@ -0,0 +1,239 @@
+package chip8
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Save state format identification
+const (
+	stateMagic   = "C8ST"
+	StateVersion = 8 // Version of the save state format written by SaveState
+)
+
+var (
+	// ErrStateFormat is returned by LoadState when the data is not a save state
+	ErrStateFormat = errors.New("not a save state")
+	// ErrStateVersion is returned by LoadState when the save state was written
+	// by an incompatible version of the format
+	ErrStateVersion = errors.New("unsupported save state version")
+	// ErrStateROMMismatch is returned by LoadState when the save state was made
+	// with a different ROM to the one currently loaded
+	ErrStateROMMismatch = errors.New("save state is for a different ROM")
+)
+
+// stateHeader identifies a save state and the ROM it belongs to
+type stateHeader struct {
+	Magic   [4]byte
+	Version uint16
+	ROMHash [sha1.Size]byte
+}
+
+// stateBody is the fixed size part of the emulator state, written after the
+// header and followed by MemorySize bytes of memory
+type stateBody struct {
+	Platform uint8
+	Variant  uint8
+	Quirks   uint16 // bitfield, see quirkBits
+	Timing   uint8
+	RandSeed int64
+	RandTap  uint16 // random number generator state, see rngSource
+	RandFeed uint16
+	RandVec  [rngLen]int64
+
+	PC         uint16
+	I          uint16
+	Stack      [StackSize]uint16
+	SP         uint8
+	DelayTimer uint8
+	SoundTimer uint8
+	TimerDelta int64
+	VBlankWait bool
+	VIPCycles  uint32 // machine cycles used in the current frame with TimingVIP
+	Cycle      uint64
+	Frame      uint64
+	Registers  [RegisterCount]byte
+	Keypad     [16]bool
+	WaitKey    int8 // key pressed during FX0A, or -1
+
+	HighRes      bool
+	Planes       byte
+	Pitch        byte
+	RPLFlags     [XOChipRPLFlagCount]byte
+	AudioPattern [AudioPatternSize]byte
+	Display      [HighResWidth * HighResHeight]byte
+
+	MemorySize uint32
+}
+
+// validate returns ErrStateFormat if any field is out of range, so a corrupt
+// or crafted save state can't leave the emulator in a state it would crash in
+func (b *stateBody) validate() error {
+	switch {
+	case b.MemorySize > XOChipMemorySize:
+		return fmt.Errorf("%w: memory size %d", ErrStateFormat, b.MemorySize)
+	case b.WaitKey < -1 || b.WaitKey > 0xF:
+		return fmt.Errorf("%w: invalid key %d", ErrStateFormat, b.WaitKey)
+	case b.SP > StackSize:
+		return fmt.Errorf("%w: stack pointer %d", ErrStateFormat, b.SP)
+	case b.Planes >= 1<<PlaneCount:
+		return fmt.Errorf("%w: planes 0x%X", ErrStateFormat, b.Planes)
+	case !slices.Contains(Platforms(), Platform(b.Platform)):
+		return fmt.Errorf("%w: unknown platform %d", ErrStateFormat, b.Platform)
+	case Variant(b.Variant) > VariantXOChip:
+		return fmt.Errorf("%w: unknown variant %d", ErrStateFormat, b.Variant)
+	case Timing(b.Timing) > TimingVIP:
+		return fmt.Errorf("%w: unknown timing %d", ErrStateFormat, b.Timing)
+	case b.RandTap >= rngLen || b.RandFeed >= rngLen:
+		return fmt.Errorf("%w: random number generator position out of range", ErrStateFormat)
+	}
+	return nil
+}
+
+// quirkBits returns pointers to each quirk in the order of their bit in a save state
+func quirkBits(q *Quirks) []*bool {
+	return []*bool{
+		&q.ShiftUsesVY,
+		&q.JumpUsesV0,
+		&q.StoreLoadIncrementsI,
+		&q.VFReset,
+		&q.DisplayWait,
+		&q.WrapSprites,
+		&q.IndexOverflowVF,
+		&q.LowResBigSprite8x16,
+		&q.LowResHalfScroll,
+	}
+}
+
+// SaveState writes a snapshot of the full emulator state to w. The snapshot
+// can be restored with LoadState on an emulator with the same ROM loaded.
+func (e *Emulator) SaveState(w io.Writer) error {
+	header := stateHeader{
+		Version: StateVersion,
+		ROMHash: e.romHash,
+	}
+	copy(header.Magic[:], stateMagic)
+
+	body := stateBody{
+		Platform: uint8(e.Config.Platform),
+		Variant:  uint8(e.Config.Variant),
+		Timing:   uint8(e.Config.Timing),
+		RandSeed: e.Config.randSeed,
+		RandTap:  uint16(e.rngSrc.tap),
+		RandFeed: uint16(e.rngSrc.feed),
+		RandVec:  e.rngSrc.vec,
+
+		PC:         e.PC,
+		I:          e.I,
+		Stack:      e.Stack,
+		SP:         e.SP,
+		DelayTimer: e.DelayTimer,
+		SoundTimer: e.SoundTimer,
+		TimerDelta: int64(e.timerDelta),
+		VBlankWait: e.waitingForVBlank,
+		VIPCycles:  uint32(e.vipFrameCycles),
+		Cycle:      e.cycle,
+		Frame:      e.frame,
+		Registers:  e.Registers,
+		Keypad:     e.Keypad,
+		WaitKey:    int8(e.waitKey),
+
+		HighRes:      e.HighRes,
+		Planes:       e.Planes,
+		Pitch:        e.Pitch,
+		RPLFlags:     e.RPLFlags,
+		AudioPattern: e.AudioPattern,
+		Display:      e.Display,
+
+		MemorySize: uint32(e.MemorySize()),
+	}
+	for i, quirk := range quirkBits(&e.Config.Quirks) {
+		if *quirk {
+			body.Quirks |= 1 << i
+		}
+	}
+
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("failed to write save state header: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, &body); err != nil {
+		return fmt.Errorf("failed to write save state: %w", err)
+	}
+	if _, err := w.Write(e.Memory[:body.MemorySize]); err != nil {
+		return fmt.Errorf("failed to write save state memory: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores a snapshot written by SaveState. The emulator is left
+// unchanged if the snapshot cannot be read, or was made with a different ROM.
+func (e *Emulator) LoadState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("failed to read save state header: %w", err)
+	}
+	if string(header.Magic[:]) != stateMagic {
+		return ErrStateFormat
+	}
+	if header.Version != StateVersion {
+		return fmt.Errorf("%w: %d (expected %d)", ErrStateVersion, header.Version, StateVersion)
+	}
+	if header.ROMHash != e.romHash {
+		return ErrStateROMMismatch
+	}
+
+	var body stateBody
+	if err := binary.Read(r, binary.BigEndian, &body); err != nil {
+		return fmt.Errorf("failed to read save state: %w", err)
+	}
+	if err := body.validate(); err != nil {
+		return err
+	}
+	memory := make([]byte, body.MemorySize)
+	if _, err := io.ReadFull(r, memory); err != nil {
+		return fmt.Errorf("failed to read save state memory: %w", err)
+	}
+
+	e.Config.Platform = Platform(body.Platform)
+	e.Config.Variant = Variant(body.Variant)
+	e.Config.Timing = Timing(body.Timing)
+	for i, quirk := range quirkBits(&e.Config.Quirks) {
+		*quirk = body.Quirks&(1<<i) != 0
+	}
+	e.Config.randSeed = body.RandSeed
+	e.rngSrc.tap = int(body.RandTap)
+	e.rngSrc.feed = int(body.RandFeed)
+	e.rngSrc.vec = body.RandVec
+
+	e.PC = body.PC
+	e.I = body.I
+	e.Stack = body.Stack
+	e.SP = body.SP
+	e.DelayTimer = body.DelayTimer
+	e.SoundTimer = body.SoundTimer
+	e.timerDelta = time.Duration(body.TimerDelta)
+	e.waitingForVBlank = body.VBlankWait
+	e.vipFrameCycles = int(body.VIPCycles)
+	e.cycle = body.Cycle
+	e.frame = body.Frame
+	e.inFrame = false
+	e.Registers = body.Registers
+	e.Keypad = body.Keypad
+	e.waitKey = int(body.WaitKey)
+
+	e.HighRes = body.HighRes
+	e.Planes = body.Planes
+	e.Pitch = body.Pitch
+	e.RPLFlags = body.RPLFlags
+	e.AudioPattern = body.AudioPattern
+	e.Display = body.Display
+
+	clear(e.Memory[:])
+	copy(e.Memory[:], memory)
+	return nil
+}
@@ -0,0 +1,169 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSaveState(t *testing.T) {
+	// 0xCA FF - VA = random, 0x1200 - jump back to start
+	rom := []byte{0xCA, 0xFF, 0x12, 0x00}
+
+	t.Run("Round trip restores state", func(t *testing.T) {
+		e := New(WithPlatform(PlatformXOChip), WithSeed(1234))
+		if err := e.LoadROMFromData(rom); err != nil {
+			t.Fatalf("failed to load ROM: %v", err)
+		}
+		for range 10 {
+			e.Step(0)
+		}
+		e.Display[42] = 3
+		e.HighRes = true
+		e.DelayTimer = 0x20
+		e.Stack[0] = 0x345
+		e.SP = 1
+		e.RPLFlags[12] = 0x99
+
+		var buf bytes.Buffer
+		if err := e.SaveState(&buf); err != nil {
+			t.Fatalf("SaveState returned error: %v", err)
+		}
+		saved := buf.Bytes()
+
+		// Run on to record the random values that follow the snapshot
+		var expected []byte
+		for range 6 {
+			e.Step(0)
+			e.Step(0)
+			expected = append(expected, e.Registers[0xA])
+		}
+
+		restored := New(WithSeed(1))
+		restored.LoadROMFromData(rom)
+		if err := restored.LoadState(bytes.NewReader(saved)); err != nil {
+			t.Fatalf("LoadState returned error: %v", err)
+		}
+
+		if restored.Config.Platform != PlatformXOChip || restored.Config.Quirks != PlatformXOChip.Profile().Quirks {
+			t.Errorf("Config should be restored, got %+v", restored.Config)
+		}
+		if restored.Display[42] != 3 || !restored.HighRes || restored.DelayTimer != 0x20 ||
+			restored.Stack[0] != 0x345 || restored.SP != 1 || restored.RPLFlags[12] != 0x99 {
+			t.Errorf("Emulator state should be restored")
+		}
+
+		for i, want := range expected {
+			restored.Step(0)
+			restored.Step(0)
+			if restored.Registers[0xA] != want {
+				t.Errorf("Random value %d after restore should be 0x%02X, got 0x%02X", i, want, restored.Registers[0xA])
+			}
+		}
+	})
+
+	t.Run("Wrong ROM", func(t *testing.T) {
+		e := New()
+		e.LoadROMFromData(rom)
+
+		var buf bytes.Buffer
+		e.SaveState(&buf)
+
+		other := New()
+		other.LoadROMFromData([]byte{0x00, 0xE0})
+		other.PC = 0x300
+
+		err := other.LoadState(&buf)
+		if !errors.Is(err, ErrStateROMMismatch) {
+			t.Errorf("LoadState should return ErrStateROMMismatch, got %v", err)
+		}
+		if other.PC != 0x300 {
+			t.Errorf("Emulator should be unchanged after failed load, PC=0x%04X", other.PC)
+		}
+	})
+
+	t.Run("Invalid data", func(t *testing.T) {
+		e := New()
+
+		err := e.LoadState(bytes.NewReader(bytes.Repeat([]byte{0xAB}, 64)))
+		if !errors.Is(err, ErrStateFormat) {
+			t.Errorf("LoadState should return ErrStateFormat, got %v", err)
+		}
+
+		var buf bytes.Buffer
+		e.SaveState(&buf)
+		data := buf.Bytes()
+		data[5] = StateVersion + 1
+
+		err = e.LoadState(bytes.NewReader(data))
+		if !errors.Is(err, ErrStateVersion) {
+			t.Errorf("LoadState should return ErrStateVersion, got %v", err)
+		}
+	})
+	t.Run("Corrupt state", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			corrupt func(b *stateBody)
+		}{
+			{"Stack pointer past the stack", func(b *stateBody) { b.SP = 200 }},
+			{"Stack pointer one past the stack", func(b *stateBody) { b.SP = StackSize + 1 }},
+			{"Unknown platform", func(b *stateBody) { b.Platform = 99 }},
+			{"Unknown variant", func(b *stateBody) { b.Variant = uint8(VariantXOChip) + 1 }},
+			{"Unknown timing", func(b *stateBody) { b.Timing = uint8(TimingVIP) + 1 }},
+			{"Planes beyond the plane count", func(b *stateBody) { b.Planes = 4 }},
+			{"Wait key out of range", func(b *stateBody) { b.WaitKey = 0x10 }},
+			{"Memory size too large", func(b *stateBody) { b.MemorySize = XOChipMemorySize + 1 }},
+			{"Random number generator position out of range", func(b *stateBody) { b.RandFeed = rngLen }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				e := New()
+				e.LoadROMFromData(rom)
+				data := corruptState(t, e, tt.corrupt)
+				e.PC = 0x300
+
+				err := e.LoadState(bytes.NewReader(data))
+				if !errors.Is(err, ErrStateFormat) {
+					t.Errorf("LoadState should return ErrStateFormat, got %v", err)
+				}
+				if e.PC != 0x300 || e.SP != 0 {
+					t.Errorf("Emulator should be unchanged after failed load, PC=0x%04X SP=%d", e.PC, e.SP)
+				}
+			})
+		}
+
+		t.Run("Full stack is valid", func(t *testing.T) {
+			e := New()
+			e.LoadROMFromData(rom)
+			data := corruptState(t, e, func(b *stateBody) { b.SP = StackSize })
+			if err := e.LoadState(bytes.NewReader(data)); err != nil {
+				t.Errorf("LoadState returned error: %v", err)
+			}
+		})
+	})
+}
+
+// corruptState returns a save state of e with its body changed by corrupt
+func corruptState(t *testing.T, e *Emulator, corrupt func(b *stateBody)) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := e.SaveState(&buf); err != nil {
+		t.Fatalf("SaveState returned error: %v", err)
+	}
+
+	var header stateHeader
+	var body stateBody
+	r := bytes.NewReader(buf.Bytes())
+	binary.Read(r, binary.BigEndian, &header)
+	binary.Read(r, binary.BigEndian, &body)
+	memory, _ := io.ReadAll(r)
+	corrupt(&body)
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, &header)
+	binary.Write(&out, binary.BigEndian, &body)
+	out.Write(memory)
+	return out.Bytes()
+}
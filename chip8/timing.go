@@ -0,0 +1,173 @@
+package chip8
+
+import "fmt"
+
+// Timing selects how execution is paced against the 60Hz timers
+type Timing int
+
+const (
+	// TimingFixed runs Config.InstructionsPerFrame instructions per frame, or
+	// advances the timers by the time passed to Step
+	TimingFixed Timing = iota
+	// TimingVIP charges each instruction the machine cycles it took the
+	// original COSMAC VIP interpreter, running the vertical blank interrupt
+	// once a frame's cycles are used. The time passed to Step is ignored.
+	TimingVIP
+)
+
+// COSMAC VIP clock: a 1.76064MHz CDP1802, with 8 clock pulses per machine cycle
+const (
+	VIPMachineCyclesPerSecond = 1760640 / 8
+	VIPCyclesPerFrame         = VIPMachineCyclesPerSecond / FramesPerSecond
+
+	// Each frame the display interrupt takes 128 scan lines of 8 DMA cycles and
+	// 6 cycles of the interrupt routine, plus its entry and exit. The
+	// interpreter runs in the cycles left over.
+	vipInterruptCycles   = 1832
+	vipInterpreterCycles = VIPCyclesPerFrame - vipInterruptCycles
+
+	vipFetchCycles = 40 // fetching and decoding an instruction in the interpreter's main loop
+	vipSkipCycles  = 4  // extra cost of a conditional skip being taken
+)
+
+// String returns the name of the timing model, as accepted by ParseTiming
+func (t Timing) String() string {
+	switch t {
+	case TimingFixed:
+		return "fixed"
+	case TimingVIP:
+		return "vip"
+	}
+	return fmt.Sprintf("Timing(%d)", int(t))
+}
+
+// ParseTiming returns the timing model with the given name
+func ParseTiming(name string) (Timing, error) {
+	for _, t := range []Timing{TimingFixed, TimingVIP} {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown timing: %q", name)
+}
+
+// WithTiming sets the timing model, see Timing
+func WithTiming(timing Timing) EmulatorOption {
+	return func(e *Emulator) {
+		e.Config.Timing = timing
+	}
+}
+
+// InstructionCycles returns the COSMAC VIP machine cycles taken by the last
+// instruction executed by Step, which TimingVIP charges against the frame.
+// It is 0 after a Step spent waiting for the vertical blank.
+func (e *Emulator) InstructionCycles() int {
+	return e.instructionCycles
+}
+
+// vipCycles returns the machine cycles the COSMAC VIP interpreter takes to
+// fetch and execute opcode, excluding any conditional skip. It must be called
+// before the opcode is executed, as the cost of some depends on registers.
+// These follow the interpreter's code paths: DXYN shifts each sprite row into
+// place one bit at a time and FX33 divides by repeated subtraction, so both
+// vary with their operands. Extension opcodes the VIP lacks cost the same as
+// the cheapest register operations.
+func (e *Emulator) vipCycles(opcode uint16) int {
+	x := (opcode & 0x0F00) >> 8
+	n := int(opcode & 0x000F)
+
+	cycles := 10
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			cycles = 24 + 256*12 // clears 256 bytes of display memory
+		case 0x00EE:
+			cycles = 10
+		default:
+			if !e.superChip() {
+				cycles = 22 // calling the machine code routine, not counting the routine itself
+			}
+		}
+	case 0x1000:
+		cycles = 12
+	case 0x2000:
+		cycles = 26
+	case 0x3000, 0x4000:
+		cycles = 10
+	case 0x5000, 0x9000:
+		cycles = 14
+	case 0x6000:
+		cycles = 6
+	case 0x7000:
+		cycles = 10
+	case 0x8000:
+		cycles = 44
+	case 0xA000:
+		cycles = 12
+	case 0xB000:
+		cycles = 22
+	case 0xC000:
+		cycles = 36
+	case 0xD000:
+		rows := n
+		if n == 0 && e.superChip() {
+			rows = BigSpriteWidth * 2
+		}
+		shift := int(e.Registers[x] & 0x7)
+		cycles = 26 + rows*(34+4*shift)
+	case 0xE000:
+		cycles = 14
+	case 0xF000:
+		switch opcode & 0x00FF {
+		case 0x07, 0x15, 0x18:
+			cycles = 10
+		case 0x0A:
+			cycles = 18 // each poll of the keypad
+		case 0x1E, 0x29:
+			cycles = 16
+		case 0x33:
+			value := e.Registers[x]
+			cycles = 84 + 8*int(value/100+value/10%10+value%10)
+		case 0x55, 0x65:
+			cycles = 14 + 14*int(x+1)
+		}
+	}
+	return vipFetchCycles + cycles
+}
+
+// isSkip reports whether opcode is a conditional skip
+func isSkip(opcode uint16) bool {
+	switch opcode & 0xF000 {
+	case 0x3000, 0x4000, 0x5000, 0x9000, 0xE000:
+		return true
+	}
+	return false
+}
+
+// runVIPClock charges an instruction's machine cycles to the frame, running
+// the vertical blank interrupt once the cycles left to the interpreter are
+// used. The interpreter waits for the interrupt before DXYN draws, so with the
+// display wait quirk the cost of drawing falls in the following frame.
+func (e *Emulator) runVIPClock(cycles int) {
+	if e.waitingForVBlank {
+		e.waitForVIPVBlank()
+	}
+	e.vipFrameCycles += cycles
+	for e.vipFrameCycles >= vipInterpreterCycles {
+		e.vipFrameCycles -= vipInterpreterCycles
+		e.vipVBlank()
+	}
+}
+
+// waitForVIPVBlank idles for the rest of the frame, until the vertical blank interrupt
+func (e *Emulator) waitForVIPVBlank() {
+	e.vipFrameCycles = 0
+	e.vipVBlank()
+}
+
+// vipVBlank runs the vertical blank interrupt
+func (e *Emulator) vipVBlank() {
+	e.vblank = true
+	e.tickTimers()
+}
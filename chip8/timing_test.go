@@ -0,0 +1,113 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+)
+
+func TestInstructionCycles(t *testing.T) {
+	tests := []struct {
+		name     string
+		opcode   uint16
+		v0       byte
+		expected int
+	}{
+		{"Load", 0x6005, 0, 46},
+		{"Skip taken", 0x3000, 0, 54},
+		{"Skip not taken", 0x3001, 0, 50},
+		{"Draw aligned", 0xD001, 0, 100},
+		{"Draw shifted", 0xD002, 3, 40 + 26 + 2*(34+12)},
+		{"Decimal digits", 0xF033, 255, 40 + 84 + 8*12},
+		{"Store registers", 0xF255, 0, 40 + 14 + 3*14},
+	}
+	for _, tt := range tests {
+		e := New()
+		e.LoadROMFromData([]byte{byte(tt.opcode >> 8), byte(tt.opcode)})
+		e.Registers[0] = tt.v0
+		e.I = 0x300
+		if err := e.Step(0); err != nil {
+			t.Fatalf("%s: Step returned error: %v", tt.name, err)
+		}
+		if e.InstructionCycles() != tt.expected {
+			t.Errorf("%s: expected %d machine cycles, got %d", tt.name, tt.expected, e.InstructionCycles())
+		}
+	}
+}
+
+func TestVIPTiming(t *testing.T) {
+	t.Run("Runs until the frame's cycles are used", func(t *testing.T) {
+		e := New(WithTiming(TimingVIP), WithInstructionsPerFrame(1))
+		e.LoadROMFromData(asm.MustAssemble(`
+		loop:
+			ld v0, 1
+			jp loop
+		`))
+		e.DelayTimer = 5
+
+		if _, err := e.RunFrame(); err != nil {
+			t.Fatalf("RunFrame returned error: %v", err)
+		}
+		// 18 pairs of 46 + 52 cycles, then one more pair crosses 1836
+		if e.Cycle() != 38 || e.Frame() != 1 {
+			t.Errorf("Expected 38 instructions in the first frame, got %d", e.Cycle())
+		}
+		if e.DelayTimer != 4 {
+			t.Errorf("Delay timer should tick once per frame, got %d", e.DelayTimer)
+		}
+		if e.vipFrameCycles != 1862-vipInterpreterCycles {
+			t.Errorf("Expected %d cycles carried into the next frame, got %d", 1862-vipInterpreterCycles, e.vipFrameCycles)
+		}
+	})
+
+	t.Run("Step ignores the time passed", func(t *testing.T) {
+		e := New(WithTiming(TimingVIP))
+		e.LoadROMFromData(asm.MustAssemble(`
+		loop:
+			jp loop
+		`))
+		e.DelayTimer = 5
+		e.Step(time.Second)
+		if e.DelayTimer != 5 {
+			t.Errorf("Delay timer should not tick within a frame's cycles, got %d", e.DelayTimer)
+		}
+		for range vipInterpreterCycles / 52 {
+			e.Step(0)
+		}
+		if e.DelayTimer != 4 {
+			t.Errorf("Delay timer should tick once the frame's cycles are used, got %d", e.DelayTimer)
+		}
+	})
+
+	t.Run("Draw waits for the vertical blank", func(t *testing.T) {
+		e := New(WithPlatform(PlatformCosmacVIP), WithTiming(TimingVIP))
+		e.LoadROMFromData(asm.MustAssemble(`
+			ld  i, 0x300
+			drw v0, v0, 1
+		loop:
+			jp  loop
+		`))
+
+		if _, err := e.RunFrame(); err != nil {
+			t.Fatalf("RunFrame returned error: %v", err)
+		}
+		if e.Cycle() != 2 {
+			t.Errorf("Draw should end the frame after 2 instructions, got %d", e.Cycle())
+		}
+		if e.vipFrameCycles != 100 {
+			t.Errorf("Draw should be charged to the next frame, got %d cycles", e.vipFrameCycles)
+		}
+	})
+
+	t.Run("Parse timing", func(t *testing.T) {
+		for _, timing := range []Timing{TimingFixed, TimingVIP} {
+			if parsed, err := ParseTiming(timing.String()); err != nil || parsed != timing {
+				t.Errorf("ParseTiming(%q) returned %v, %v", timing.String(), parsed, err)
+			}
+		}
+		if _, err := ParseTiming("fast"); err == nil {
+			t.Error("ParseTiming should reject unknown names")
+		}
+	})
+}
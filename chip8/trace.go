@@ -0,0 +1,301 @@
+package chip8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Trace file format identification
+const (
+	traceMagic   = "C8TR"
+	TraceVersion = 1 // Version of the binary trace format written by TraceWriter
+)
+
+// ErrTraceFormat is returned by TraceReader when a trace can't be parsed
+var ErrTraceFormat = errors.New("invalid trace")
+
+// TraceRecord is the state after executing one instruction. Cycles spent
+// waiting for the display run no instruction and aren't traced.
+type TraceRecord struct {
+	Cycle  uint64 // cycle number, as returned by Emulator.Cycle
+	PC     uint16 // address of the instruction
+	Opcode uint16
+	Long   uint16 // address following an XO-CHIP F000 opcode, otherwise 0
+	V      [16]byte
+	I      uint16
+	SP     byte
+	DT     byte
+	ST     byte
+}
+
+// Mnemonic returns the assembly for the traced instruction, see Mnemonic
+func (r TraceRecord) Mnemonic(variant Variant) string {
+	return Mnemonic(r.Opcode, r.Long, variant)
+}
+
+// Tracer is called with the state after every executed instruction
+type Tracer func(TraceRecord)
+
+// WithTracer sets a callback fired after every executed instruction, see SetTracer
+func WithTracer(tracer Tracer) EmulatorOption {
+	return func(e *Emulator) {
+		e.SetTracer(tracer)
+	}
+}
+
+// SetTracer sets a callback fired after every instruction executed by Step,
+// replacing any previous tracer. Pass nil to remove it.
+func (e *Emulator) SetTracer(tracer Tracer) {
+	e.tracer = tracer
+}
+
+// trace reports the instruction just executed to the tracer
+func (e *Emulator) trace(opcode uint16) {
+	record := TraceRecord{
+		Cycle:  e.cycle,
+		PC:     e.instructionPC,
+		Opcode: opcode,
+		V:      e.Registers,
+		I:      e.I,
+		SP:     e.SP,
+		DT:     e.DelayTimer,
+		ST:     e.SoundTimer,
+	}
+	if opcode == 0xF000 && e.xoChip() && int(e.instructionPC)+3 < len(e.Memory) {
+		record.Long = uint16(e.Memory[e.instructionPC+2])<<8 | uint16(e.Memory[e.instructionPC+3])
+	}
+	e.tracer(record)
+}
+
+// TraceFormat selects how a TraceWriter encodes records
+type TraceFormat int
+
+const (
+	// TraceText writes one line per instruction:
+	//   CYCLE PC OPCODE V=V0..VF I=I SP=SP DT=DT ST=ST ; MNEMONIC
+	// with all numbers but the cycle in hex, e.g.
+	//   12 0216 7101 V=00050000000000000000000000000000 I=0300 SP=0 DT=00 ST=00 ; ADD V1, 0x01
+	// The opcode of an XO-CHIP F000 NNNN includes the address, e.g. F0001234.
+	// Lines starting with # are comments.
+	TraceText TraceFormat = iota
+	// TraceBinary writes a header followed by fixed size big endian records
+	TraceBinary
+)
+
+// String returns the name of the format, as accepted by ParseTraceFormat
+func (f TraceFormat) String() string {
+	switch f {
+	case TraceText:
+		return "text"
+	case TraceBinary:
+		return "binary"
+	}
+	return fmt.Sprintf("TraceFormat(%d)", int(f))
+}
+
+// ParseTraceFormat returns the trace format with the given name
+func ParseTraceFormat(name string) (TraceFormat, error) {
+	for _, f := range []TraceFormat{TraceText, TraceBinary} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown trace format: %q", name)
+}
+
+// TraceFilter selects which instructions are traced
+type TraceFilter struct {
+	Start, End uint16 // address range of traced instructions, inclusive. Both zero traces all addresses
+	Classes    uint16 // bit N set traces opcodes with a high nibble of N, e.g. 1<<0xD for DXYN. Zero traces all opcodes
+}
+
+// Match reports whether the filter selects the record
+func (f TraceFilter) Match(r TraceRecord) bool {
+	if (f.Start != 0 || f.End != 0) && (r.PC < f.Start || r.PC > f.End) {
+		return false
+	}
+	return f.Classes == 0 || f.Classes&(1<<(r.Opcode>>12)) != 0
+}
+
+// traceHeader starts a binary trace
+type traceHeader struct {
+	Magic   [4]byte
+	Version uint16
+	Variant uint8
+}
+
+// traceEntry is the binary representation of a TraceRecord
+type traceEntry TraceRecord
+
+// TraceWriter writes trace records to a file, and can be used as a Tracer:
+//
+//	tw := chip8.NewTraceWriter(file, chip8.TraceText, emu.Config.Variant)
+//	emu.SetTracer(tw.Trace)
+//	...
+//	err := tw.Flush()
+type TraceWriter struct {
+	Filter TraceFilter
+
+	w       *bufio.Writer
+	format  TraceFormat
+	variant Variant // decodes mnemonics in text traces
+	err     error   // first write error, returned by Flush
+}
+
+// NewTraceWriter creates a writer of traces in format, with mnemonics for variant.
+// Output is buffered until Flush.
+func NewTraceWriter(w io.Writer, format TraceFormat, variant Variant) *TraceWriter {
+	t := &TraceWriter{w: bufio.NewWriter(w), format: format, variant: variant}
+	switch format {
+	case TraceBinary:
+		header := traceHeader{Version: TraceVersion, Variant: uint8(variant)}
+		copy(header.Magic[:], traceMagic)
+		t.err = binary.Write(t.w, binary.BigEndian, &header)
+	default:
+		_, t.err = fmt.Fprintf(t.w, "# chip8 trace, variant %s\n", variant)
+	}
+	return t
+}
+
+// Trace writes the record if it matches the filter. Write errors stop the
+// trace and are returned by Flush.
+func (t *TraceWriter) Trace(r TraceRecord) {
+	if t.err != nil || !t.Filter.Match(r) {
+		return
+	}
+	if t.format == TraceBinary {
+		t.err = binary.Write(t.w, binary.BigEndian, traceEntry(r))
+		return
+	}
+
+	opcode := fmt.Sprintf("%04X", r.Opcode)
+	if r.Opcode == 0xF000 && t.variant >= VariantXOChip {
+		opcode += fmt.Sprintf("%04X", r.Long)
+	}
+	_, t.err = fmt.Fprintf(t.w, "%d %04X %s V=%X I=%04X SP=%X DT=%02X ST=%02X ; %s\n",
+		r.Cycle, r.PC, opcode, r.V[:], r.I, r.SP, r.DT, r.ST, r.Mnemonic(t.variant))
+}
+
+// Flush writes any buffered records, and returns the first error writing the trace
+func (t *TraceWriter) Flush() error {
+	if t.err != nil {
+		return fmt.Errorf("failed to write trace: %w", t.err)
+	}
+	if err := t.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+// TraceReader reads traces written by TraceWriter, detecting the format
+type TraceReader struct {
+	r      *bufio.Reader
+	binary bool
+	line   int // line number of the last text record read
+	err    error
+}
+
+// NewTraceReader creates a reader of a text or binary trace
+func NewTraceReader(r io.Reader) *TraceReader {
+	t := &TraceReader{r: bufio.NewReader(r)}
+	if magic, err := t.r.Peek(len(traceMagic)); err == nil && bytes.Equal(magic, []byte(traceMagic)) {
+		var header traceHeader
+		if err := binary.Read(t.r, binary.BigEndian, &header); err != nil {
+			t.err = fmt.Errorf("%w: %w", ErrTraceFormat, err)
+		} else if header.Version != TraceVersion {
+			t.err = fmt.Errorf("%w: unsupported version %d (expected %d)", ErrTraceFormat, header.Version, TraceVersion)
+		}
+		t.binary = true
+	}
+	return t
+}
+
+// Next returns the next record, or io.EOF at the end of the trace
+func (t *TraceReader) Next() (TraceRecord, error) {
+	if t.err != nil {
+		return TraceRecord{}, t.err
+	}
+	if t.binary {
+		var entry traceEntry
+		err := binary.Read(t.r, binary.BigEndian, &entry)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("%w: truncated record", ErrTraceFormat)
+		}
+		return TraceRecord(entry), err
+	}
+
+	for {
+		text, err := t.r.ReadString('\n')
+		if err != nil && (err != io.EOF || text == "") {
+			return TraceRecord{}, err
+		}
+		t.line++
+		text = strings.TrimSpace(text)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		record, err := parseTraceLine(text)
+		if err != nil {
+			return TraceRecord{}, fmt.Errorf("%w: line %d: %w", ErrTraceFormat, t.line, err)
+		}
+		return record, nil
+	}
+}
+
+// parseTraceLine parses a line of a text trace, ignoring the mnemonic
+func parseTraceLine(text string) (TraceRecord, error) {
+	text, _, _ = strings.Cut(text, ";")
+	fields := strings.Fields(text)
+	if len(fields) != 8 {
+		return TraceRecord{}, fmt.Errorf("expected 8 fields, got %d", len(fields))
+	}
+
+	var r TraceRecord
+	var err error
+	// hex parses a hex number of at most size bits, keeping the first error
+	hex := func(s string, size int) uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = strconv.ParseUint(s, 16, size)
+		return v
+	}
+	// field returns the value of a NAME=VALUE field
+	field := func(i int, name string) string {
+		value, ok := strings.CutPrefix(fields[i], name+"=")
+		if !ok && err == nil {
+			err = fmt.Errorf("expected %s=, got %q", name, fields[i])
+		}
+		return value
+	}
+
+	r.Cycle, err = strconv.ParseUint(fields[0], 10, 64)
+	r.PC = uint16(hex(fields[1], 16))
+	if opcode := fields[2]; len(opcode) == 8 {
+		r.Opcode = uint16(hex(opcode[:4], 16))
+		r.Long = uint16(hex(opcode[4:], 16))
+	} else {
+		r.Opcode = uint16(hex(opcode, 16))
+	}
+	registers := field(3, "V")
+	if len(registers) != 2*len(r.V) && err == nil {
+		err = fmt.Errorf("expected %d registers, got %q", len(r.V), registers)
+	}
+	for i := range r.V {
+		if err == nil {
+			r.V[i] = byte(hex(registers[2*i:2*i+2], 8))
+		}
+	}
+	r.I = uint16(hex(field(4, "I"), 16))
+	r.SP = byte(hex(field(5, "SP"), 8))
+	r.DT = byte(hex(field(6, "DT"), 8))
+	r.ST = byte(hex(field(7, "ST"), 8))
+	return r, err
+}
@@ -0,0 +1,177 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+)
+
+// traceProgram runs source for cycles steps, returning the trace records
+func traceProgram(t *testing.T, source string, cycles int, options ...EmulatorOption) []TraceRecord {
+	t.Helper()
+	var records []TraceRecord
+	e := New(append(options, WithTracer(func(r TraceRecord) {
+		records = append(records, r)
+	}))...)
+	if err := e.LoadROMFromData(asm.MustAssemble(source)); err != nil {
+		t.Fatalf("LoadROMFromData returned error: %v", err)
+	}
+	for range cycles {
+		if err := e.Step(0); err != nil && !errors.Is(err, ErrProgramExit) {
+			t.Fatalf("Step returned error: %v", err)
+		}
+	}
+	return records
+}
+
+func TestTrace(t *testing.T) {
+	source := `
+		ld  v0, 5
+		ld  i, 0x300
+		add v0, 1
+		ld  dt, v0
+		jp  0x200
+	`
+
+	t.Run("Records state after each instruction", func(t *testing.T) {
+		records := traceProgram(t, source, 6)
+
+		if len(records) != 6 {
+			t.Fatalf("Expected 6 records, got %d", len(records))
+		}
+		third := records[2]
+		if third.Cycle != 3 || third.PC != 0x204 || third.Opcode != 0x7001 {
+			t.Errorf("Third record should be cycle 3, PC 0x204, opcode 0x7001, got %+v", third)
+		}
+		if third.V[0] != 6 || third.I != 0x300 {
+			t.Errorf("Third record should have V0 = 6 and I = 0x300, got %+v", third)
+		}
+		if records[3].DT != 6 {
+			t.Errorf("Fourth record should have DT = 6, got %d", records[3].DT)
+		}
+		if records[5].PC != 0x200 {
+			t.Errorf("Sixth record should be back at 0x200, got 0x%04X", records[5].PC)
+		}
+	})
+
+	t.Run("Text format", func(t *testing.T) {
+		records := traceProgram(t, source, 3)
+		var buf bytes.Buffer
+		tw := NewTraceWriter(&buf, TraceText, VariantChip8)
+		for _, r := range records {
+			tw.Trace(r)
+		}
+		if err := tw.Flush(); err != nil {
+			t.Fatalf("Flush returned error: %v", err)
+		}
+
+		expected := "# chip8 trace, variant chip8\n" +
+			"1 0200 6005 V=05000000000000000000000000000000 I=0000 SP=0 DT=00 ST=00 ; LD V0, 0x05\n" +
+			"2 0202 A300 V=05000000000000000000000000000000 I=0300 SP=0 DT=00 ST=00 ; LD I, 0x300\n" +
+			"3 0204 7001 V=06000000000000000000000000000000 I=0300 SP=0 DT=00 ST=00 ; ADD V0, 0x01\n"
+		if buf.String() != expected {
+			t.Errorf("Expected trace:\n%s\ngot:\n%s", expected, buf.String())
+		}
+	})
+
+	for _, format := range []TraceFormat{TraceText, TraceBinary} {
+		t.Run("Read "+format.String()+" format", func(t *testing.T) {
+			records := traceProgram(t, `
+				ld   i, long 0x1234
+				ld   v3, 0xAB
+				call sub
+				exit
+			sub:
+				ret
+			`, 5, WithVariant(VariantXOChip))
+
+			var buf bytes.Buffer
+			tw := NewTraceWriter(&buf, format, VariantXOChip)
+			for _, r := range records {
+				tw.Trace(r)
+			}
+			if err := tw.Flush(); err != nil {
+				t.Fatalf("Flush returned error: %v", err)
+			}
+
+			tr := NewTraceReader(&buf)
+			for i, expected := range records {
+				r, err := tr.Next()
+				if err != nil {
+					t.Fatalf("Next returned error for record %d: %v", i, err)
+				}
+				if r != expected {
+					t.Errorf("Record %d should be %+v, got %+v", i, expected, r)
+				}
+			}
+			if _, err := tr.Next(); err != io.EOF {
+				t.Errorf("Expected io.EOF after the last record, got %v", err)
+			}
+		})
+	}
+
+	t.Run("Invalid text trace", func(t *testing.T) {
+		tr := NewTraceReader(bytes.NewBufferString("# comment\n1 0200 6005 V=05 I=0000 SP=0 DT=00 ST=00\n"))
+		if _, err := tr.Next(); !errors.Is(err, ErrTraceFormat) {
+			t.Errorf("Expected ErrTraceFormat, got %v", err)
+		}
+	})
+
+	t.Run("Filter by address range and opcode class", func(t *testing.T) {
+		records := traceProgram(t, source, 10)
+		tests := []struct {
+			name     string
+			filter   TraceFilter
+			expected int
+		}{
+			{"No filter", TraceFilter{}, 10},
+			{"Address range", TraceFilter{Start: 0x202, End: 0x204}, 4},
+			{"Opcode class", TraceFilter{Classes: 1<<0x6 | 1<<0x1}, 4},
+			{"Both", TraceFilter{Start: 0x200, End: 0x204, Classes: 1 << 0x7}, 2},
+		}
+		for _, tt := range tests {
+			count := 0
+			for _, r := range records {
+				if tt.filter.Match(r) {
+					count++
+				}
+			}
+			if count != tt.expected {
+				t.Errorf("%s: expected %d records, got %d", tt.name, tt.expected, count)
+			}
+		}
+	})
+}
+
+func TestMnemonic(t *testing.T) {
+	// every mnemonic should assemble back to the same instruction
+	for opcode := range 0x10000 {
+		opcode := uint16(opcode)
+		mnemonic := Mnemonic(opcode, 0xABCD, VariantXOChip)
+		if mnemonic == "???" {
+			continue
+		}
+		rom, err := asm.Assemble(mnemonic)
+		if err != nil {
+			t.Errorf("Mnemonic %q of 0x%04X does not assemble: %v", mnemonic, opcode, err)
+			continue
+		}
+		expected := []byte{byte(opcode >> 8), byte(opcode)}
+		if opcode == 0xF000 {
+			expected = append(expected, 0xAB, 0xCD)
+		}
+		if !bytes.Equal(rom.ROM, expected) {
+			t.Errorf("Mnemonic %q of 0x%04X assembles to %X", mnemonic, opcode, rom.ROM)
+		}
+	}
+
+	if mnemonic := Mnemonic(0x00FF, 0, VariantChip8); mnemonic != "SYS 0x0FF" {
+		t.Errorf("0x00FF should be SYS on CHIP-8, got %q", mnemonic)
+	}
+	if mnemonic := Mnemonic(0xF000, 0, VariantSuperChip); mnemonic != "???" {
+		t.Errorf("0xF000 should be invalid on SUPER-CHIP, got %q", mnemonic)
+	}
+}
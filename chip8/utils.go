@@ -28,6 +28,29 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		case 0x00EE:
 			// Return from subroutine
 			return "Return from subroutine"
+		case 0x00FB:
+			// 00FB: Scroll right (SUPER-CHIP)
+			return "Scroll display right 4 pixels"
+		case 0x00FC:
+			// 00FC: Scroll left (SUPER-CHIP)
+			return "Scroll display left 4 pixels"
+		case 0x00FD:
+			// 00FD: Exit (SUPER-CHIP)
+			return "Exit interpreter"
+		case 0x00FE:
+			// 00FE: Low resolution (SUPER-CHIP)
+			return "Switch to low resolution (64x32)"
+		case 0x00FF:
+			// 00FF: High resolution (SUPER-CHIP)
+			return "Switch to high resolution (128x64)"
+		}
+		if opcode&0xFFF0 == 0x00C0 {
+			// 00CN: Scroll down (SUPER-CHIP)
+			return fmt.Sprintf("Scroll display down %d pixels", n)
+		}
+		if opcode&0xFFF0 == 0x00D0 {
+			// 00DN: Scroll up (XO-CHIP)
+			return fmt.Sprintf("Scroll display up %d pixels", n)
 		}
 	case 0x1000:
 		// 1NNN: Jump
@@ -42,9 +65,16 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		// 4XNN: Skip next instruction if VX not equal to NN
 		return fmt.Sprintf("Skip next instruction if V%X (0x%02X) != 0x%02X", x, e.Registers[x], nn)
 	case 0x5000:
-		// 5XY0: Skip next instruction if VX equal to VY
-		if n == 0 {
+		switch n {
+		case 0x0:
+			// 5XY0: Skip next instruction if VX equal to VY
 			return fmt.Sprintf("Skip next instruction if V%X (0x%02X) == V%X (0x%02X)", x, e.Registers[x], y, e.Registers[y])
+		case 0x2:
+			// 5XY2: Store VX-VY at address I (XO-CHIP)
+			return fmt.Sprintf("Store registers V%X-V%X at address I (0x%04X)", x, y, e.I)
+		case 0x3:
+			// 5XY3: Load VX-VY from address I (XO-CHIP)
+			return fmt.Sprintf("Load registers V%X-V%X from address I (0x%04X)", x, y, e.I)
 		}
 	case 0x6000:
 		// 6XNN: Set
@@ -74,7 +104,7 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 			return fmt.Sprintf("Subtract V%X -= V%X (0x%02X - 0x%02X) with borrow", x, y, e.Registers[x], e.Registers[y])
 		case 0x6:
 			// 8XY6: Shift right
-			if e.Config.LegacyShift {
+			if e.Config.Quirks.ShiftUsesVY {
 				return fmt.Sprintf("Set V%X = V%X (0x%02X) >> 1 with VF = LSB", x, y, e.Registers[y])
 			} else {
 				return fmt.Sprintf("Shift V%X (0x%02X) >> 1 with VF = LSB", x, e.Registers[x])
@@ -84,7 +114,7 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 			return fmt.Sprintf("Set V%X = V%X - V%X (0x%02X - 0x%02X) with borrow", x, y, x, e.Registers[y], e.Registers[x])
 		case 0xE:
 			// 8XYE: Shift left
-			if e.Config.LegacyShift {
+			if e.Config.Quirks.ShiftUsesVY {
 				return fmt.Sprintf("Set V%X = V%X (0x%02X) << 1 with VF = MSB", x, y, e.Registers[y])
 			} else {
 				return fmt.Sprintf("Shift V%X (0x%02X) << 1 with VF = MSB", x, e.Registers[x])
@@ -100,7 +130,7 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		return fmt.Sprintf("Set I = 0x%03X", nnn)
 	case 0xB000:
 		// BNNN: Jump with offset
-		if e.Config.LegacyJump {
+		if e.Config.Quirks.JumpUsesV0 {
 			return fmt.Sprintf("Jump to address 0x%03X + V0 (0x%02X)", nnn, e.Registers[0])
 		} else {
 			return fmt.Sprintf("Jump to address 0x%03X + V%X (0x%02X)", nnn, x, e.Registers[x])
@@ -110,6 +140,9 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		return fmt.Sprintf("Set V%X = random & 0x%02X", x, nn)
 	case 0xD000:
 		// DXYN: Display
+		if n == 0 && e.superChip() {
+			return fmt.Sprintf("Draw 16x16 sprite at (V%X,V%X) = (%d,%d)", x, y, e.Registers[x], e.Registers[y])
+		}
 		return fmt.Sprintf("Draw sprite at (V%X,V%X) = (%d,%d) with height %d", x, y, e.Registers[x], e.Registers[y], n)
 	case 0xE000:
 		switch nn {
@@ -122,6 +155,16 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		}
 	case 0xF000:
 		switch nn {
+		case 0x00:
+			// F000 NNNN: Long index load (XO-CHIP)
+			address := uint16(e.Memory[e.PC+2])<<8 | uint16(e.Memory[e.PC+3])
+			return fmt.Sprintf("Set I = 0x%04X", address)
+		case 0x01:
+			// FN01: Select planes (XO-CHIP)
+			return fmt.Sprintf("Select display planes %d", x)
+		case 0x02:
+			// F002: Load audio pattern (XO-CHIP)
+			return fmt.Sprintf("Load audio pattern from address I (0x%04X)", e.I)
 		case 0x07:
 			// FX07 Set VX to current value of delay timer
 			return fmt.Sprintf("Set V%X = delay timer (0x%02X)", x, e.DelayTimer)
@@ -140,6 +183,12 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		case 0x29:
 			// 0xFX29 Set I to address of font for hex char in VX
 			return fmt.Sprintf("Set I to font address for hex digit V%X (0x%02X)", x, e.Registers[x])
+		case 0x30:
+			// 0xFX30 Set I to address of big font for hex char in VX
+			return fmt.Sprintf("Set I to big font address for hex digit V%X (0x%02X)", x, e.Registers[x])
+		case 0x3A:
+			// 0xFX3A Set pitch to VX
+			return fmt.Sprintf("Set pitch = V%X (0x%02X)", x, e.Registers[x])
 		case 0x33:
 			// 0xFX33 Take number in VX, convert to three decimal digits, and store at address in I, I+1, I+2
 			return fmt.Sprintf("Store BCD of V%X (0x%02X) at I, I+1, I+2", x, e.Registers[x])
@@ -149,6 +198,12 @@ func getOpcodeDescription(e *Emulator, opcode uint16) string {
 		case 0x65:
 			// 0xFX65 Load memory from address I into V0-VX
 			return fmt.Sprintf("Load registers V0-V%X from address I (0x%04X)", x, e.I)
+		case 0x75:
+			// 0xFX75 Store V0-VX in RPL user flags
+			return fmt.Sprintf("Store registers V0-V%X in RPL flags", x)
+		case 0x85:
+			// 0xFX85 Load V0-VX from RPL user flags
+			return fmt.Sprintf("Load registers V0-V%X from RPL flags", x)
 		}
 	}
 	return ""
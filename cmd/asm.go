@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+)
+
+func runAssembler(args []string) {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	outPath := flags.String("o", "", "Output ROM file (default SOURCE with the extension replaced by .ch8, or stdout when reading stdin)")
+	symbolPath := flags.String("sym", "", "Write label addresses to this symbol file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8 asm [flags] [SOURCE]")
+		fmt.Fprintln(flags.Output(), "Reads stdin if SOURCE is - or omitted, e.g. chip8 disasm game.ch8 | chip8 asm -o copy.ch8")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	sourcePath := flags.Arg(0)
+	fromStdin := sourcePath == "" || sourcePath == "-"
+
+	var source []byte
+	var err error
+	if fromStdin {
+		sourcePath = "stdin"
+		source, err = io.ReadAll(os.Stdin)
+	} else {
+		source, err = os.ReadFile(sourcePath)
+	}
+	if err != nil {
+		fmt.Printf("Error reading source: %v\n", err)
+		os.Exit(1)
+	}
+
+	program, err := asm.Assemble(string(source))
+	if err != nil {
+		fmt.Printf("%s: %v\n", sourcePath, err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *outPath == "" && fromStdin:
+		_, err = os.Stdout.Write(program.ROM)
+	case *outPath == "":
+		*outPath = strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath)) + ".ch8"
+		fallthrough
+	default:
+		err = os.WriteFile(*outPath, program.ROM, 0o644)
+	}
+	if err != nil {
+		fmt.Printf("Error writing ROM: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *symbolPath != "" {
+		file, err := os.Create(*symbolPath)
+		if err != nil {
+			fmt.Printf("Error creating symbol file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		if err := program.WriteSymbols(file); err != nil {
+			fmt.Printf("Error writing symbol file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/disasm"
+)
+
+func runDisassembler(args []string) {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	variantName := flags.String("variant", chip8.VariantXOChip.String(), "Instruction set to decode: chip8, schip or xochip")
+	raw := flags.Bool("raw", false, "Prefix each line with its address and bytes (output cannot be reassembled)")
+	outPath := flags.String("o", "", "Output file (default stdout)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8 disasm [flags] ROM")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	variant, err := chip8.ParseVariant(*variantName)
+	if err != nil {
+		fmt.Println("Invalid variant. Use chip8, schip or xochip")
+		os.Exit(1)
+	}
+
+	rom, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error loading ROM: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *outPath != "" {
+		if out, err = os.Create(*outPath); err != nil {
+			fmt.Printf("Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	if err := disasm.Disassemble(out, rom, disasm.Options{Variant: variant, Raw: *raw}); err != nil {
+		fmt.Printf("Error writing disassembly: %v\n", err)
+		os.Exit(1)
+	}
+}
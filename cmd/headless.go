@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/capture"
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/sound"
+)
+
+// keyEvent sets the held keys from a frame onwards
+type keyEvent struct {
+	frame int
+	keys  [16]bool
+}
+
+// parseKeyScript parses a key script of comma separated FRAME=KEYS entries,
+// where KEYS are the hex keys held from that frame until the next entry,
+// e.g. "60=5,64=,120=4A" holds 5 for 4 frames, then 4 and A from frame 120.
+// A script starting with @ is read from that file, entries may also be on separate lines.
+func parseKeyScript(script string) ([]keyEvent, error) {
+	if path, ok := strings.CutPrefix(script, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key script: %w", err)
+		}
+		script = string(data)
+	}
+
+	var events []keyEvent
+	entries := strings.FieldsFunc(script, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		frameText, keys, found := strings.Cut(entry, "=")
+		frame, err := strconv.Atoi(strings.TrimSpace(frameText))
+		if !found || err != nil || frame < 0 {
+			return nil, fmt.Errorf("invalid key script entry %q, expected FRAME=KEYS", entry)
+		}
+		if len(events) > 0 && frame < events[len(events)-1].frame {
+			return nil, fmt.Errorf("key script entry %q is out of order", entry)
+		}
+
+		event := keyEvent{frame: frame}
+		for _, key := range strings.TrimSpace(keys) {
+			k, err := strconv.ParseUint(string(key), 16, 4)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %q in key script entry %q", key, entry)
+			}
+			event.keys[k] = true
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// runHeadless runs the emulator without output for a fixed number of frames,
+// or whole frames until a number of cycles have run, then writes the display.
+// Returns an error if the emulator failed.
+func runHeadless(emu *chip8.Emulator, options *options) error {
+	events, err := parseKeyScript(options.keyScript)
+	if err != nil {
+		return err
+	}
+
+	frames := options.frames
+	if options.replayPath != "" {
+		movie, err := readMovie(options.replayPath)
+		if err != nil {
+			return err
+		}
+		if err := emu.PlayMovie(movie); err != nil {
+			return err
+		}
+		if options.cycles == 0 && frames == 0 {
+			frames = int(movie.Length)
+		}
+	}
+
+	if options.recordPath != "" {
+		if err := emu.StartRecording(); err != nil {
+			return err
+		}
+		// saved even if emulation fails, so the movie reproduces the failure
+		defer func() {
+			if err := writeMovie(options.recordPath, emu.StopRecording()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing movie: %v\n", err)
+			}
+		}()
+	}
+
+	var recorder *capture.Recorder
+	if options.gifPath != "" {
+		recorder = capture.NewRecorder()
+		// saved even if emulation fails, so the GIF shows what went wrong
+		defer func() {
+			if err := writeGIF(options.gifPath, recorder, options.scale, options.palette); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing GIF: %v\n", err)
+			}
+		}()
+	}
+
+	var buzzer *sound.Buzzer
+	var samples []float32
+	if options.wavPath != "" {
+		buzzer = sound.NewBuzzer(sound.SampleRate, options.sound)
+		// saved even if emulation fails, like the GIF
+		defer func() {
+			if err := writeWAV(options.wavPath, samples); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing WAV: %v\n", err)
+			}
+		}()
+	}
+
+	startCycle := emu.Cycle()
+	for frame := 0; ; frame++ {
+		if options.cycles > 0 && emu.Cycle()-startCycle >= uint64(options.cycles) ||
+			options.cycles == 0 && frame >= frames {
+			break
+		}
+		for len(events) > 0 && events[0].frame <= frame {
+			setKeys(emu, events[0].keys)
+			events = events[1:]
+		}
+
+		_, err := emu.RunFrame()
+		if recorder != nil {
+			recorder.AddFrame(emu.DisplayImage())
+		}
+		if buzzer != nil {
+			// the buzzer sounds for the next frame if the sound timer is still running
+			buzzer.Update(emu)
+			frameSamples := make([]float32, (frame+1)*sound.SampleRate/chip8.FramesPerSecond-len(samples))
+			buzzer.Generate(frameSamples)
+			samples = append(samples, frameSamples...)
+		}
+		if errors.Is(err, chip8.ErrProgramExit) {
+			break
+		}
+		if err != nil {
+			// still write the display, it may show what went wrong
+			if writeErr := writeDisplay(emu, options.outPath, options.outFormat, options.scale, options.palette); writeErr != nil {
+				fmt.Fprintf(os.Stderr, "Error writing display: %v\n", writeErr)
+			}
+			return fmt.Errorf("frame %d: %w", frame, err)
+		}
+	}
+
+	return writeDisplay(emu, options.outPath, options.outFormat, options.scale, options.palette)
+}
+
+// setKeys presses and releases keys to match the held keys, so the changes can be recorded
+func setKeys(emu *chip8.Emulator, keys [16]bool) {
+	for key, pressed := range keys {
+		if pressed {
+			emu.PressKey(byte(key))
+		} else {
+			emu.ReleaseKey(byte(key))
+		}
+	}
+}
+
+func readMovie(path string) (*chip8.Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open movie: %w", err)
+	}
+	defer file.Close()
+	return chip8.ReadMovie(bufio.NewReader(file))
+}
+
+func writeMovie(path string, movie *chip8.Movie) error {
+	var buf bytes.Buffer
+	if err := movie.Write(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// writeGIF writes a recording of the display as an animated GIF
+func writeGIF(path string, recorder *capture.Recorder, scale int, palette capture.Palette) error {
+	var buf bytes.Buffer
+	if err := recorder.WriteGIF(&buf, scale, palette); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// writeWAV writes the buzzer's samples as a WAV file
+func writeWAV(path string, samples []float32) error {
+	var buf bytes.Buffer
+	if err := sound.WriteWAV(&buf, samples, sound.SampleRate); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// displayFormats are the supported output formats, by name and file extension
+var displayFormats = map[string]func(w io.Writer, img *image.Paletted, scale int, palette capture.Palette) error{
+	"png": capture.WritePNG,
+	"pbm": writePBM,
+	"txt": writeASCII,
+}
+
+// writeDisplay writes the display to path, or stdout if path is empty. The
+// format is taken from the file extension unless given.
+func writeDisplay(emu *chip8.Emulator, path string, format string, scale int, palette capture.Palette) error {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+		if path == "" {
+			format = "txt"
+		}
+	}
+	write, ok := displayFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown output format %q, use png, pbm or txt", format)
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, emu.DisplayImage(), scale, palette); err != nil {
+		return err
+	}
+	if path == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// writePBM writes a plain PBM bitmap, with pixels set on any plane black
+func writePBM(w io.Writer, img *image.Paletted, scale int, _ capture.Palette) error {
+	bounds := img.Bounds()
+	fmt.Fprintf(w, "P1\n%d %d\n", bounds.Dx()*scale, bounds.Dy()*scale)
+	for y := range bounds.Dy() * scale {
+		row := make([]string, 0, bounds.Dx()*scale)
+		for x := range bounds.Dx() * scale {
+			if img.ColorIndexAt(x/scale, y/scale) != 0 {
+				row = append(row, "1")
+			} else {
+				row = append(row, "0")
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeASCII writes one character per pixel: '.' off, '#' plane 1,
+// 'o' plane 2 and '@' both planes. Scale and palette are ignored.
+func writeASCII(w io.Writer, img *image.Paletted, _ int, _ capture.Palette) error {
+	glyphs := [4]byte{'.', '#', 'o', '@'}
+	bounds := img.Bounds()
+	for y := range bounds.Dy() {
+		row := make([]byte, 0, bounds.Dx()+1)
+		for x := range bounds.Dx() {
+			row = append(row, glyphs[img.ColorIndexAt(x, y)&0x3])
+		}
+		row = append(row, '\n')
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
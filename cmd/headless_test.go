@@ -0,0 +1,154 @@
+package main
+
+import (
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/capture"
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+func TestParseKeyScript(t *testing.T) {
+	// keys returns the held keys for an event
+	keys := func(held ...int) [16]bool {
+		var k [16]bool
+		for _, key := range held {
+			k[key] = true
+		}
+		return k
+	}
+
+	scriptPath := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(scriptPath, []byte("# jump then run\n10=5\r\n20=46\n\n30=\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   []keyEvent
+		err    string
+	}{
+		{
+			name:   "Entries",
+			script: "60=5,64=,120=4a",
+			want:   []keyEvent{{60, keys(5)}, {64, keys()}, {120, keys(4, 0xA)}},
+		},
+		{
+			name:   "Empty entry releases all keys",
+			script: "0=0123456789ABCDEF,1=",
+			want:   []keyEvent{{0, keys(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xA, 0xB, 0xC, 0xD, 0xE, 0xF)}, {1, keys()}},
+		},
+		{
+			name:   "Comments and blank entries",
+			script: "# start\n 5 = 1 ,,\n# hold 2\n7=2\n",
+			want:   []keyEvent{{5, keys(1)}, {7, keys(2)}},
+		},
+		{
+			name:   "Entries on the same frame",
+			script: "3=1,3=2",
+			want:   []keyEvent{{3, keys(1)}, {3, keys(2)}},
+		},
+		{
+			name:   "File",
+			script: "@" + scriptPath,
+			want:   []keyEvent{{10, keys(5)}, {20, keys(4, 6)}, {30, keys()}},
+		},
+		{name: "Empty script", script: ""},
+		{name: "Out of order", script: "20=1,10=2", err: "out of order"},
+		{name: "Bad key", script: "10=5G", err: `invalid key 'G'`},
+		{name: "Missing keys", script: "10", err: "expected FRAME=KEYS"},
+		{name: "Bad frame", script: "x=1", err: "expected FRAME=KEYS"},
+		{name: "Negative frame", script: "-1=1", err: "expected FRAME=KEYS"},
+		{name: "Missing file", script: "@" + filepath.Join(t.TempDir(), "missing"), err: "failed to read key script"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseKeyScript(test.script)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKeyScript returned error: %v", err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("Expected %d events, got %d: %v", len(test.want), len(got), got)
+			}
+			for i := range test.want {
+				if got[i] != test.want[i] {
+					t.Errorf("Event %d should be %v, got %v", i, test.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+// testDisplay returns a 4x2 display with a pixel of each colour index
+func testDisplay() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, 4, 2), chip8.DisplayPalette)
+	img.SetColorIndex(0, 0, 1)
+	img.SetColorIndex(1, 0, 2)
+	img.SetColorIndex(2, 1, 3)
+	return img
+}
+
+func TestWriteFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(io.Writer, *image.Paletted, int, capture.Palette) error
+		scale int
+		want  string
+	}{
+		{
+			name:  "PBM scale 1",
+			write: writePBM,
+			scale: 1,
+			want: "P1\n4 2\n" +
+				"1 1 0 0\n" +
+				"0 0 1 0\n",
+		},
+		{
+			name:  "PBM scale 2",
+			write: writePBM,
+			scale: 2,
+			want: "P1\n8 4\n" +
+				"1 1 1 1 0 0 0 0\n" +
+				"1 1 1 1 0 0 0 0\n" +
+				"0 0 0 0 1 1 0 0\n" +
+				"0 0 0 0 1 1 0 0\n",
+		},
+		{
+			name:  "ASCII scale 1",
+			write: writeASCII,
+			scale: 1,
+			want: "#o..\n" +
+				"..@.\n",
+		},
+		{
+			// a character per pixel whatever the scale
+			name:  "ASCII scale 2",
+			write: writeASCII,
+			scale: 2,
+			want: "#o..\n" +
+				"..@.\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := test.write(&out, testDisplay(), test.scale, capture.DefaultPalette); err != nil {
+				t.Fatalf("Write returned error: %v", err)
+			}
+			if out.String() != test.want {
+				t.Errorf("Expected output\n%s\ngot\n%s", test.want, out.String())
+			}
+		})
+	}
+}
@@ -1,52 +1,258 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"strings"
 	"time"
 
+	"github.com/bdeatock/chip8-emulator/capture"
 	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/debugger"
+	"github.com/bdeatock/chip8-emulator/sound"
 )
 
 func main() {
-	options := parseCommandLineOptions()
+	// Subcommands, anything else runs the emulator with the original flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			runEmulator(os.Args[2:])
+			return
+		case "disasm":
+			runDisassembler(os.Args[2:])
+			return
+		case "asm":
+			runAssembler(os.Args[2:])
+			return
+		case "tracediff":
+			runTraceDiff(os.Args[2:])
+			return
+		case "help", "-h", "-help", "--help":
+			printUsage()
+			return
+		}
+	}
+	runEmulator(os.Args[1:])
+}
+
+func printUsage() {
+	fmt.Println(`Usage:
+  chip8 [run] -rom ROM [flags]   run a ROM in the terminal, or headless with -headless
+  chip8 disasm [flags] ROM       disassemble a ROM to Octo assembly
+  chip8 asm [flags] [SOURCE]     assemble a ROM from CHIP-8 mnemonics or disasm output
+  chip8 tracediff [flags] ROM REF compare execution with a reference trace or platform
 
-	emu := chip8.New()
-	fmt.Println("=== CHIP-8 Emulator initialized ===")
+Run 'chip8 COMMAND -h' for the flags of a command.`)
+}
+
+func runEmulator(args []string) {
+	options := parseCommandLineOptions(args)
 
+	emulatorOptions := []chip8.EmulatorOption{
+		chip8.WithPlatform(options.platform),
+		chip8.WithInstructionsPerFrame(options.instructionsPerFrame),
+		chip8.WithTiming(options.timing),
+	}
+	if options.seedSet {
+		emulatorOptions = append(emulatorOptions, chip8.WithSeed(options.seed))
+	}
+	if options.useROMDatabase {
+		db, err := chip8.LoadROMDatabase(options.romDatabasePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading ROM database: %v\n", err)
+			os.Exit(1)
+		}
+		emulatorOptions = append(emulatorOptions, chip8.WithROMDatabase(db))
+	}
+	emu := chip8.New(emulatorOptions...)
+
+	out := os.Stdout
+	if options.headless {
+		out = os.Stderr // stdout is the display output
+	} else {
+		fmt.Println("=== CHIP-8 Emulator initialized ===")
+	}
 	if err := emu.LoadROMFromPath(options.romPath); err != nil {
-		fmt.Printf("Error loading ROM: %v\n", err)
+		fmt.Fprintf(out, "Error loading ROM: %v\n", err)
 		os.Exit(1)
 	}
+	applyROMInfo(emu, options, out)
+
+	// start tracing once the ROM database and command line have set the variant
+	stopTrace, err := startTrace(emu, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting trace: %v\n", err)
+		os.Exit(1)
+	}
+	defer stopTrace()
+
+	if options.headless {
+		if err := runHeadless(emu, options); err != nil {
+			stopTrace()
+			fmt.Fprintf(os.Stderr, "Emulation stopped with error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	emu.Print()
 
 	if options.cycleMode == "continuous" {
-		runContinuousMode(emu, options.cyclesPerSecond, options.displayRate)
+		runContinuousMode(emu, options)
 	} else {
-		runStepMode(emu)
+		runStepMode(emu, options.cyclesPerSecond)
+	}
+}
+
+// applyROMInfo reports the ROM database entry the loaded ROM matched, then
+// restores any settings given on the command line, which take precedence
+func applyROMInfo(emu *chip8.Emulator, options *options, w io.Writer) {
+	info, ok := emu.ROMInfo()
+	if !ok {
+		return
+	}
+	fmt.Fprintf(w, "ROM database (%s): %s", info.Source, info.Title)
+	if info.File != "" {
+		fmt.Fprintf(w, " (%s)", info.File)
+	}
+	fmt.Fprintf(w, ", platform %s", info.Platform)
+	if info.Quirks != info.Platform.Profile().Quirks {
+		fmt.Fprint(w, " with custom quirks")
+	}
+	if info.TickRate > 0 {
+		fmt.Fprintf(w, ", %d instructions per frame", info.TickRate)
+	}
+	fmt.Fprintln(w)
+
+	if options.platformSet {
+		emu.Config.SetPlatform(options.platform)
+	}
+	if options.speedSet {
+		emu.Config.InstructionsPerFrame = options.instructionsPerFrame
 	}
 }
 
 type options struct {
-	romPath         string
-	cycleMode       string
-	cyclesPerSecond int
-	displayRate     int
+	romPath              string
+	cycleMode            string
+	cyclesPerSecond      int
+	instructionsPerFrame int
+	displayRate          int
+	ui                   string // continuous mode frontend, tui or plain
+	bell                 bool   // ring the terminal bell for the sound timer
+	platform             chip8.Platform
+	timing               chip8.Timing
+	seed                 int64
+	seedSet              bool
+	platformSet          bool // -platform was given, overriding the ROM database
+	speedSet             bool // -speed or -ipf was given, overriding the ROM database
+
+	useROMDatabase  bool
+	romDatabasePath string
+
+	// headless mode
+	headless   bool
+	cycles     int
+	frames     int
+	keyScript  string
+	recordPath string
+	replayPath string
+	outPath    string
+	outFormat  string
+	scale      int
+	palette    capture.Palette
+	gifPath    string
+	wavPath    string
+	sound      sound.Settings
+
+	// execution trace
+	tracePath   string
+	traceFormat chip8.TraceFormat
+	traceFilter chip8.TraceFilter
 }
 
-func parseCommandLineOptions() *options {
-	romPath := flag.String("rom", "", "Path to the ROM")
-	cycleMode := flag.String("mode", "continuous", "Execution mode: 'step' for manual stepping or 'continuous' for continuous execution")
-	cyclesPerSecond := flag.Int("speed", 700, "Number of cycles per second in continuous mode")
-	displayRate := flag.Int("refresh", 60, "Display refresh rate in Hz")
-	flag.Parse()
+func parseCommandLineOptions(args []string) *options {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	romPath := flags.String("rom", "", "Path to the ROM")
+	cycleMode := flags.String("mode", "continuous", "Execution mode: 'step' for the interactive debugger or 'continuous' for continuous execution")
+	cyclesPerSecond := flags.Int("speed", 700, "Number of instructions per second, run in 60Hz frames unless -ipf is given, and for timers in step mode")
+	instructionsPerFrame := flags.Int("ipf", 0, "Number of instructions per 60Hz frame (default -speed / 60)")
+	displayRate := flags.Int("refresh", 60, "Display refresh rate in Hz, at most 60")
+	ui := flags.String("ui", "tui", "Continuous mode frontend: 'tui' for a full-screen terminal UI with keyboard input, or 'plain' to print the display when it changes. Output which isn't a terminal is always plain")
+	bell := flags.Bool("bell", false, "Terminal UI: ring the terminal bell when the sound timer starts, as well as showing it")
+	platformName := flags.String("platform", chip8.DefaultPlatform.String(), "Platform preset setting instruction set and quirks: "+strings.Join(chip8.PlatformNames(), ", "))
+	timingName := flags.String("timing", chip8.TimingFixed.String(), "Timing model: 'fixed' runs -ipf instructions per frame, 'vip' charges COSMAC VIP machine cycles per instruction and ignores -speed and -ipf")
+	seed := flags.Int64("seed", 0, "Random number generator seed (default random, or 0 in headless mode)")
+	useROMDatabase := flags.Bool("romdb", true, "Apply the platform, quirks and speed listed for the ROM in the ROM database, unless -platform, -speed or -ipf is given")
+	romDatabasePath := flags.String("romdb-file", chip8.UserROMDatabasePath(), "ROM database file in the community chip-8-database programs.json format, whose entries take precedence over the built-in ones")
+	headless := flags.Bool("headless", false, "Run without output for -cycles or -frames, then write the display to -out")
+	cycles := flags.Int("cycles", 0, "Number of cycles to run in headless mode, rounded up to whole frames")
+	frames := flags.Int("frames", 0, "Number of 60Hz frames to run in headless mode")
+	keyScript := flags.String("keys", "", "Headless key input as FRAME=KEYS entries, e.g. '60=5,64=' holds key 5 for 4 frames, or @FILE")
+	outPath := flags.String("out", "", "Headless output file, .png, .pbm or .txt (default text to stdout)")
+	outFormat := flags.String("format", "", "Headless output format, png, pbm or txt (default from -out extension)")
+	scale := flags.Int("scale", 1, "Pixel scale of headless PNG, PBM and GIF output")
+	paletteList := flags.String("palette", "", "Colours of headless PNG and GIF output, 2 or 4 comma separated #RRGGBB colours for the background and planes (default greys)")
+	gifPath := flags.String("gif", "", "Headless: record every frame to an animated GIF")
+	wavPath := flags.String("wav", "", "Headless: render the buzzer, driven by the sound timer, to a WAV file")
+	waveformName := flags.String("waveform", sound.DefaultSettings.Waveform.String(), "Buzzer waveform of -wav output: "+strings.Join(sound.WaveformNames(), ", ")+". XO-CHIP programs play their audio pattern instead")
+	tone := flags.Float64("tone", sound.DefaultSettings.Frequency, "Buzzer frequency of -wav output in Hz")
+	volume := flags.Float64("volume", sound.DefaultSettings.Volume, "Buzzer volume of -wav output from 0 to 1")
+	recordPath := flags.String("record", "", "Headless: record key input to a movie file")
+	replayPath := flags.String("replay", "", "Headless: replay a movie file, for its length unless -cycles or -frames is given")
+	tracePath := flags.String("trace", "", "Write an execution trace, one record per instruction, to this file")
+	traceFormatName := flags.String("trace-format", chip8.TraceText.String(), "Trace format, text or binary")
+	traceRange := flags.String("trace-range", "", "Only trace instructions in this hex address range, e.g. 200-2FF")
+	traceOps := flags.String("trace-ops", "", "Only trace these opcode classes, by first hex digit, e.g. 8,D")
+	flags.Parse(args)
 
+	if *romPath == "" && flags.NArg() == 1 {
+		*romPath = flags.Arg(0)
+	}
 	if *romPath == "" {
 		fmt.Println("Please provide a ROM path using the -rom flag")
 		os.Exit(1)
 	}
 
+	seedSet := *headless
+	var platformSet, speedSet bool
+	flags.Visit(func(f *flag.Flag) {
+		seedSet = seedSet || f.Name == "seed"
+		platformSet = platformSet || f.Name == "platform"
+		speedSet = speedSet || f.Name == "speed" || f.Name == "ipf"
+	})
+
+	if *headless && (*cycles < 0 || *frames < 0 || *cycles > 0 && *frames > 0 || *cycles == 0 && *frames == 0 && *replayPath == "") {
+		fmt.Println("Headless mode needs a positive number of either -cycles or -frames")
+		os.Exit(1)
+	}
+	if !*headless && (*recordPath != "" || *replayPath != "") {
+		fmt.Println("Movies can only be recorded and replayed in headless mode")
+		os.Exit(1)
+	}
+	if *scale <= 0 {
+		fmt.Println("Scale must be a positive number")
+		os.Exit(1)
+	}
+	if !*headless && (*gifPath != "" || *wavPath != "") {
+		fmt.Println("GIFs and WAVs can only be recorded in headless mode")
+		os.Exit(1)
+	}
+	palette := capture.DefaultPalette
+	if *paletteList != "" {
+		var err error
+		if palette, err = capture.ParsePalette(*paletteList); err != nil {
+			fmt.Printf("Invalid palette: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	if *cycleMode != "step" && *cycleMode != "continuous" {
 		fmt.Println("Invalid mode. Use 'step' or 'continuous'")
 		os.Exit(1)
@@ -56,44 +262,155 @@ func parseCommandLineOptions() *options {
 		fmt.Println("Speed must be a positive number")
 		os.Exit(1)
 	}
+	if *instructionsPerFrame < 0 {
+		fmt.Println("Instructions per frame must be a positive number")
+		os.Exit(1)
+	}
+	if *instructionsPerFrame == 0 {
+		*instructionsPerFrame = max(1, (*cyclesPerSecond+chip8.FramesPerSecond/2)/chip8.FramesPerSecond)
+	}
 	if *displayRate <= 0 {
 		fmt.Println("Display rate must be a positive number")
 		os.Exit(1)
 	}
+	if *ui != "tui" && *ui != "plain" {
+		fmt.Println("Invalid UI. Use 'tui' or 'plain'")
+		os.Exit(1)
+	}
 
-	return &options{
-		romPath:         *romPath,
-		cycleMode:       *cycleMode,
-		cyclesPerSecond: *cyclesPerSecond,
-		displayRate:     min(*displayRate, *cyclesPerSecond), // display rate has no reason to ever be above cycle rate
+	platform, err := chip8.ParsePlatform(*platformName)
+	if err != nil {
+		fmt.Printf("Invalid platform. Use one of: %s\n", strings.Join(chip8.PlatformNames(), ", "))
+		os.Exit(1)
 	}
-}
 
-func runContinuousMode(emu *chip8.Emulator, cyclesPerSecond int, displayRate int) {
-	errCh := emu.Run(cyclesPerSecond)
+	waveform, err := sound.ParseWaveform(*waveformName)
+	if err != nil {
+		fmt.Printf("Invalid waveform. Use one of: %s\n", strings.Join(sound.WaveformNames(), ", "))
+		os.Exit(1)
+	}
+	soundSettings := sound.Settings{Waveform: waveform, Frequency: *tone, Volume: *volume}
+	if err := soundSettings.Validate(); err != nil {
+		fmt.Printf("Invalid sound settings: %v\n", err)
+		os.Exit(1)
+	}
 
-	displayRefreshClock := time.NewTicker(time.Second / time.Duration(displayRate))
+	timing, err := chip8.ParseTiming(*timingName)
+	if err != nil {
+		fmt.Println("Invalid timing. Use 'fixed' or 'vip'")
+		os.Exit(1)
+	}
 
-	go func() {
-		for range displayRefreshClock.C {
-			emu.Print()
-		}
-	}()
+	traceFormat, err := chip8.ParseTraceFormat(*traceFormatName)
+	if err != nil {
+		fmt.Println("Invalid trace format. Use 'text' or 'binary'")
+		os.Exit(1)
+	}
+	var traceFilter chip8.TraceFilter
+	traceFilter.Start, traceFilter.End, err = parseTraceRange(*traceRange)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	traceFilter.Classes, err = parseTraceClasses(*traceOps)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return &options{
+		romPath:              *romPath,
+		cycleMode:            *cycleMode,
+		cyclesPerSecond:      *cyclesPerSecond,
+		instructionsPerFrame: *instructionsPerFrame,
+		displayRate:          min(*displayRate, chip8.FramesPerSecond), // the display can only change once a frame
+		ui:                   *ui,
+		bell:                 *bell,
+		platform:             platform,
+		timing:               timing,
+		seed:                 *seed,
+		seedSet:              seedSet,
+		platformSet:          platformSet,
+		speedSet:             speedSet,
+
+		useROMDatabase:  *useROMDatabase,
+		romDatabasePath: *romDatabasePath,
+
+		headless:   *headless,
+		cycles:     *cycles,
+		frames:     *frames,
+		keyScript:  *keyScript,
+		recordPath: *recordPath,
+		replayPath: *replayPath,
+		outPath:    *outPath,
+		outFormat:  *outFormat,
+		scale:      *scale,
+		palette:    palette,
+		gifPath:    *gifPath,
+		wavPath:    *wavPath,
+		sound:      soundSettings,
+
+		tracePath:   *tracePath,
+		traceFormat: traceFormat,
+		traceFilter: traceFilter,
+	}
+}
+
+// runContinuousMode runs the emulator until it stops or is interrupted, in the
+// terminal UI if input and output are a terminal, otherwise printing the display
+func runContinuousMode(emu *chip8.Emulator, options *options) {
+	var err error
+	if options.ui == "tui" && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		err = runTerminalUI(emu, options.displayRate, options.bell)
+	} else {
+		err = runPlainMode(emu, options.displayRate)
+	}
 
-	if err := <-errCh; err != nil {
+	if errors.Is(err, chip8.ErrProgramExit) {
+		emu.Print()
+		fmt.Println("\nProgram exited")
+	} else if errors.Is(err, context.Canceled) {
+		fmt.Println("\nInterrupted")
+	} else if err != nil {
 		fmt.Printf("\nEmulation stopped with error: %v\n", err)
 	}
 }
 
-func runStepMode(emu *chip8.Emulator) {
+// runPlainMode prints the display and registers whenever the display changes,
+// for output which isn't a terminal
+func runPlainMode(emu *chip8.Emulator, displayRate int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	shown := emu.Snapshot() // display last printed
+	runner := emu.Run(ctx)
+
+	refresh := time.NewTicker(time.Second / time.Duration(displayRate))
+	defer refresh.Stop()
+
 	for {
-		fmt.Println("\nPress Enter to continue to next cycle...")
-		fmt.Scanln()
-		fmt.Printf("Executing opcode: %s\n", emu.GetCurrentOpcode(false))
-		if err := emu.Step(time.Second / 4); err != nil {
-			fmt.Printf("\nEmulation stopped with error: %v\n", err)
-			return
+		select {
+		case <-runner.Done():
+			return runner.Wait()
+		case <-refresh.C:
+			snapshot := runner.Snapshot()
+			if snapshot.Display != shown.Display || snapshot.HighRes != shown.HighRes {
+				snapshot.Print()
+				shown = snapshot
+			}
 		}
+	}
+}
+
+func runStepMode(emu *chip8.Emulator, cyclesPerSecond int) {
+	fmt.Println("\nDebugger ready, type 'help' for a list of commands")
+	dbg := debugger.New(emu, os.Stdout)
+	dbg.StepDuration = time.Second / time.Duration(cyclesPerSecond)
+
+	if err := dbg.Run(os.Stdin); errors.Is(err, chip8.ErrProgramExit) {
 		emu.Print()
+		fmt.Println("\nProgram exited")
+	} else if err != nil {
+		fmt.Printf("\nEmulation stopped with error: %v\n", err)
 	}
 }
@@ -0,0 +1,22 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+var errTerminalUnsupported = errors.New("terminal UI is not supported on this platform")
+
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+func makeRaw(f *os.File) (func() error, error) {
+	return nil, errTerminalUnsupported
+}
+
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errTerminalUnsupported
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), ioctlGetTermios)
+	return err == nil
+}
+
+// makeRaw puts the terminal into raw mode, so input is read a key at a time
+// without echo and Ctrl-C is read rather than raising SIGINT. Output
+// processing is kept, so "\n" still starts a new line. The returned function
+// restores the previous mode.
+func makeRaw(f *os.File) (func() error, error) {
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal mode: %w", err)
+	}
+	restore := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %w", err)
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(fd, ioctlSetTermios, &restore)
+	}, nil
+}
+
+// terminalSize returns the width and height of the terminal in cells
+func terminalSize(f *os.File) (int, int, error) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read terminal size: %w", err)
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// startTrace writes an execution trace to the trace file if one was given,
+// returning a function which finishes writing it
+func startTrace(emu *chip8.Emulator, options *options) (func(), error) {
+	if options.tracePath == "" {
+		return func() {}, nil
+	}
+
+	file, err := os.Create(options.tracePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace: %w", err)
+	}
+	tw := chip8.NewTraceWriter(file, options.traceFormat, emu.Config.Variant)
+	tw.Filter = options.traceFilter
+	emu.SetTracer(tw.Trace)
+
+	return func() {
+		emu.SetTracer(nil)
+		if err := tw.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing trace: %v\n", err)
+		}
+		file.Close()
+	}, nil
+}
+
+// parseTraceRange parses an inclusive address range such as 200-2FF
+func parseTraceRange(text string) (start, end uint16, err error) {
+	if text == "" {
+		return 0, 0, nil
+	}
+	startText, endText, found := strings.Cut(text, "-")
+	if !found {
+		endText = startText
+	}
+	startValue, startErr := parseHex(startText)
+	endValue, endErr := parseHex(endText)
+	if startErr != nil || endErr != nil || startValue > endValue {
+		return 0, 0, fmt.Errorf("invalid address range %q, expected START-END in hex", text)
+	}
+	return uint16(startValue), uint16(endValue), nil
+}
+
+// parseTraceClasses parses a list of opcode classes, given by their first
+// hex digit, e.g. "8,D" or "8D" for ALU and draw instructions
+func parseTraceClasses(text string) (uint16, error) {
+	var classes uint16
+	for _, c := range strings.ReplaceAll(text, ",", "") {
+		class, err := strconv.ParseUint(string(c), 16, 4)
+		if err != nil {
+			return 0, fmt.Errorf("invalid opcode class %q, expected hex digits", c)
+		}
+		classes |= 1 << class
+	}
+	return classes, nil
+}
+
+func parseHex(text string) (uint64, error) {
+	text = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(text)), "0x")
+	return strconv.ParseUint(text, 16, 16)
+}
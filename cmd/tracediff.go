@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/tracediff"
+)
+
+// runTraceDiff exits with status 0 if execution matched the reference, 1 if
+// it diverged and 2 on errors, like diff
+func runTraceDiff(args []string) {
+	flags := flag.NewFlagSet("tracediff", flag.ExitOnError)
+	platformName := flags.String("platform", chip8.DefaultPlatform.String(), "Platform preset to run the ROM under: "+strings.Join(chip8.PlatformNames(), ", "))
+	seed := flags.Int64("seed", 0, "Random number generator seed")
+	cyclesPerSecond := flags.Int("speed", 700, "Number of instructions per second, run in 60Hz frames unless -ipf is given, as in run")
+	instructionsPerFrame := flags.Int("ipf", 0, "Number of instructions per 60Hz frame (default -speed / 60)")
+	useROMDatabase := flags.Bool("romdb", true, "Apply the platform, quirks and speed listed for the ROM in the ROM database, unless -platform, -speed or -ipf is given, as in run")
+	romDatabasePath := flags.String("romdb-file", chip8.UserROMDatabasePath(), "ROM database file in the community chip-8-database programs.json format, whose entries take precedence over the built-in ones")
+	timingName := flags.String("timing", chip8.TimingFixed.String(), "Timing model: 'fixed' runs -ipf instructions per frame, 'vip' charges COSMAC VIP machine cycles per instruction and ignores -speed and -ipf")
+	formatName := flags.String("format", tracediff.FormatAuto.String(), "Reference trace format: auto, chip8 (from run -trace) or keyvalue (PC:0200 V0:00 ... lines)")
+	refPlatformName := flags.String("ref-platform", "", "Compare against the ROM running under this platform instead of a trace")
+	maxCycles := flags.Uint64("max-cycles", 10_000_000, "Stop comparing after this many cycles (0 for no limit)")
+	context := flags.Int("context", 8, "Number of matching instructions to show before a divergence")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8 tracediff [flags] ROM REFERENCE\n       chip8 tracediff [flags] -ref-platform PLATFORM ROM")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	var platformSet, speedSet bool
+	flags.Visit(func(f *flag.Flag) {
+		platformSet = platformSet || f.Name == "platform"
+		speedSet = speedSet || f.Name == "speed" || f.Name == "ipf"
+	})
+
+	if flags.NArg() != 2 && (flags.NArg() != 1 || *refPlatformName == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *cyclesPerSecond <= 0 {
+		fmt.Println("Speed must be a positive number")
+		os.Exit(2)
+	}
+	if *instructionsPerFrame < 0 {
+		fmt.Println("Instructions per frame must be a positive number")
+		os.Exit(2)
+	}
+	if *instructionsPerFrame == 0 {
+		*instructionsPerFrame = max(1, (*cyclesPerSecond+chip8.FramesPerSecond/2)/chip8.FramesPerSecond)
+	}
+	timing, err := chip8.ParseTiming(*timingName)
+	if err != nil {
+		fmt.Println("Invalid timing. Use 'fixed' or 'vip'")
+		os.Exit(2)
+	}
+
+	platform, err := chip8.ParsePlatform(*platformName)
+	if err != nil {
+		fmt.Printf("Invalid platform. Use one of: %s\n", strings.Join(chip8.PlatformNames(), ", "))
+		os.Exit(2)
+	}
+	format, err := tracediff.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Println("Invalid trace format. Use auto, chip8 or keyvalue")
+		os.Exit(2)
+	}
+
+	var db *chip8.ROMDatabase
+	if *useROMDatabase {
+		if db, err = chip8.LoadROMDatabase(*romDatabasePath); err != nil {
+			fmt.Printf("Error loading ROM database: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	// newEmulator loads the ROM as run does, with the settings of its ROM
+	// database entry unless the platform or speed are given
+	newEmulator := func(platform chip8.Platform, platformSet bool) *chip8.Emulator {
+		options := []chip8.EmulatorOption{
+			chip8.WithPlatform(platform),
+			chip8.WithSeed(*seed),
+			chip8.WithInstructionsPerFrame(*instructionsPerFrame),
+			chip8.WithTiming(timing),
+		}
+		if db != nil {
+			options = append(options, chip8.WithROMDatabase(db))
+		}
+		emu := chip8.New(options...)
+		if err := emu.LoadROMFromPath(flags.Arg(0)); err != nil {
+			fmt.Printf("Error loading ROM: %v\n", err)
+			os.Exit(2)
+		}
+		if platformSet {
+			emu.Config.SetPlatform(platform)
+		}
+		if speedSet {
+			emu.Config.InstructionsPerFrame = *instructionsPerFrame
+		}
+		return emu
+	}
+	emu := newEmulator(platform, platformSet)
+
+	var reference tracediff.Source
+	if *refPlatformName != "" {
+		refPlatform, err := chip8.ParsePlatform(*refPlatformName)
+		if err != nil {
+			fmt.Printf("Invalid reference platform. Use one of: %s\n", strings.Join(chip8.PlatformNames(), ", "))
+			os.Exit(2)
+		}
+		reference = &tracediff.EmulatorSource{Emulator: newEmulator(refPlatform, true), MaxCycles: *maxCycles}
+	} else {
+		file, err := os.Open(flags.Arg(1))
+		if err != nil {
+			fmt.Printf("Error opening reference trace: %v\n", err)
+			os.Exit(2)
+		}
+		defer file.Close()
+		if reference, err = tracediff.NewReader(file, format); err != nil {
+			fmt.Printf("Error reading reference trace: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	d, err := tracediff.Compare(emu, reference, tracediff.Options{
+		MaxCycles: *maxCycles,
+		Context:   *context,
+	})
+	if errors.Is(err, tracediff.ErrCycleLimit) {
+		fmt.Printf("No divergence in %d cycles\n", emu.Cycle())
+		return
+	}
+	if err != nil {
+		fmt.Printf("Comparison stopped with error: %v\n", err)
+		os.Exit(2)
+	}
+	if d == nil {
+		fmt.Printf("No divergence, %d cycles matched the reference\n", emu.Cycle())
+		return
+	}
+
+	if err := tracediff.WriteReport(os.Stdout, d, emu.Config.Variant); err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/disasm"
+)
+
+// Terminals only report key presses, repeated while the key is held, so a
+// key is released once no repeat has arrived for a while. The first repeat
+// comes after the keyboard's repeat delay, so a first press holds for longer.
+const (
+	tuiPressHold  = 500 * time.Millisecond
+	tuiRepeatHold = 100 * time.Millisecond
+
+	tuiPanelWidth  = 32 // columns of the side panel
+	tuiDisasmLines = 8  // instructions disassembled from PC
+)
+
+// tuiKeys maps the 1234/QWER/ASDF/ZXCV keyboard layout to the CHIP-8 keypad
+var tuiKeys = map[byte]byte{
+	'x': 0x0, '1': 0x1, '2': 0x2, '3': 0x3,
+	'q': 0x4, 'w': 0x5, 'e': 0x6, 'a': 0x7,
+	's': 0x8, 'd': 0x9, 'z': 0xA, 'c': 0xB,
+	'4': 0xC, 'r': 0xD, 'f': 0xE, 'v': 0xF,
+}
+
+// tuiArrows maps the final byte of the arrow key escape sequences to the
+// ROM database control names
+var tuiArrows = map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}
+
+// terminalUI is a full-screen terminal frontend, drawing the display with
+// half-block characters so each cell shows two rows of pixels
+type terminalUI struct {
+	emu    *chip8.Emulator
+	runner *chip8.Runner
+	out    *os.File
+	bell   bool // ring the terminal bell when the sound timer starts
+
+	controls map[string]byte // ROM database controls, bound to the arrow keys
+	release  [16]time.Time   // when each held CHIP-8 key is released, zero if not held
+	sounding bool            // sound timer was running at the last draw
+	size     [2]int          // terminal size at the last draw, to clear it on resize
+}
+
+// runTerminalUI runs the emulator in the terminal until the program exits,
+// fails, or the user quits with Ctrl-C
+func runTerminalUI(emu *chip8.Emulator, displayRate int, bell bool) error {
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// alternate screen buffer and hidden cursor, so the shell is left as it was
+	fmt.Print("\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	t := &terminalUI{emu: emu, out: os.Stdout, bell: bell}
+	if info, ok := emu.ROMInfo(); ok {
+		t.controls = info.Keys
+	}
+	t.runner = emu.Run(ctx)
+
+	input := make(chan []byte)
+	go readTerminalInput(os.Stdin, input)
+
+	refresh := time.NewTicker(time.Second / time.Duration(displayRate))
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-t.runner.Done():
+			return t.runner.Wait()
+		case data := <-input:
+			if !t.handleInput(data, time.Now()) {
+				stop()
+			}
+		case now := <-refresh.C:
+			t.releaseKeys(now)
+			t.draw()
+		}
+	}
+}
+
+// readTerminalInput sends each read from the terminal, until it fails
+func readTerminalInput(f *os.File, input chan<- []byte) {
+	buf := make([]byte, 64)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			return
+		}
+		input <- append([]byte(nil), buf[:n]...)
+	}
+}
+
+// handleInput presses the CHIP-8 keys for the bytes read from the terminal,
+// returning false if the user quit
+func (t *terminalUI) handleInput(data []byte, now time.Time) bool {
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b == 0x03 || b == 0x04: // Ctrl-C, Ctrl-D
+			return false
+		case b == ' ':
+			if t.runner.Paused() {
+				t.runner.Resume()
+			} else {
+				t.runner.Pause()
+			}
+		case b == 0x1b && i+2 < len(data) && data[i+1] == '[':
+			if key, ok := t.controls[tuiArrows[data[i+2]]]; ok {
+				t.press(key, now)
+			}
+			i += 2
+		default:
+			if b >= 'A' && b <= 'Z' {
+				b += 'a' - 'A'
+			}
+			if key, ok := tuiKeys[b]; ok {
+				t.press(key, now)
+			}
+		}
+	}
+	return true
+}
+
+// press presses a CHIP-8 key, or keeps it held if it already is
+func (t *terminalUI) press(key byte, now time.Time) {
+	if t.release[key].IsZero() {
+		t.emu.PressKey(key)
+		t.release[key] = now.Add(tuiPressHold)
+		return
+	}
+	if hold := now.Add(tuiRepeatHold); hold.After(t.release[key]) {
+		t.release[key] = hold
+	}
+}
+
+// releaseKeys releases the held keys which haven't repeated in time
+func (t *terminalUI) releaseKeys(now time.Time) {
+	for key, release := range t.release {
+		if !release.IsZero() && now.After(release) {
+			t.emu.ReleaseKey(byte(key))
+			t.release[key] = time.Time{}
+		}
+	}
+}
+
+// draw redraws the display and side panel in place
+func (t *terminalUI) draw() {
+	var (
+		s       chip8.Snapshot
+		code    []byte
+		variant chip8.Variant
+	)
+	t.runner.Do(func(e *chip8.Emulator) {
+		s = e.Snapshot()
+		variant = e.Config.Variant
+		end := min(int(e.PC)+2*tuiDisasmLines+2, e.MemorySize())
+		if int(e.PC) < end {
+			code = append(code, e.Memory[e.PC:end]...)
+		}
+	})
+
+	var frame strings.Builder
+	width, height, err := terminalSize(t.out)
+	if err == nil && [2]int{width, height} != t.size {
+		frame.WriteString("\x1b[2J")
+		t.size = [2]int{width, height}
+	}
+	if s.SoundTimer > 0 && !t.sounding && t.bell {
+		frame.WriteString("\a")
+	}
+	t.sounding = s.SoundTimer > 0
+
+	display := displayLines(&s)
+	displayWidth := len([]rune(display[0]))
+	var panel []string
+	if err != nil || width >= displayWidth+2+tuiPanelWidth {
+		panel = t.panelLines(&s, code, variant)
+	}
+
+	lines := make([]string, max(len(display), len(panel)))
+	for i := range lines {
+		if i < len(display) {
+			lines[i] = display[i]
+		} else {
+			lines[i] = strings.Repeat(" ", displayWidth)
+		}
+		if i < len(panel) {
+			lines[i] += "  " + panel[i]
+		}
+	}
+	if err == nil && len(lines) > height {
+		lines = lines[:height] // never scroll
+	}
+	if err == nil && width < displayWidth {
+		lines = []string{fmt.Sprintf("Terminal too small, the display needs %d columns", displayWidth)}
+	}
+
+	frame.WriteString("\x1b[H")
+	frame.WriteString(strings.Join(lines, "\x1b[K\n"))
+	frame.WriteString("\x1b[K\x1b[J")
+	t.out.WriteString(frame.String())
+}
+
+// displayLines draws the display in a box, two rows of pixels per line
+func displayLines(s *chip8.Snapshot) []string {
+	width, height := chip8.LowResWidth, chip8.LowResHeight
+	if s.HighRes {
+		width, height = chip8.HighResWidth, chip8.HighResHeight
+	}
+	on := func(x, y int) bool {
+		return s.Display[y*width+x] != 0
+	}
+
+	lines := make([]string, 0, height/2+2)
+	lines = append(lines, "┌"+strings.Repeat("─", width)+"┐")
+	for y := 0; y < height; y += 2 {
+		var line strings.Builder
+		line.WriteString("│")
+		for x := range width {
+			switch top, bottom := on(x, y), on(x, y+1); {
+			case top && bottom:
+				line.WriteString("█")
+			case top:
+				line.WriteString("▀")
+			case bottom:
+				line.WriteString("▄")
+			default:
+				line.WriteString(" ")
+			}
+		}
+		line.WriteString("│")
+		lines = append(lines, line.String())
+	}
+	lines = append(lines, "└"+strings.Repeat("─", width)+"┘")
+	return lines
+}
+
+// panelLines lists the registers, stack and disassembly from PC
+func (t *terminalUI) panelLines(s *chip8.Snapshot, code []byte, variant chip8.Variant) []string {
+	lines := []string{
+		fmt.Sprintf("PC %04X  I %04X  SP %d", s.PC, s.I, s.SP),
+	}
+	for row := range 4 {
+		var line strings.Builder
+		for i := row * 4; i < row*4+4; i++ {
+			fmt.Fprintf(&line, "V%X %02X  ", i, s.Registers[i])
+		}
+		lines = append(lines, strings.TrimSpace(line.String()))
+	}
+
+	timers := fmt.Sprintf("DT %02X  ST %02X", s.DelayTimer, s.SoundTimer)
+	if s.SoundTimer > 0 {
+		timers += "  \x1b[7m ♪ BEEP \x1b[0m"
+	}
+	lines = append(lines, timers)
+
+	stack := "Stack"
+	for i := range int(min(s.SP, chip8.StackSize)) {
+		stack += fmt.Sprintf(" %04X", s.Stack[i])
+	}
+	lines = append(lines, stack)
+	lines = append(lines, fmt.Sprintf("Frame %d  Cycle %d", s.Frame, s.Cycle))
+	if t.runner.Paused() {
+		lines = append(lines, "\x1b[7m PAUSED \x1b[0m")
+	} else {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "")
+	address := s.PC
+	offset := 0
+	for range tuiDisasmLines {
+		if offset+1 >= len(code) {
+			break
+		}
+		opcode := uint16(code[offset])<<8 | uint16(code[offset+1])
+		var long uint16
+		if offset+3 < len(code) {
+			long = uint16(code[offset+2])<<8 | uint16(code[offset+3])
+		}
+		marker := " "
+		if address == s.PC {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s %04X  %s", marker, address, chip8.Mnemonic(opcode, long, variant)))
+		size := disasm.InstructionSize(opcode, variant)
+		offset += size
+		address += uint16(size)
+	}
+
+	lines = append(lines,
+		"",
+		"Keypad 1234/QWER/ASDF/ZXCV",
+	)
+	if len(t.controls) > 0 {
+		lines = append(lines, "Arrows: ROM controls")
+	}
+	lines = append(lines, "Space pause, Ctrl-C quit")
+	return lines
+}
@@ -0,0 +1,113 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// comparisons maps condition operators to their test, longest operators first
+// so that "<=" is not read as "<"
+var comparisons = []struct {
+	op   string
+	test func(a, b int) bool
+}{
+	{"==", func(a, b int) bool { return a == b }},
+	{"!=", func(a, b int) bool { return a != b }},
+	{"<=", func(a, b int) bool { return a <= b }},
+	{">=", func(a, b int) bool { return a >= b }},
+	{"<", func(a, b int) bool { return a < b }},
+	{">", func(a, b int) bool { return a > b }},
+}
+
+// Condition is a comparison between registers or numbers, such as "V3 == 0x10".
+// Several comparisons may be joined with "&&", all of which must hold.
+type Condition struct {
+	source string
+	terms  []term
+}
+
+type term struct {
+	left, right operand
+	test        func(a, b int) bool
+}
+
+// operand is either a register name or a constant
+type operand struct {
+	register string
+	value    int
+}
+
+func (o operand) eval(e *chip8.Emulator) int {
+	if o.register == "" {
+		return o.value
+	}
+	// register names are validated when parsed
+	value, _ := lookup(e, o.register)
+	return value
+}
+
+// ParseCondition parses a breakpoint condition. Operands are V0-VF, I, PC, SP,
+// DT, ST or numbers, compared with ==, !=, <, <=, > or >=.
+func ParseCondition(s string) (*Condition, error) {
+	c := &Condition{source: strings.Join(strings.Fields(s), " ")}
+
+	for part := range strings.SplitSeq(s, "&&") {
+		t, err := parseTerm(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: %w", strings.TrimSpace(part), err)
+		}
+		c.terms = append(c.terms, t)
+	}
+	return c, nil
+}
+
+func parseTerm(s string) (term, error) {
+	for _, cmp := range comparisons {
+		left, right, found := strings.Cut(s, cmp.op)
+		if !found {
+			continue
+		}
+
+		l, err := parseOperand(left)
+		if err != nil {
+			return term{}, err
+		}
+		r, err := parseOperand(right)
+		if err != nil {
+			return term{}, err
+		}
+		return term{left: l, right: r, test: cmp.test}, nil
+	}
+	return term{}, fmt.Errorf("expected comparison using ==, !=, <, <=, > or >=")
+}
+
+func parseOperand(s string) (operand, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return operand{}, fmt.Errorf("missing operand")
+	}
+	if isRegister(s) {
+		return operand{register: strings.ToUpper(s)}, nil
+	}
+	value, err := parseNumber(s, 0xFFFF)
+	if err != nil {
+		return operand{}, fmt.Errorf("%q is not a register or number", s)
+	}
+	return operand{value: value}, nil
+}
+
+// Eval reports whether the condition holds for the emulator's current state
+func (c *Condition) Eval(e *chip8.Emulator) bool {
+	for _, t := range c.terms {
+		if !t.test(t.left.eval(e), t.right.eval(e)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Condition) String() string {
+	return c.source
+}
@@ -0,0 +1,572 @@
+// Package debugger implements an interactive command-line debugger for the
+// CHIP-8 emulator, with breakpoints, stepping and register/memory inspection.
+// It drives the emulator only through Emulator.Step, so any frontend that can
+// read lines of input and write text can host it.
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// DefaultStepDuration is the time each instruction takes for timer purposes,
+// matching the 700Hz default speed of the frontends
+const DefaultStepDuration = time.Second / 700
+
+// DefaultRunLimit is the maximum number of instructions run by a single
+// continue, next or finish command before control returns to the prompt
+const DefaultRunLimit = 10_000_000
+
+// errQuit is returned by Execute when the user asks to leave the debugger
+var errQuit = errors.New("quit")
+
+// Debugger controls an emulator from text commands.
+type Debugger struct {
+	emulator     *chip8.Emulator
+	out          io.Writer
+	breakpoints  []*Breakpoint
+	nextID       int
+	lastCommand  string
+	StepDuration time.Duration // time passed to Emulator.Step for each instruction
+	RunLimit     int           // maximum instructions per continue, next or finish
+}
+
+// Breakpoint stops execution before the instruction at Address runs,
+// if its condition (when set) holds.
+type Breakpoint struct {
+	ID        int
+	Address   uint16
+	Condition *Condition
+}
+
+func (b *Breakpoint) String() string {
+	if b.Condition != nil {
+		return fmt.Sprintf("#%d at 0x%04X if %s", b.ID, b.Address, b.Condition)
+	}
+	return fmt.Sprintf("#%d at 0x%04X", b.ID, b.Address)
+}
+
+// New creates a debugger for the emulator, writing output to out
+func New(emulator *chip8.Emulator, out io.Writer) *Debugger {
+	return &Debugger{
+		emulator:     emulator,
+		out:          out,
+		nextID:       1,
+		StepDuration: DefaultStepDuration,
+		RunLimit:     DefaultRunLimit,
+	}
+}
+
+// Run reads commands from in until it is exhausted, the user quits, or the
+// emulator stops with an error, which is returned. An empty line repeats the
+// previous command.
+func (d *Debugger) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	d.printLocation()
+
+	for {
+		fmt.Fprint(d.out, "(chip8) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(d.out)
+			return scanner.Err()
+		}
+
+		err := d.Execute(scanner.Text())
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		var emuErr *EmulatorError
+		if errors.As(err, &emuErr) {
+			return emuErr.Err
+		}
+		if err != nil {
+			fmt.Fprintf(d.out, "Error: %v\n", err)
+		}
+	}
+}
+
+// EmulatorError is returned by Execute when the emulator stopped with an
+// error while running a command, as opposed to the command being invalid.
+type EmulatorError struct {
+	Err error
+}
+
+func (e *EmulatorError) Error() string {
+	return fmt.Sprintf("emulation stopped: %v", e.Err)
+}
+
+func (e *EmulatorError) Unwrap() error {
+	return e.Err
+}
+
+// Execute runs a single debugger command
+func (d *Debugger) Execute(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		line = d.lastCommand
+		if line == "" {
+			return nil
+		}
+	}
+	d.lastCommand = line
+
+	fields := strings.Fields(line)
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "break", "b":
+		return d.cmdBreak(args)
+	case "delete", "d":
+		return d.cmdDelete(args)
+	case "watch", "w":
+		return d.cmdWatch(args)
+	case "unwatch", "u":
+		return d.cmdUnwatch(args)
+	case "breakpoints", "info":
+		d.cmdListBreakpoints()
+		return nil
+	case "continue", "c":
+		return d.cmdContinue()
+	case "step", "s":
+		return d.cmdStep(args)
+	case "next", "n":
+		return d.cmdNext()
+	case "finish", "f":
+		return d.cmdFinish()
+	case "print", "p":
+		return d.cmdPrint(args)
+	case "set":
+		return d.cmdSet(args)
+	case "display":
+		return d.emulator.Fprint(d.out)
+	case "help", "h", "?":
+		fmt.Fprint(d.out, helpText)
+		return nil
+	case "quit", "q", "exit":
+		return errQuit
+	}
+	return fmt.Errorf("unknown command %q, type 'help' for a list of commands", command)
+}
+
+const helpText = `Commands:
+  break ADDR [if COND]   set breakpoint, e.g. 'break 0x2A4 if V3 == 0x10' (b)
+  delete [ID]            delete breakpoint by number, or all breakpoints (d)
+  watch ADDR [KIND]      stop after an instruction accesses ADDR, KIND is read, write or rw (w)
+  unwatch ADDR           remove watchpoint (u)
+  breakpoints            list breakpoints and watchpoints (info)
+  continue               run until a breakpoint is hit (c)
+  step [N]               execute N instructions, default 1 (s)
+  next                   execute one instruction, running 2NNN calls to completion (n)
+  finish                 run until the current subroutine returns (f)
+  print WHAT             print regs, stack, V0-VF, I, PC, SP, DT, ST or 'mem ADDR [LEN]' (p)
+  set WHAT VALUE         set V0-VF, I, PC, DT, ST or 'mem ADDR VALUE'
+  display                print the display
+  quit                   leave the debugger (q)
+An empty line repeats the previous command.
+`
+
+func (d *Debugger) cmdBreak(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: break ADDR [if COND]")
+	}
+	address, err := parseNumber(args[0], 0xFFFF)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	bp := &Breakpoint{Address: uint16(address)}
+	if len(args) > 1 {
+		if args[1] != "if" || len(args) < 3 {
+			return fmt.Errorf("usage: break ADDR [if COND]")
+		}
+		if bp.Condition, err = ParseCondition(strings.Join(args[2:], " ")); err != nil {
+			return err
+		}
+	}
+
+	bp.ID = d.nextID
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, bp)
+	fmt.Fprintf(d.out, "Breakpoint %s\n", bp)
+	return nil
+}
+
+func (d *Debugger) cmdDelete(args []string) error {
+	if len(args) == 0 {
+		d.breakpoints = nil
+		fmt.Fprintln(d.out, "Deleted all breakpoints")
+		return nil
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return fmt.Errorf("invalid breakpoint number: %q", args[0])
+	}
+	for i, bp := range d.breakpoints {
+		if bp.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			fmt.Fprintf(d.out, "Deleted breakpoint #%d\n", id)
+			return nil
+		}
+	}
+	return fmt.Errorf("no breakpoint #%d", id)
+}
+
+func (d *Debugger) cmdListBreakpoints() {
+	watchpoints := d.emulator.Watchpoints()
+	if len(d.breakpoints) == 0 && len(watchpoints) == 0 {
+		fmt.Fprintln(d.out, "No breakpoints")
+		return
+	}
+	for _, bp := range d.breakpoints {
+		fmt.Fprintln(d.out, bp)
+	}
+	for _, address := range slices.Sorted(maps.Keys(watchpoints)) {
+		fmt.Fprintf(d.out, "Watch %s at 0x%04X\n", watchKindNames[watchpoints[address]], address)
+	}
+}
+
+// watchKindNames maps watchpoint kinds to the names used by the watch command
+var watchKindNames = map[chip8.WatchKind]string{
+	chip8.WatchRead:      "read",
+	chip8.WatchWrite:     "write",
+	chip8.WatchReadWrite: "rw",
+}
+
+func (d *Debugger) cmdWatch(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: watch ADDR [read|write|rw]")
+	}
+	address, err := parseNumber(args[0], d.emulator.MemorySize()-1)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	kind := chip8.WatchWrite
+	if len(args) == 2 {
+		found := false
+		for k, name := range watchKindNames {
+			if strings.EqualFold(args[1], name) {
+				kind, found = k, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid watchpoint kind %q, use read, write or rw", args[1])
+		}
+	}
+
+	d.emulator.Watch(uint16(address), kind)
+	fmt.Fprintf(d.out, "Watch %s at 0x%04X\n", watchKindNames[kind], address)
+	return nil
+}
+
+func (d *Debugger) cmdUnwatch(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: unwatch ADDR")
+	}
+	address, err := parseNumber(args[0], 0xFFFF)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	if _, ok := d.emulator.Watchpoints()[uint16(address)]; !ok {
+		return fmt.Errorf("no watchpoint at 0x%04X", address)
+	}
+	d.emulator.Unwatch(uint16(address))
+	return nil
+}
+
+func (d *Debugger) cmdContinue() error {
+	return d.runUntil(func() bool { return false })
+}
+
+func (d *Debugger) cmdStep(args []string) error {
+	count := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid step count: %q", args[0])
+		}
+		count = n
+	}
+
+	for range count {
+		watched, err := d.step()
+		if err != nil {
+			return err
+		}
+		if watched {
+			break
+		}
+	}
+	d.printLocation()
+	return nil
+}
+
+// cmdNext steps over subroutine calls, running them until they return
+func (d *Debugger) cmdNext() error {
+	e := d.emulator
+	if d.opcode()&0xF000 != 0x2000 {
+		return d.cmdStep(nil)
+	}
+
+	returnAddress := e.PC + 2
+	depth := e.SP
+	return d.runUntil(func() bool {
+		return e.PC == returnAddress && e.SP == depth
+	})
+}
+
+// cmdFinish runs until the current subroutine returns to its caller
+func (d *Debugger) cmdFinish() error {
+	e := d.emulator
+	if e.SP == 0 {
+		return fmt.Errorf("not in a subroutine")
+	}
+
+	depth := e.SP
+	return d.runUntil(func() bool {
+		return e.SP < depth
+	})
+}
+
+// runUntil executes instructions until done returns true, a breakpoint is hit,
+// or the run limit is reached. The instruction at the starting PC always runs,
+// so continuing from a breakpoint does not immediately stop again.
+func (d *Debugger) runUntil(done func() bool) error {
+	for i := range d.RunLimit {
+		if i > 0 {
+			if bp := d.breakpointHit(); bp != nil {
+				fmt.Fprintf(d.out, "Hit breakpoint %s\n", bp)
+				d.printLocation()
+				return nil
+			}
+		}
+		watched, err := d.step()
+		if err != nil {
+			return err
+		}
+		if watched || done() {
+			d.printLocation()
+			return nil
+		}
+	}
+	fmt.Fprintf(d.out, "Stopped after %d instructions\n", d.RunLimit)
+	d.printLocation()
+	return nil
+}
+
+// breakpointHit returns the first breakpoint that applies at the current PC
+func (d *Debugger) breakpointHit() *Breakpoint {
+	for _, bp := range d.breakpoints {
+		if bp.Address == d.emulator.PC && (bp.Condition == nil || bp.Condition.Eval(d.emulator)) {
+			return bp
+		}
+	}
+	return nil
+}
+
+// step executes one instruction, reporting whether it hit a watchpoint
+func (d *Debugger) step() (bool, error) {
+	err := d.emulator.Step(d.StepDuration)
+	var hit *chip8.WatchpointError
+	if errors.As(err, &hit) {
+		fmt.Fprintf(d.out, "Hit watchpoint: %s\n", hit.Access)
+		return true, nil
+	}
+	if err != nil {
+		return false, &EmulatorError{Err: err}
+	}
+	return false, nil
+}
+
+// opcode returns the instruction at the program counter
+func (d *Debugger) opcode() uint16 {
+	e := d.emulator
+	return uint16(e.Memory[e.PC])<<8 | uint16(e.Memory[e.PC+1])
+}
+
+// printLocation prints the address and description of the next instruction
+func (d *Debugger) printLocation() {
+	fmt.Fprintf(d.out, "0x%04X: %s\n", d.emulator.PC, d.emulator.GetCurrentOpcode(true))
+}
+
+func (d *Debugger) cmdPrint(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: print WHAT")
+	}
+	e := d.emulator
+
+	switch strings.ToLower(args[0]) {
+	case "regs", "registers":
+		for i, value := range e.Registers {
+			fmt.Fprintf(d.out, "V%X: 0x%02X", i, value)
+			if i%4 == 3 {
+				fmt.Fprintln(d.out)
+			} else {
+				fmt.Fprint(d.out, "  ")
+			}
+		}
+		fmt.Fprintf(d.out, "I: 0x%04X  PC: 0x%04X  SP: %d  DT: 0x%02X  ST: 0x%02X\n", e.I, e.PC, e.SP, e.DelayTimer, e.SoundTimer)
+		return nil
+	case "stack":
+		if e.SP == 0 {
+			fmt.Fprintln(d.out, "Empty")
+		}
+		for i := range e.SP {
+			fmt.Fprintf(d.out, "%2d: 0x%04X\n", i, e.Stack[i])
+		}
+		return nil
+	case "mem", "memory":
+		return d.printMemory(args[1:])
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("usage: print WHAT")
+	}
+	value, err := lookup(e, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(d.out, "%s = 0x%X (%d)\n", strings.ToUpper(args[0]), value, value)
+	return nil
+}
+
+// printMemory prints a hex dump of LEN bytes (default 16) from ADDR
+func (d *Debugger) printMemory(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: print mem ADDR [LEN]")
+	}
+	memorySize := d.emulator.MemorySize()
+	start, err := parseNumber(args[0], memorySize-1)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	length := 16
+	if len(args) == 2 {
+		if length, err = parseNumber(args[1], memorySize); err != nil {
+			return fmt.Errorf("invalid length: %w", err)
+		}
+	}
+	end := min(start+length, memorySize)
+
+	for row := start; row < end; row += 16 {
+		fmt.Fprintf(d.out, "0x%04X:", row)
+		for addr := row; addr < min(row+16, end); addr++ {
+			fmt.Fprintf(d.out, " %02X", d.emulator.Memory[addr])
+		}
+		fmt.Fprintln(d.out)
+	}
+	return nil
+}
+
+func (d *Debugger) cmdSet(args []string) error {
+	e := d.emulator
+	if len(args) == 3 && strings.EqualFold(args[0], "mem") {
+		address, err := parseNumber(args[1], e.MemorySize()-1)
+		if err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+		value, err := parseNumber(args[2], 0xFF)
+		if err != nil {
+			return fmt.Errorf("invalid value: %w", err)
+		}
+		e.Memory[address] = byte(value)
+		return nil
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: set WHAT VALUE")
+	}
+
+	name := strings.ToUpper(args[0])
+	limit := 0xFF
+	if name == "I" || name == "PC" {
+		limit = 0xFFFF
+	}
+	value, err := parseNumber(args[1], limit)
+	if err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+
+	switch {
+	case name == "I":
+		e.I = uint16(value)
+	case name == "PC":
+		e.PC = uint16(value)
+		d.printLocation()
+	case name == "DT":
+		e.DelayTimer = byte(value)
+	case name == "ST":
+		e.SoundTimer = byte(value)
+	default:
+		reg, ok := parseRegister(name)
+		if !ok {
+			return fmt.Errorf("cannot set %q", args[0])
+		}
+		e.Registers[reg] = byte(value)
+	}
+	return nil
+}
+
+// parseNumber parses a decimal, 0x hex or 0b binary number no greater than limit
+func parseNumber(s string, limit int) (int, error) {
+	value, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if value < 0 || value > int64(limit) {
+		return 0, fmt.Errorf("%s is out of range (max 0x%X)", s, limit)
+	}
+	return int(value), nil
+}
+
+// parseRegister parses a register name V0-VF
+func parseRegister(name string) (int, bool) {
+	name = strings.ToUpper(name)
+	if len(name) != 2 || name[0] != 'V' {
+		return 0, false
+	}
+	reg, err := strconv.ParseUint(name[1:], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return int(reg), true
+}
+
+// isRegister reports whether name is a register accepted by lookup
+func isRegister(name string) bool {
+	switch strings.ToUpper(name) {
+	case "I", "PC", "SP", "DT", "ST":
+		return true
+	}
+	_, ok := parseRegister(name)
+	return ok
+}
+
+// lookup returns the value of a named register of the emulator
+func lookup(e *chip8.Emulator, name string) (int, error) {
+	switch strings.ToUpper(name) {
+	case "I":
+		return int(e.I), nil
+	case "PC":
+		return int(e.PC), nil
+	case "SP":
+		return int(e.SP), nil
+	case "DT":
+		return int(e.DelayTimer), nil
+	case "ST":
+		return int(e.SoundTimer), nil
+	}
+	if reg, ok := parseRegister(name); ok {
+		return int(e.Registers[reg]), nil
+	}
+	return 0, fmt.Errorf("unknown register %q", name)
+}
@@ -0,0 +1,217 @@
+package debugger
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// Test program:
+//
+//	0x200: 6000  V0 = 0
+//	0x202: 2208  call 0x208
+//	0x204: 7001  V0 += 1
+//	0x206: 1202  jump 0x202
+//	0x208: 7110  V1 += 0x10
+//	0x20A: 00EE  return
+var testProgram = []byte{
+	0x60, 0x00,
+	0x22, 0x08,
+	0x70, 0x01,
+	0x12, 0x02,
+	0x71, 0x10,
+	0x00, 0xEE,
+}
+
+func newTestDebugger(t *testing.T) (*Debugger, *chip8.Emulator) {
+	t.Helper()
+	e := chip8.New()
+	if err := e.LoadROMFromData(testProgram); err != nil {
+		t.Fatalf("LoadROMFromData returned error: %v", err)
+	}
+	return New(e, io.Discard), e
+}
+
+func execute(t *testing.T, d *Debugger, commands ...string) {
+	t.Helper()
+	for _, command := range commands {
+		if err := d.Execute(command); err != nil {
+			t.Fatalf("%q returned error: %v", command, err)
+		}
+	}
+}
+
+func TestStepping(t *testing.T) {
+	t.Run("Step N", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "step 3")
+
+		if e.PC != 0x20A {
+			t.Errorf("PC should be 0x20A after 3 steps, got 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("Empty line repeats command", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "s", "", "")
+
+		if e.PC != 0x20A {
+			t.Errorf("PC should be 0x20A after 3 steps, got 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("Next steps over calls", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "step", "next")
+
+		if e.PC != 0x204 {
+			t.Errorf("PC should be 0x204 after stepping over call, got 0x%04X", e.PC)
+		}
+		if e.Registers[1] != 0x10 {
+			t.Errorf("Subroutine should have run, V1 is 0x%02X", e.Registers[1])
+		}
+	})
+
+	t.Run("Finish runs until return", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "step 2", "finish")
+
+		if e.PC != 0x204 || e.SP != 0 {
+			t.Errorf("Should return to 0x204 with empty stack, got PC 0x%04X SP %d", e.PC, e.SP)
+		}
+	})
+
+	t.Run("Finish outside subroutine", func(t *testing.T) {
+		d, _ := newTestDebugger(t)
+		if err := d.Execute("finish"); err == nil {
+			t.Errorf("finish should return error outside a subroutine")
+		}
+	})
+}
+
+func TestBreakpoints(t *testing.T) {
+	t.Run("Continue stops at breakpoint", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "break 0x208", "continue")
+
+		if e.PC != 0x208 {
+			t.Errorf("PC should stop at breakpoint 0x208, got 0x%04X", e.PC)
+		}
+
+		// continuing from a breakpoint runs the loop once more
+		execute(t, d, "continue")
+		if e.PC != 0x208 || e.Registers[0] != 1 {
+			t.Errorf("Should stop at 0x208 on second iteration, got PC 0x%04X V0 %d", e.PC, e.Registers[0])
+		}
+	})
+
+	t.Run("Conditional breakpoint", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "break 0x204 if V0 == 3 && V1 >= 0x30", "c")
+
+		if e.PC != 0x204 || e.Registers[0] != 3 {
+			t.Errorf("Should stop at 0x204 with V0 == 3, got PC 0x%04X V0 %d", e.PC, e.Registers[0])
+		}
+	})
+
+	t.Run("Delete breakpoint", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		d.RunLimit = 100
+		execute(t, d, "break 0x208", "delete 1", "continue")
+
+		if e.Registers[0] == 0 {
+			t.Errorf("Deleted breakpoint should not stop execution")
+		}
+		if err := d.Execute("delete 1"); err == nil {
+			t.Errorf("Deleting unknown breakpoint should return error")
+		}
+	})
+
+	t.Run("Watchpoint", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		e.Memory[0x20A] = 0xF1 // replace return with FX55 to store V0-V1
+		e.Memory[0x20B] = 0x55
+		e.I = 0x300
+		execute(t, d, "watch 0x301", "continue")
+
+		if e.PC != 0x20C || e.Memory[0x301] != 0x10 {
+			t.Errorf("Should stop after write to 0x301, got PC 0x%04X", e.PC)
+		}
+	})
+
+	t.Run("Invalid conditions", func(t *testing.T) {
+		d, _ := newTestDebugger(t)
+		for _, command := range []string{
+			"break 0x204 if V0",
+			"break 0x204 if VG == 1",
+			"break 0x204 if V0 == ",
+			"break 0x204 when V0 == 1",
+			"break 0x10000",
+		} {
+			if err := d.Execute(command); err == nil {
+				t.Errorf("%q should return error", command)
+			}
+		}
+	})
+}
+
+func TestInspection(t *testing.T) {
+	t.Run("Set registers and memory", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		execute(t, d, "set V3 0x42", "set I 0x300", "set pc 0x204", "set mem 0x300 0xFF", "set DT 10")
+
+		if e.Registers[3] != 0x42 || e.I != 0x300 || e.PC != 0x204 || e.Memory[0x300] != 0xFF || e.DelayTimer != 10 {
+			t.Errorf("Values not set, got V3 0x%02X I 0x%04X PC 0x%04X mem 0x%02X DT %d",
+				e.Registers[3], e.I, e.PC, e.Memory[0x300], e.DelayTimer)
+		}
+		if err := d.Execute("set V3 0x100"); err == nil {
+			t.Errorf("Setting register out of range should return error")
+		}
+	})
+
+	t.Run("Print", func(t *testing.T) {
+		d, _ := newTestDebugger(t)
+		var out strings.Builder
+		d.out = &out
+
+		execute(t, d, "set V3 0x42", "print V3", "print mem 0x200 4")
+
+		if !strings.Contains(out.String(), "V3 = 0x42") {
+			t.Errorf("print V3 output missing value: %q", out.String())
+		}
+		if !strings.Contains(out.String(), "0x0200: 60 00 22 08\n") {
+			t.Errorf("print mem output wrong: %q", out.String())
+		}
+	})
+
+	t.Run("Display is written to the output", func(t *testing.T) {
+		d, _ := newTestDebugger(t)
+		var out strings.Builder
+		d.out = &out
+
+		execute(t, d, "display")
+
+		lines := strings.Split(out.String(), "\n")
+		if len(lines) < chip8.LowResHeight || lines[0] != "|"+strings.Repeat(" ", 2*chip8.LowResWidth)+"|" {
+			t.Errorf("display output should start with the blank display, got %q", out.String())
+		}
+		if !strings.Contains(out.String(), "PC: 0x0200\n") {
+			t.Errorf("display output missing PC: %q", out.String())
+		}
+	})
+
+	t.Run("Emulator errors", func(t *testing.T) {
+		d, e := newTestDebugger(t)
+		e.Memory[0x200] = 0xFF
+		e.Memory[0x201] = 0xFF
+
+		err := d.Execute("step")
+		var emuErr *EmulatorError
+		if !errors.As(err, &emuErr) {
+			t.Errorf("Unknown opcode should return EmulatorError, got %v", err)
+		}
+	})
+}
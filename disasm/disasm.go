@@ -0,0 +1,281 @@
+// Package disasm disassembles CHIP-8 ROMs into Octo assembly. Code is
+// separated from data by following control flow from the entry point, so
+// the output can be reassembled into an identical ROM, by Octo or the asm
+// package.
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// Options controls how a ROM is disassembled
+type Options struct {
+	// Variant selects which instruction set extensions are decoded,
+	// opcodes outside it are treated as data
+	Variant chip8.Variant
+	// Raw prefixes each line with its address and the bytes it was
+	// decoded from. Raw output cannot be reassembled.
+	Raw bool
+}
+
+// labelKind is the reason an address is labelled, in order of precedence
+type labelKind int
+
+const (
+	labelNone labelKind = iota
+	labelData
+	labelSprite
+	labelJump
+	labelSubroutine
+	labelMain
+)
+
+var labelPrefixes = map[labelKind]string{
+	labelData:       "data",
+	labelSprite:     "sprite",
+	labelJump:       "label",
+	labelSubroutine: "sub",
+}
+
+// program holds the analysis of a ROM
+type program struct {
+	rom     []byte
+	variant chip8.Variant
+
+	code   []bool      // code[i] is set if an instruction starts at rom[i]
+	sprite []int       // bytes per sprite row for rom[i], 0 if not sprite data
+	labels []labelKind // label for rom[i]
+}
+
+// Disassemble writes Octo assembly for a ROM loaded at chip8.ProgramStartAddress to w
+func Disassemble(w io.Writer, rom []byte, opts Options) error {
+	p := &program{
+		rom:     rom,
+		variant: opts.Variant,
+		code:    make([]bool, len(rom)),
+		sprite:  make([]int, len(rom)),
+		labels:  make([]labelKind, len(rom)),
+	}
+	p.trace()
+
+	var out strings.Builder
+	p.write(&out, opts.Raw)
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// offset returns the ROM index of address, or false if it is outside the ROM
+func (p *program) offset(address uint16) (int, bool) {
+	i := int(address) - chip8.ProgramStartAddress
+	return i, i >= 0 && i < len(p.rom)
+}
+
+// word returns the big-endian 16 bit value at ROM index i, or false if it
+// runs past the end of the ROM
+func (p *program) word(i int) (uint16, bool) {
+	if i < 0 || i+1 >= len(p.rom) {
+		return 0, false
+	}
+	return uint16(p.rom[i])<<8 | uint16(p.rom[i+1]), true
+}
+
+// decode returns the size and Octo statement for the instruction at ROM index i,
+// or false if there is no valid instruction there
+func (p *program) decode(i int, addr func(uint16) string) (int, string, bool) {
+	opcode, ok := p.word(i)
+	if !ok {
+		return 0, "", false
+	}
+	size := InstructionSize(opcode, p.variant)
+	var long uint16
+	if size == 4 {
+		if long, ok = p.word(i + 2); !ok {
+			return 0, "", false
+		}
+	}
+	statement, ok := format(opcode, long, p.variant, addr)
+	return size, statement, ok
+}
+
+func (p *program) label(address uint16, kind labelKind) {
+	if i, ok := p.offset(address); ok {
+		p.labels[i] = max(p.labels[i], kind)
+	}
+}
+
+// trace follows every path of execution from the entry point, marking
+// instructions, branch targets and sprite data drawn by DXYN
+func (p *program) trace() {
+	if len(p.rom) == 0 {
+		return
+	}
+	p.labels[0] = labelMain
+	pending := []uint16{chip8.ProgramStartAddress}
+
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		// index register value on this path, if known
+		index, indexKnown := uint16(0), false
+
+	path:
+		for {
+			i, ok := p.offset(address)
+			if !ok || p.code[i] {
+				break
+			}
+			size, _, ok := p.decode(i, hexAddress)
+			if !ok {
+				break
+			}
+			p.code[i] = true
+
+			opcode, _ := p.word(i)
+			nnn := opcode & 0x0FFF
+			next := address + uint16(size)
+
+			switch {
+			case opcode == 0x00EE, opcode == 0x00FD && p.variant >= chip8.VariantSuperChip:
+				break path
+			case opcode&0xF000 == 0x1000:
+				p.label(nnn, labelJump)
+				pending = append(pending, nnn)
+				break path
+			case opcode&0xF000 == 0xB000:
+				// the real target depends on V0, the base is the best guess
+				p.label(nnn, labelJump)
+				pending = append(pending, nnn)
+				break path
+			case opcode&0xF000 == 0x2000:
+				p.label(nnn, labelSubroutine)
+				pending = append(pending, nnn)
+				indexKnown = false
+			case p.isSkip(opcode):
+				// both the next instruction and the one after it may run
+				if j, ok := p.offset(next); ok {
+					if nextOpcode, ok := p.word(j); ok {
+						pending = append(pending, next+uint16(InstructionSize(nextOpcode, p.variant)))
+					}
+				}
+			case opcode&0xF000 == 0xA000:
+				index, indexKnown = nnn, true
+				p.label(nnn, labelData)
+			case opcode == 0xF000:
+				index, indexKnown = uint16(p.rom[i+2])<<8|uint16(p.rom[i+3]), true
+				p.label(index, labelData)
+			case opcode&0xF000 == 0xD000:
+				if indexKnown {
+					p.markSprite(index, opcode)
+				}
+			case opcode&0xF0FF == 0xF01E, opcode&0xF0FF == 0xF029, opcode&0xF0FF == 0xF030,
+				opcode&0xF0FF == 0xF055, opcode&0xF0FF == 0xF065:
+				indexKnown = false
+			}
+			address = next
+		}
+	}
+}
+
+// isSkip reports whether opcode is a conditional skip
+func (p *program) isSkip(opcode uint16) bool {
+	switch opcode & 0xF000 {
+	case 0x3000, 0x4000:
+		return true
+	case 0x5000, 0x9000:
+		return opcode&0x000F == 0
+	case 0xE000:
+		return opcode&0x00FF == 0x9E || opcode&0x00FF == 0xA1
+	}
+	return false
+}
+
+// markSprite marks the bytes drawn by a DXYN at address as sprite data
+func (p *program) markSprite(address uint16, opcode uint16) {
+	bytesPerRow, rows := 1, int(opcode&0x000F)
+	if rows == 0 && p.variant >= chip8.VariantSuperChip {
+		bytesPerRow, rows = 2, chip8.BigSpriteWidth
+	}
+	p.label(address, labelSprite)
+
+	start, ok := p.offset(address)
+	if !ok {
+		return
+	}
+	for i := start; i < min(start+bytesPerRow*rows, len(p.rom)); i++ {
+		p.sprite[i] = bytesPerRow
+	}
+}
+
+// labelName returns the label for an address, or its number if unlabelled
+func (p *program) labelName(address uint16) string {
+	i, ok := p.offset(address)
+	if !ok || p.labels[i] == labelNone {
+		return hexAddress(address)
+	}
+	if p.labels[i] == labelMain {
+		return "main"
+	}
+	return fmt.Sprintf("%s_%03X", labelPrefixes[p.labels[i]], address)
+}
+
+// hasLabel reports whether any of the bytes rom[start:end] are labelled
+func (p *program) hasLabel(start, end int) bool {
+	return slices.ContainsFunc(p.labels[start:min(end, len(p.rom))], func(k labelKind) bool {
+		return k != labelNone
+	})
+}
+
+// write emits the listing. An instruction is only emitted when no label points
+// inside it, otherwise its bytes are emitted as data so labels stay exact.
+func (p *program) write(out *strings.Builder, raw bool) {
+	for i := 0; i < len(p.rom); {
+		address := uint16(i + chip8.ProgramStartAddress)
+		if p.labels[i] != labelNone {
+			fmt.Fprintf(out, ": %s\n", p.labelName(address))
+		}
+
+		if p.code[i] {
+			size, statement, ok := p.decode(i, p.labelName)
+			if ok && !p.hasLabel(i+1, i+size) {
+				p.writeLine(out, raw, address, p.rom[i:i+size], statement)
+				i += size
+				continue
+			}
+		}
+
+		// data runs to the next code, label or sprite row boundary
+		end := i + 1
+		limit := i + 8
+		if p.sprite[i] > 0 {
+			limit = i + p.sprite[i]
+		}
+		for end < min(limit, len(p.rom)) && !p.code[end] && p.labels[end] == labelNone && p.sprite[end] == p.sprite[i] {
+			end++
+		}
+
+		values := make([]string, 0, end-i)
+		for _, b := range p.rom[i:end] {
+			if p.sprite[i] > 0 {
+				values = append(values, fmt.Sprintf("0b%08b", b))
+			} else {
+				values = append(values, fmt.Sprintf("0x%02X", b))
+			}
+		}
+		p.writeLine(out, raw, address, p.rom[i:end], strings.Join(values, " "))
+		i = end
+	}
+}
+
+func (p *program) writeLine(out *strings.Builder, raw bool, address uint16, data []byte, statement string) {
+	if raw {
+		fmt.Fprintf(out, "%04X: %-16X %s\n", address, data, statement)
+	} else {
+		fmt.Fprintf(out, "\t%s\n", statement)
+	}
+}
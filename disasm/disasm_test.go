@@ -0,0 +1,179 @@
+package disasm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// Test program:
+//
+//	0x200: A20C  i := sprite
+//	0x202: D015  sprite v0 v1 5
+//	0x204: 2208  call 0x208
+//	0x206: 1206  jump 0x206
+//	0x208: 3001  skip if v0 == 1
+//	0x20A: 00EE  return
+//	0x20C: sprite data (5 bytes)
+//	0x211: 0xFF  trailing data
+var testProgram = []byte{
+	0xA2, 0x0C,
+	0xD0, 0x15,
+	0x22, 0x08,
+	0x12, 0x06,
+	0x30, 0x01,
+	0x00, 0xEE,
+	0xF0, 0x90, 0x90, 0x90, 0xF0,
+	0xFF,
+}
+
+func disassemble(t *testing.T, rom []byte, opts Options) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Disassemble(&out, rom, opts); err != nil {
+		t.Fatalf("Disassemble returned error: %v", err)
+	}
+	return out.String()
+}
+
+func TestDisassemble(t *testing.T) {
+	t.Run("Octo output", func(t *testing.T) {
+		out := disassemble(t, testProgram, Options{Variant: chip8.VariantChip8})
+		expected := `: main
+	i := sprite_20C
+	sprite v0 v1 5
+	:call sub_208
+: label_206
+	jump label_206
+: sub_208
+	if v0 != 0x01 then
+	return
+: sprite_20C
+	0b11110000
+	0b10010000
+	0b10010000
+	0b10010000
+	0b11110000
+	0xFF
+`
+		if out != expected {
+			t.Errorf("Unexpected disassembly:\n%s\nexpected:\n%s", out, expected)
+		}
+	})
+
+	t.Run("Raw output covers every byte", func(t *testing.T) {
+		for _, variant := range []chip8.Variant{chip8.VariantChip8, chip8.VariantSuperChip, chip8.VariantXOChip} {
+			// jump into the middle of an instruction, and an F000 long load
+			rom := append([]byte{0x12, 0x03, 0x60, 0x12, 0xEE, 0xF0, 0x00, 0x02, 0x00}, testProgram...)
+			out := disassemble(t, rom, Options{Variant: variant, Raw: true})
+
+			var listed []byte
+			for line := range strings.Lines(out) {
+				if strings.HasPrefix(line, ":") {
+					continue
+				}
+				data, err := hex.DecodeString(strings.Fields(line)[1])
+				if err != nil {
+					t.Fatalf("Invalid raw line %q: %v", line, err)
+				}
+				listed = append(listed, data...)
+			}
+			if !bytes.Equal(listed, rom) {
+				t.Errorf("%v: raw listing bytes % X differ from ROM % X", variant, listed, rom)
+			}
+		}
+	})
+
+	t.Run("Labels are defined", func(t *testing.T) {
+		rom := append([]byte{0x12, 0x03, 0x60, 0x12, 0xEE}, testProgram...)
+		out := disassemble(t, rom, Options{Variant: chip8.VariantXOChip})
+
+		for _, name := range regexp.MustCompile(`(?:sub|label|sprite|data)_[0-9A-F]+`).FindAllString(out, -1) {
+			if !strings.Contains(out, ": "+name+"\n") {
+				t.Errorf("Label %s is used but not defined:\n%s", name, out)
+			}
+		}
+	})
+}
+
+// Reassembly is only byte-identical if no two opcodes share a statement
+func TestMnemonicsUnique(t *testing.T) {
+	for _, variant := range []chip8.Variant{chip8.VariantChip8, chip8.VariantSuperChip, chip8.VariantXOChip} {
+		seen := make(map[string]uint16)
+		for opcode := range 0x10000 {
+			statement, ok := format(uint16(opcode), 0, variant, hexAddress)
+			if !ok {
+				continue
+			}
+			if other, found := seen[statement]; found {
+				t.Errorf("%v: 0x%04X and 0x%04X both disassemble to %q", variant, other, opcode, statement)
+			}
+			seen[statement] = uint16(opcode)
+		}
+	}
+}
+
+func TestReassemble(t *testing.T) {
+	// code, a subroutine, a data table, sprites of both sizes and trailing data
+	rom := asm.MustAssemble(`
+		ld   i, table
+		ld   v3, [i]
+		ld   i, sprite
+		drw  v0, v1, 5
+		ld   i, big
+		drw  v0, v1, 0
+		call sub
+		se   v0, 4
+		jp   v0, table
+		ld   i, long far
+	end:
+		jp   end
+	sub:
+		ld   v0, dt
+		sknp v0
+		shr  v0, v1
+		ret
+	table:
+		:byte 0x12 0x34 0x56 0x78
+	sprite:
+		:byte 0xF0 0x90 0x90 0x90 0xF0
+	big:
+		:byte 0xFF 0xFF 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01
+		:byte 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0x80 0x01 0xFF 0xFF
+	far:
+		:byte 0x00 0xE0 0x12
+	`)
+	roms := map[string][]byte{"Test program": testProgram, "Code, data and sprites": rom}
+
+	// every opcode, each in a ROM of its own as jumps end the traced code
+	for _, variant := range []chip8.Variant{chip8.VariantChip8, chip8.VariantSuperChip, chip8.VariantXOChip} {
+		t.Run(variant.String(), func(t *testing.T) {
+			for name, rom := range roms {
+				reassemble(t, name, rom, variant)
+			}
+			for opcode := range 0x10000 {
+				reassemble(t, fmt.Sprintf("0x%04X", opcode), []byte{byte(opcode >> 8), byte(opcode), 0x02, 0x04}, variant)
+			}
+		})
+	}
+}
+
+// reassemble checks the disassembly of rom assembles back to the same bytes
+func reassemble(t *testing.T, name string, rom []byte, variant chip8.Variant) {
+	t.Helper()
+	source := disassemble(t, rom, Options{Variant: variant})
+	program, err := asm.Assemble(source)
+	if err != nil {
+		t.Errorf("%s: reassembly returned error: %v\n%s", name, err, source)
+		return
+	}
+	if !bytes.Equal(program.ROM, rom) {
+		t.Errorf("%s: reassembled to % X, expected % X\n%s", name, program.ROM, rom, source)
+	}
+}
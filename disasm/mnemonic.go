@@ -0,0 +1,144 @@
+package disasm
+
+import (
+	"fmt"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// InstructionSize returns the size in bytes of the instruction starting with
+// opcode: 4 for the XO-CHIP F000 NNNN long load, otherwise 2
+func InstructionSize(opcode uint16, variant chip8.Variant) int {
+	if opcode == 0xF000 && variant >= chip8.VariantXOChip {
+		return 4
+	}
+	return 2
+}
+
+// format returns the Octo statement for an instruction, or false if the opcode
+// is not a valid instruction for the variant. long is the address following an
+// F000 opcode, and addr formats address operands, e.g. as labels.
+func format(opcode uint16, long uint16, variant chip8.Variant, addr func(uint16) string) (string, bool) {
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+	nn := opcode & 0x00FF
+	nnn := opcode & 0x0FFF
+
+	superChip := variant >= chip8.VariantSuperChip
+	xoChip := variant >= chip8.VariantXOChip
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opcode == 0x00E0:
+			return "clear", true
+		case opcode == 0x00EE:
+			return "return", true
+		case opcode&0xFFF0 == 0x00C0 && superChip:
+			return fmt.Sprintf("scroll-down %d", n), true
+		case opcode&0xFFF0 == 0x00D0 && xoChip:
+			return fmt.Sprintf("scroll-up %d", n), true
+		case opcode == 0x00FB && superChip:
+			return "scroll-right", true
+		case opcode == 0x00FC && superChip:
+			return "scroll-left", true
+		case opcode == 0x00FD && superChip:
+			return "exit", true
+		case opcode == 0x00FE && superChip:
+			return "lores", true
+		case opcode == 0x00FF && superChip:
+			return "hires", true
+		}
+	case 0x1000:
+		return "jump " + addr(nnn), true
+	case 0x2000:
+		return ":call " + addr(nnn), true
+	case 0x3000:
+		// skip if equal executes the next statement only if not equal
+		return fmt.Sprintf("if v%x != 0x%02X then", x, nn), true
+	case 0x4000:
+		return fmt.Sprintf("if v%x == 0x%02X then", x, nn), true
+	case 0x5000:
+		switch {
+		case n == 0:
+			return fmt.Sprintf("if v%x != v%x then", x, y), true
+		case n == 2 && xoChip:
+			return fmt.Sprintf("save v%x - v%x", x, y), true
+		case n == 3 && xoChip:
+			return fmt.Sprintf("load v%x - v%x", x, y), true
+		}
+	case 0x6000:
+		return fmt.Sprintf("v%x := 0x%02X", x, nn), true
+	case 0x7000:
+		return fmt.Sprintf("v%x += 0x%02X", x, nn), true
+	case 0x8000:
+		operators := map[uint16]string{
+			0x0: ":=", 0x1: "|=", 0x2: "&=", 0x3: "^=", 0x4: "+=",
+			0x5: "-=", 0x6: ">>=", 0x7: "=-", 0xE: "<<=",
+		}
+		if op, ok := operators[n]; ok {
+			return fmt.Sprintf("v%x %s v%x", x, op, y), true
+		}
+	case 0x9000:
+		if n == 0 {
+			return fmt.Sprintf("if v%x == v%x then", x, y), true
+		}
+	case 0xA000:
+		return "i := " + addr(nnn), true
+	case 0xB000:
+		return "jump0 " + addr(nnn), true
+	case 0xC000:
+		return fmt.Sprintf("v%x := random 0x%02X", x, nn), true
+	case 0xD000:
+		return fmt.Sprintf("sprite v%x v%x %d", x, y, n), true
+	case 0xE000:
+		switch nn {
+		case 0x9E:
+			return fmt.Sprintf("if v%x -key then", x), true
+		case 0xA1:
+			return fmt.Sprintf("if v%x key then", x), true
+		}
+	case 0xF000:
+		switch {
+		case opcode == 0xF000 && xoChip:
+			return "i := long " + addr(long), true
+		case nn == 0x01 && x <= 3 && xoChip:
+			return fmt.Sprintf("plane %d", x), true
+		case opcode == 0xF002 && xoChip:
+			return "audio", true
+		case nn == 0x07:
+			return fmt.Sprintf("v%x := delay", x), true
+		case nn == 0x0A:
+			return fmt.Sprintf("v%x := key", x), true
+		case nn == 0x15:
+			return fmt.Sprintf("delay := v%x", x), true
+		case nn == 0x18:
+			return fmt.Sprintf("buzzer := v%x", x), true
+		case nn == 0x1E:
+			return fmt.Sprintf("i += v%x", x), true
+		case nn == 0x29:
+			return fmt.Sprintf("i := hex v%x", x), true
+		case nn == 0x30 && superChip:
+			return fmt.Sprintf("i := bighex v%x", x), true
+		case nn == 0x33:
+			return fmt.Sprintf("bcd v%x", x), true
+		case nn == 0x3A && xoChip:
+			return fmt.Sprintf("pitch := v%x", x), true
+		case nn == 0x55:
+			return fmt.Sprintf("save v%x", x), true
+		case nn == 0x65:
+			return fmt.Sprintf("load v%x", x), true
+		case nn == 0x75 && superChip:
+			return fmt.Sprintf("saveflags v%x", x), true
+		case nn == 0x85 && superChip:
+			return fmt.Sprintf("loadflags v%x", x), true
+		}
+	}
+	return "", false
+}
+
+// hexAddress formats an address operand as a number
+func hexAddress(address uint16) string {
+	return fmt.Sprintf("0x%03X", address)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/capture"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// handleCaptureInput handles the screenshot and GIF recording keys, unless
+// they are bound to CHIP-8 keys
+func (g *Game) handleCaptureInput() {
+	if g.remap != nil {
+		return
+	}
+	if !g.keyBindings.bound(screenshotKey) && inpututil.IsKeyJustPressed(screenshotKey) {
+		if path, err := g.saveScreenshot(); err != nil {
+			fmt.Printf("Failed to save screenshot: %v\n", err)
+		} else {
+			fmt.Printf("Saved screenshot to %s\n", path)
+		}
+	}
+	if !g.keyBindings.bound(gifKey) && inpututil.IsKeyJustPressed(gifKey) {
+		if g.gifRecorder == nil {
+			g.gifRecorder = capture.NewRecorder()
+			fmt.Println("Recording GIF")
+		} else if err := g.saveGIF(); err != nil {
+			fmt.Printf("Failed to save GIF: %v\n", err)
+		}
+	}
+}
+
+// recordGIFFrame adds the display to the GIF being recorded, if any
+func (g *Game) recordGIFFrame() {
+	if g.gifRecorder != nil {
+		g.gifRecorder.AddFrame(g.emulator.DisplayImage())
+	}
+}
+
+// saveScreenshot writes the display as a PNG in the current palette, without
+// deflicker or CRT effects, and returns its path
+func (g *Game) saveScreenshot() (string, error) {
+	var buf bytes.Buffer
+	if err := capture.WritePNG(&buf, g.emulator.DisplayImage(), g.captureScale, g.pixelColors); err != nil {
+		return "", err
+	}
+	return g.writeCapture(".png", buf.Bytes())
+}
+
+// saveGIF stops recording and writes the GIF in the current palette, if recording
+func (g *Game) saveGIF() error {
+	recorder := g.gifRecorder
+	if recorder == nil {
+		return nil
+	}
+	g.gifRecorder = nil
+
+	var buf bytes.Buffer
+	if err := recorder.WriteGIF(&buf, g.captureScale, g.pixelColors); err != nil {
+		return err
+	}
+	path, err := g.writeCapture(".gif", buf.Bytes())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d frames to %s\n", recorder.Frames(), path)
+	return nil
+}
+
+// writeCapture writes a capture to the capture directory, named after the
+// ROM and the time, and returns its path
+func (g *Game) writeCapture(ext string, data []byte) (string, error) {
+	name := strings.TrimSuffix(g.romName, filepath.Ext(g.romName))
+	if name == "" {
+		name = "chip8"
+	}
+	path := filepath.Join(g.captureDir, name+"-"+time.Now().Format("20060102-150405.000")+ext)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
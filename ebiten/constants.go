@@ -9,7 +9,7 @@ import (
 // Display constants
 const (
 	// CHIP-8 display constants
-	chip8PixelSize     = 8  // Size of each CHIP-8 pixel in screen pixels
+	chip8PixelSize     = 8  // Size of each low resolution CHIP-8 pixel in screen pixels
 	chip8DisplayWidth  = 64 // CHIP-8 display width in pixels
 	chip8DisplayHeight = 32 // CHIP-8 display height in pixels
 
@@ -28,13 +28,17 @@ const (
 	topRowSpacing       = 120 // X Spacing for elements displayed above memory (PC, I, delaytimer, soundtimer)
 )
 
+// Save state constants
+const (
+	saveSlotCount = 4 // Number of quick-save slots
+)
+
 // Sound constants
 const (
-	sampleRate = 48000
-	frequency  = 440 // 440Hz = A4 Note
+	volumeStep = 0.1 // Volume change of each press of the volume keys
 )
 
-// Input key mapping
+// Default input key mapping, which a key config can override, see keymap.go
 var keyArray = [16]ebiten.Key{
 	ebiten.KeyX, // 0x0
 	ebiten.Key1, // 0x1
@@ -60,6 +64,48 @@ var stepKeys = []ebiten.Key{
 	ebiten.KeyArrowRight,
 }
 
+// Keybinds which rewind, held in run mode or pressed to step back a cycle in step mode
+var rewindKeys = []ebiten.Key{
+	ebiten.KeyBackspace,
+	ebiten.KeyArrowLeft,
+}
+
+// Keybind which opens the key remapping screen, Shift+key remaps for every ROM
+const remapKey = ebiten.KeyF5
+
+// Keybind which cycles through the display palettes, see palette.go
+const paletteKey = ebiten.KeyF6
+
+// Keybind which cycles through the deflicker modes, see phosphor.go
+const deflickerKey = ebiten.KeyF7
+
+// Keybind which cycles through the CRT shader presets, see crt.go
+const crtKey = ebiten.KeyF8
+
+// Keybind which saves a PNG screenshot of the chip-8 display, see capture.go
+const screenshotKey = ebiten.KeyF9
+
+// Keybind which starts and stops recording the chip-8 display to a GIF
+const gifKey = ebiten.KeyF10
+
+// Keybinds which mute and unmute the buzzer and change its volume, see sound.go
+const (
+	muteKey       = ebiten.KeyM
+	volumeDownKey = ebiten.KeyMinus
+	volumeUpKey   = ebiten.KeyEqual
+)
+
+// Keybind which toggles fullscreen, showing only the chip-8 display
+const fullscreenKey = ebiten.KeyF11
+
+// Keybinds for quick-save slots, Shift+key saves and key loads
+var saveSlotKeys = [saveSlotCount]ebiten.Key{
+	ebiten.KeyF1,
+	ebiten.KeyF2,
+	ebiten.KeyF3,
+	ebiten.KeyF4,
+}
+
 // Colours
 var colorBackground = color.RGBA{
 	51, 51, 51, 255,
@@ -70,11 +116,6 @@ var colorPrimary = color.RGBA{
 	245, 245, 245, 255,
 }
 
-// colorAccent is the colour of "on" pixels in chip-8 display
-var colorAccent = color.RGBA{
-	0, 255, 0, 255,
-}
-
 // colour used to highlight current opcode in memory view
 var colorMemHighlight = color.RGBA{
 	100, 100, 200, 155,
@@ -0,0 +1,120 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+//go:embed crt.kage
+var crtShaderSource []byte
+
+// crtSettings are the intensities of the CRT shader's effects, each from 0 to 1
+type crtSettings struct {
+	Scanlines float32
+	Bloom     float32
+	Curvature float32
+	Vignette  float32
+}
+
+// enabled reports whether any effect is on, otherwise the shader is skipped
+func (s crtSettings) enabled() bool {
+	return s != crtSettings{}
+}
+
+// crtPreset is a named set of CRT effect intensities
+type crtPreset struct {
+	name     string
+	settings crtSettings
+}
+
+// crtPresets are the selectable CRT presets, the first is the default
+var crtPresets = []crtPreset{
+	{"off", crtSettings{}},
+	{"scanlines", crtSettings{Scanlines: 0.5}},
+	{"crt", crtSettings{Scanlines: 0.35, Bloom: 0.3, Curvature: 0.15, Vignette: 0.3}},
+	{"arcade", crtSettings{Scanlines: 0.6, Bloom: 0.6, Curvature: 0.3, Vignette: 0.5}},
+}
+
+// crtPresetNames returns the names of the CRT presets, for use in help text
+func crtPresetNames() []string {
+	names := make([]string, len(crtPresets))
+	for i, p := range crtPresets {
+		names[i] = p.name
+	}
+	return names
+}
+
+// parseCRTPreset returns the index of the CRT preset with the given name
+func parseCRTPreset(name string) (int, error) {
+	i := slices.IndexFunc(crtPresets, func(p crtPreset) bool { return p.name == name })
+	if i < 0 {
+		return 0, fmt.Errorf("unknown CRT preset: %q, use one of %s", name, strings.Join(crtPresetNames(), ", "))
+	}
+	return i, nil
+}
+
+// setCRT changes the CRT effect intensities, compiling the shader the first
+// time any is enabled
+func (g *Game) setCRT(settings crtSettings) error {
+	if settings.enabled() && g.crtShader == nil {
+		shader, err := ebiten.NewShader(crtShaderSource)
+		if err != nil {
+			return fmt.Errorf("failed to compile CRT shader: %w", err)
+		}
+		g.crtShader = shader
+	}
+	g.crt = settings
+	return nil
+}
+
+// setCRTPreset applies the CRT preset with the given index
+func (g *Game) setCRTPreset(i int) error {
+	if err := g.setCRT(crtPresets[i].settings); err != nil {
+		return err
+	}
+	g.crtPreset = i
+	return nil
+}
+
+// nextCRTPreset cycles to the next CRT preset
+func (g *Game) nextCRTPreset() {
+	next := (g.crtPreset + 1) % len(crtPresets)
+	if err := g.setCRTPreset(next); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("CRT: %s\n", crtPresets[next].name)
+}
+
+// drawCRT draws the display image over the given area through the CRT shader.
+// The display is first scaled into an image of the area's size, which the
+// shader samples.
+func (g *Game) drawCRT(dst *ebiten.Image, display *ebiten.Image, x0, y0, width, height float32) {
+	w, h := max(1, int(width)), max(1, int(height))
+	if g.crtImage == nil || g.crtImage.Bounds().Dx() != w || g.crtImage.Bounds().Dy() != h {
+		if g.crtImage != nil {
+			g.crtImage.Deallocate()
+		}
+		g.crtImage = ebiten.NewImage(w, h)
+	}
+
+	scaled := &ebiten.DrawImageOptions{}
+	scaled.GeoM.Scale(float64(w)/float64(display.Bounds().Dx()), float64(h)/float64(display.Bounds().Dy()))
+	g.crtImage.DrawImage(display, scaled)
+
+	options := &ebiten.DrawRectShaderOptions{}
+	options.GeoM.Translate(float64(x0), float64(y0))
+	options.Images[0] = g.crtImage
+	options.Uniforms = map[string]any{
+		"Scanlines": g.crt.Scanlines,
+		"Bloom":     g.crt.Bloom,
+		"Curvature": g.crt.Curvature,
+		"Vignette":  g.crt.Vignette,
+		"Rows":      float32(display.Bounds().Dy()),
+	}
+	dst.DrawRectShader(w, h, g.crtShader, options)
+}
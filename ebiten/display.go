@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"image"
 
+	"github.com/bdeatock/chip8-emulator/chip8"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
@@ -24,22 +26,40 @@ func (g *Game) drawChip8Display(screen *ebiten.Image) {
 	// Right
 	vector.DrawFilledRect(screen, displayWidth+marginX, marginY, borderWidth, displayHeight, borderColor, false)
 
-	// Draw emulator display (pixel grid), "true" pixels are displayed
-	for x := range chip8DisplayWidth {
-		for y := range chip8DisplayHeight {
-			if g.emulator.Display[y*chip8DisplayWidth+x] {
-				vector.DrawFilledRect(
-					screen,
-					float32(x*chip8PixelSize)+marginX,
-					float32(y*chip8PixelSize)+marginY,
-					float32(chip8PixelSize),
-					float32(chip8PixelSize),
-					colorAccent,
-					false,
-				)
-			}
+	g.drawChip8Pixels(screen, marginX, marginY, displayWidth, displayHeight)
+}
+
+// drawChip8Pixels draws the emulator display over the given area, pixels with
+// any plane set are drawn in the palette's colour for them, along with those
+// the deflicker mode keeps lit. Pixels shrink in high resolution mode so the
+// display area stays the same size. The display is rendered into an image a
+// pixel per display pixel, which is scaled up, or passed through the CRT
+// shader when it is enabled.
+func (g *Game) drawChip8Pixels(screen *ebiten.Image, x0, y0, areaWidth, areaHeight float32) {
+	width := g.emulator.DisplayWidth()
+	height := g.emulator.DisplayHeight()
+	if g.displayImage == nil {
+		g.displayImage = ebiten.NewImage(chip8.HighResWidth, chip8.HighResHeight)
+	}
+	pixels := g.displayPixels[:width*height*4]
+	for i := range width * height {
+		c, ok := g.phosphor.pixel(&g.emulator.Display, i, &g.pixelColors)
+		if !ok {
+			c = g.pixelColors[0]
 		}
+		pixels[i*4], pixels[i*4+1], pixels[i*4+2], pixels[i*4+3] = c.R, c.G, c.B, 255
 	}
+	display := g.displayImage.SubImage(image.Rect(0, 0, width, height)).(*ebiten.Image)
+	display.WritePixels(pixels)
+
+	if g.crt.enabled() {
+		g.drawCRT(screen, display, x0, y0, areaWidth, areaHeight)
+		return
+	}
+	options := &ebiten.DrawImageOptions{}
+	options.GeoM.Scale(float64(areaWidth)/float64(width), float64(areaHeight)/float64(height))
+	options.GeoM.Translate(float64(x0), float64(y0))
+	screen.DrawImage(display, options)
 }
 
 // Calls various UI drawing functions, handling textOptions and setting correct starting locations before each call
@@ -132,15 +152,14 @@ func (g *Game) drawMemoryView(screen *ebiten.Image, face *text.GoXFace, textOpti
 
 	}
 
-	endAddress := g.memViewStart + memViewSize
-
-	if endAddress > 4096 {
-		g.memViewStart = 4096 - memViewSize // Adjust start address to keep endAddress within memory bounds
-		endAddress = 4096
+	memorySize := uint32(g.emulator.MemorySize())
+	if uint32(g.memViewStart)+uint32(memViewSize) > memorySize {
+		g.memViewStart = uint16(memorySize - uint32(memViewSize)) // Adjust start address to keep end address within memory bounds
 	}
 
 	// Draw memory rows
-	for addr := g.memViewStart; addr < endAddress; addr += uint16(memWidth) {
+	for row := range uint16(memNumRows) {
+		addr := g.memViewStart + row*memWidth
 		textOptions.GeoM.Translate(0, lineHeight)
 
 		// Draw row address
@@ -1,25 +1,46 @@
 package main
 
 import (
+	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 // Handles input and returns true if a cycle should happen
 func (g *Game) handleInput() bool {
-	for i, key := range keyArray {
-		if inpututil.IsKeyJustPressed(key) {
-			g.emulator.PressKey(byte(i))
-		} else if inpututil.IsKeyJustReleased(key) {
-			g.emulator.ReleaseKey(byte(i))
+	for key := range g.heldKeys {
+		held := g.keyBindings.held(key, g.gamepads)
+		if held == g.heldKeys[key] {
+			continue
+		}
+		g.heldKeys[key] = held
+		if held {
+			g.emulator.PressKey(byte(key))
+		} else {
+			g.emulator.ReleaseKey(byte(key))
 		}
 	}
 
 	return !g.stepMode || g.inputForStepCycle()
 }
 
+// updateGamepads refreshes the list of connected gamepads
+func (g *Game) updateGamepads() {
+	g.gamepads = ebiten.AppendGamepadIDs(g.gamepads[:0])
+}
+
+// releaseKeys releases every CHIP-8 key held by the bindings
+func (g *Game) releaseKeys() {
+	for key, held := range g.heldKeys {
+		if held {
+			g.emulator.ReleaseKey(byte(key))
+			g.heldKeys[key] = false
+		}
+	}
+}
+
 func (g *Game) inputForStepCycle() bool {
 	for _, key := range stepKeys {
-		if inpututil.IsKeyJustPressed(key) {
+		if !g.keyBindings.bound(key) && inpututil.IsKeyJustPressed(key) {
 			return true
 		}
 	}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// keyBindings are the keyboard keys and gamepad buttons which hold each CHIP-8 key
+type keyBindings struct {
+	keys    [16][]ebiten.Key
+	buttons [16][]ebiten.StandardGamepadButton
+}
+
+// defaultKeyBindings returns the 1234/QWER/ASDF/ZXCV keyboard layout, with the
+// gamepad D-pad on 5/7/8/9 (up/left/down/right, as WASD) and the face buttons on 6 and 4
+func defaultKeyBindings() *keyBindings {
+	b := &keyBindings{}
+	for key, k := range keyArray {
+		b.keys[key] = []ebiten.Key{k}
+	}
+	b.buttons[0x5] = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftTop}
+	b.buttons[0x7] = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftLeft}
+	b.buttons[0x8] = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftBottom}
+	b.buttons[0x9] = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonLeftRight}
+	b.buttons[0x6] = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightBottom}
+	b.buttons[0x4] = []ebiten.StandardGamepadButton{ebiten.StandardGamepadButtonRightRight}
+	return b
+}
+
+// controlBindings are the keyboard keys and gamepad buttons for the control
+// names a ROM database uses to list a ROM's keys
+var controlBindings = map[string]struct {
+	key    ebiten.Key
+	button ebiten.StandardGamepadButton
+}{
+	"up":    {ebiten.KeyArrowUp, ebiten.StandardGamepadButtonLeftTop},
+	"down":  {ebiten.KeyArrowDown, ebiten.StandardGamepadButtonLeftBottom},
+	"left":  {ebiten.KeyArrowLeft, ebiten.StandardGamepadButtonLeftLeft},
+	"right": {ebiten.KeyArrowRight, ebiten.StandardGamepadButtonLeftRight},
+	"a":     {ebiten.KeyShiftRight, ebiten.StandardGamepadButtonRightBottom},
+	"b":     {ebiten.KeyEnter, ebiten.StandardGamepadButtonRightRight},
+}
+
+// bindControls adds the arrow keys, D-pad and face buttons to the CHIP-8 keys
+// a ROM database lists for each control, alongside their keyboard layout keys
+func (b *keyBindings) bindControls(controls map[string]byte) {
+	for name, key := range controls {
+		control, ok := controlBindings[name]
+		if !ok {
+			continue // e.g. player 2 controls
+		}
+		for i := range b.keys {
+			b.keys[i] = slices.DeleteFunc(b.keys[i], func(bound ebiten.Key) bool { return bound == control.key })
+			b.buttons[i] = slices.DeleteFunc(b.buttons[i], func(bound ebiten.StandardGamepadButton) bool { return bound == control.button })
+		}
+		b.keys[key] = append(b.keys[key], control.key)
+		b.buttons[key] = append(b.buttons[key], control.button)
+	}
+}
+
+// held reports whether any input bound to a CHIP-8 key is held, on the
+// keyboard or any of the connected gamepads
+func (b *keyBindings) held(key int, gamepads []ebiten.GamepadID) bool {
+	for _, k := range b.keys[key] {
+		if ebiten.IsKeyPressed(k) {
+			return true
+		}
+	}
+	for _, id := range gamepads {
+		for _, button := range b.buttons[key] {
+			if ebiten.IsStandardGamepadButtonPressed(id, button) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bound reports whether a keyboard key is bound to any CHIP-8 key, in which
+// case it no longer triggers the emulator's own controls
+func (b *keyBindings) bound(k ebiten.Key) bool {
+	for _, keys := range b.keys {
+		if slices.Contains(keys, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindKey binds a keyboard key to a CHIP-8 key in place of its other keys,
+// removing it from any other CHIP-8 key
+func (b *keyBindings) bindKey(key int, k ebiten.Key) {
+	for i := range b.keys {
+		b.keys[i] = slices.DeleteFunc(b.keys[i], func(bound ebiten.Key) bool { return bound == k })
+	}
+	b.keys[key] = []ebiten.Key{k}
+}
+
+// bindButton binds a gamepad button to a CHIP-8 key in place of its other
+// buttons, removing it from any other CHIP-8 key
+func (b *keyBindings) bindButton(key int, button ebiten.StandardGamepadButton) {
+	for i := range b.buttons {
+		b.buttons[i] = slices.DeleteFunc(b.buttons[i], func(bound ebiten.StandardGamepadButton) bool { return bound == button })
+	}
+	b.buttons[key] = []ebiten.StandardGamepadButton{button}
+}
+
+// clone returns a deep copy of the bindings
+func (b *keyBindings) clone() *keyBindings {
+	c := &keyBindings{}
+	for i := range b.keys {
+		c.keys[i] = slices.Clone(b.keys[i])
+		c.buttons[i] = slices.Clone(b.buttons[i])
+	}
+	return c
+}
+
+// gamepadButtonNames are the names of standard gamepad buttons in a key config,
+// using the Xbox layout
+var gamepadButtonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "LB",
+	ebiten.StandardGamepadButtonFrontTopRight:    "RB",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "LT",
+	ebiten.StandardGamepadButtonFrontBottomRight: "RT",
+	ebiten.StandardGamepadButtonCenterLeft:       "Back",
+	ebiten.StandardGamepadButtonCenterRight:      "Start",
+	ebiten.StandardGamepadButtonLeftStick:        "LS",
+	ebiten.StandardGamepadButtonRightStick:       "RS",
+	ebiten.StandardGamepadButtonLeftTop:          "Up",
+	ebiten.StandardGamepadButtonLeftBottom:       "Down",
+	ebiten.StandardGamepadButtonLeftLeft:         "Left",
+	ebiten.StandardGamepadButtonLeftRight:        "Right",
+	ebiten.StandardGamepadButtonCenterCenter:     "Home",
+}
+
+// gamepadButton is a standard gamepad button, written by name in a key config
+type gamepadButton ebiten.StandardGamepadButton
+
+func (b gamepadButton) MarshalText() ([]byte, error) {
+	name, ok := gamepadButtonNames[ebiten.StandardGamepadButton(b)]
+	if !ok {
+		return nil, fmt.Errorf("unknown gamepad button: %d", b)
+	}
+	return []byte(name), nil
+}
+
+func (b *gamepadButton) UnmarshalText(text []byte) error {
+	for button, name := range gamepadButtonNames {
+		if strings.EqualFold(name, string(text)) {
+			*b = gamepadButton(button)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown gamepad button: %q", text)
+}
+
+// bindingConfig overrides the bindings of some CHIP-8 keys, by hex digit
+type bindingConfig struct {
+	Keyboard map[string][]ebiten.Key    `json:"keyboard,omitempty"`
+	Gamepad  map[string][]gamepadButton `json:"gamepad,omitempty"`
+}
+
+// keyConfig is the key binding config file, e.g.
+//
+//	{
+//	  "keyboard": {"5": ["W", "ArrowUp"]},
+//	  "gamepad": {"6": ["A", "RB"]},
+//	  "roms": {"tetris.ch8": {"keyboard": {"4": ["ArrowLeft"], "6": ["ArrowRight"]}}}
+//	}
+//
+// CHIP-8 keys not listed keep their default bindings. ROM overrides are
+// looked up by the SHA-1 of the ROM in hex, then by file name.
+type keyConfig struct {
+	bindingConfig
+	ROMs map[string]bindingConfig `json:"roms,omitempty"`
+}
+
+// defaultKeyConfigPath returns the key config path in the user's config directory
+func defaultKeyConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chip8-emulator", "keys.json")
+}
+
+// loadKeyConfig reads a key config, which is empty if the file doesn't exist
+func loadKeyConfig(path string) (*keyConfig, error) {
+	config := &keyConfig{}
+	if path == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key config: %w", err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse key config %s: %w", path, err)
+	}
+
+	// check every override applies, so mistakes are reported at startup
+	if _, err := config.bindings("", "", nil); err != nil {
+		return nil, err
+	}
+	for rom, override := range config.ROMs {
+		if err := override.apply(defaultKeyBindings()); err != nil {
+			return nil, fmt.Errorf("key config for ROM %s: %w", rom, err)
+		}
+	}
+	return config, nil
+}
+
+// save writes the key config, creating its directory if needed
+func (c *keyConfig) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create key config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write key config: %w", err)
+	}
+	return nil
+}
+
+// romOverride returns the name of the ROM override for a ROM's hash or file name, if any
+func (c *keyConfig) romOverride(romHash, romName string) (string, bool) {
+	for _, name := range []string{romHash, romName} {
+		if _, ok := c.ROMs[name]; ok && name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// bindings returns the default bindings with the ROM's controls bound, then
+// the config's overrides applied, and those for the ROM if it has any
+func (c *keyConfig) bindings(romHash, romName string, controls map[string]byte) (*keyBindings, error) {
+	b := defaultKeyBindings()
+	b.bindControls(controls)
+	if err := c.bindingConfig.apply(b); err != nil {
+		return nil, err
+	}
+	if name, ok := c.romOverride(romHash, romName); ok {
+		if err := c.ROMs[name].apply(b); err != nil {
+			return nil, fmt.Errorf("key config for ROM %s: %w", name, err)
+		}
+	}
+	return b, nil
+}
+
+// apply replaces the bindings of the CHIP-8 keys listed in the config
+func (bc bindingConfig) apply(b *keyBindings) error {
+	for name, keys := range bc.Keyboard {
+		key, err := parseChip8Key(name)
+		if err != nil {
+			return err
+		}
+		b.keys[key] = slices.Clone(keys)
+	}
+	for name, buttons := range bc.Gamepad {
+		key, err := parseChip8Key(name)
+		if err != nil {
+			return err
+		}
+		b.buttons[key] = make([]ebiten.StandardGamepadButton, len(buttons))
+		for i, button := range buttons {
+			b.buttons[key][i] = ebiten.StandardGamepadButton(button)
+		}
+	}
+	return nil
+}
+
+// newBindingConfig returns a config listing every CHIP-8 key's bindings
+func newBindingConfig(b *keyBindings) bindingConfig {
+	bc := bindingConfig{
+		Keyboard: make(map[string][]ebiten.Key),
+		Gamepad:  make(map[string][]gamepadButton),
+	}
+	for key := range b.keys {
+		name := fmt.Sprintf("%X", key)
+		bc.Keyboard[name] = slices.Clone(b.keys[key])
+		for _, button := range b.buttons[key] {
+			bc.Gamepad[name] = append(bc.Gamepad[name], gamepadButton(button))
+		}
+	}
+	return bc
+}
+
+// parseChip8Key parses a CHIP-8 key's hex digit
+func parseChip8Key(name string) (int, error) {
+	key, err := strconv.ParseUint(name, 16, 8)
+	if err != nil || key > 0xF {
+		return 0, fmt.Errorf("invalid CHIP-8 key: %q", name)
+	}
+	return int(key), nil
+}
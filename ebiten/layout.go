@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// scalingMode is how the layout is scaled to fit the window
+type scalingMode int
+
+const (
+	scalingFit     scalingMode = iota // largest scale that fits, keeping the aspect ratio
+	scalingInteger                    // largest whole-number scale that fits, so pixels stay sharp
+)
+
+// String returns the name of the scaling mode, as accepted by parseScalingMode
+func (m scalingMode) String() string {
+	switch m {
+	case scalingFit:
+		return "fit"
+	case scalingInteger:
+		return "integer"
+	}
+	return fmt.Sprintf("scalingMode(%d)", int(m))
+}
+
+// parseScalingMode returns the scaling mode with the given name
+func parseScalingMode(name string) (scalingMode, error) {
+	for _, m := range []scalingMode{scalingFit, scalingInteger} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown scaling mode: %q", name)
+}
+
+// scale returns the scale at which content of the given size fits the window
+func (m scalingMode) scale(windowWidth, windowHeight, width, height int) float64 {
+	scale := min(float64(windowWidth)/float64(width), float64(windowHeight)/float64(height))
+	if m == scalingInteger {
+		scale = max(1, math.Floor(scale))
+	}
+	return scale
+}
+
+// Layout uses the whole window, Draw scales the content to fit it
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return outsideWidth, outsideHeight
+}
+
+// handleDisplayInput handles the palette, deflicker, CRT and fullscreen keys,
+// unless they are bound to CHIP-8 keys
+func (g *Game) handleDisplayInput() {
+	if g.remap != nil {
+		return
+	}
+	if !g.keyBindings.bound(paletteKey) && inpututil.IsKeyJustPressed(paletteKey) {
+		g.nextPalette()
+	}
+	if !g.keyBindings.bound(deflickerKey) && inpututil.IsKeyJustPressed(deflickerKey) {
+		g.setDeflickerMode((g.phosphor.mode + 1) % (deflickerOR + 1))
+		fmt.Printf("Deflicker: %s\n", g.phosphor.mode)
+	}
+	if !g.keyBindings.bound(crtKey) && inpututil.IsKeyJustPressed(crtKey) {
+		g.nextCRTPreset()
+	}
+	if !g.keyBindings.bound(fullscreenKey) && inpututil.IsKeyJustPressed(fullscreenKey) {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	width, height := screen.Bounds().Dx(), screen.Bounds().Dy()
+
+	// fullscreen shows only the display, scaled on the high resolution grid so
+	// integer scaling keeps both resolutions' pixels whole
+	if ebiten.IsFullscreen() && g.remap == nil {
+		screen.Fill(g.pixelColors[0])
+		scale := g.scaling.scale(width, height, chip8.HighResWidth, chip8.HighResHeight)
+		displayWidth := float32(scale * chip8.HighResWidth)
+		displayHeight := float32(scale * chip8.HighResHeight)
+		g.drawChip8Pixels(screen, (float32(width)-displayWidth)/2, (float32(height)-displayHeight)/2, displayWidth, displayHeight)
+		return
+	}
+
+	// the panels are drawn at their native size, then scaled to the window
+	if g.uiImage == nil {
+		g.uiImage = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	g.uiImage.Fill(colorBackground)
+	g.drawChip8Display(g.uiImage)
+	g.drawUI(g.uiImage)
+	if g.remap != nil {
+		g.drawRemapScreen(g.uiImage)
+	}
+
+	screen.Fill(colorBackground)
+	scale := g.scaling.scale(width, height, screenWidth, screenHeight)
+	options := &ebiten.DrawImageOptions{}
+	options.GeoM.Scale(scale, scale)
+	options.GeoM.Translate((float64(width)-scale*screenWidth)/2, (float64(height)-scale*screenHeight)/2)
+	if g.scaling == scalingFit {
+		options.Filter = ebiten.FilterLinear
+	}
+	screen.DrawImage(g.uiImage, options)
+}
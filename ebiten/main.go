@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"image/color"
 	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
+	"github.com/bdeatock/chip8-emulator/capture"
 	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/sound"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 )
@@ -17,26 +22,69 @@ type environment interface {
 }
 
 type Game struct {
-	cycleCount      int
-	emulator        *chip8.Emulator
-	memViewStart    uint16
-	stepMode        bool // True = paused (can manually step)
-	cyclesPerSecond int
-	isRunning       bool
-	isWasm          bool
-	audioContext    *audio.Context
-	audioPlayer     *audio.Player
-	currentRom      []byte // stores last loaded rom to re-load after reset
-}
-
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
+	emulator     *chip8.Emulator
+	memViewStart uint16
+	stepMode     bool // True = paused (can manually step)
+	isRunning    bool
+	isWasm       bool
+	audioContext *audio.Context
+	audioPlayer  *audio.Player
+	buzzer       *sound.Buzzer
+	currentRom   []byte // stores last loaded rom to re-load after reset
+	saveSlots    [saveSlotCount][]byte
+	rewind       *rewindBuffer
+	recordPath   string // movie file written on exit when recording input
+
+	// Key bindings, see keymap.go
+	keyConfig     *keyConfig
+	keyConfigPath string // where remapped keys are saved, empty to not save them
+	keyBindings   *keyBindings
+	heldKeys      [16]bool // CHIP-8 keys held by the bindings, as last sent to the emulator
+	gamepads      []ebiten.GamepadID
+	romName       string // file name of the loaded ROM, which can have its own key bindings
+	remap         *remapScreen
+
+	// Display, see palette.go and layout.go
+	pixelColors   [4]color.RGBA // display colours, from the palette or ROM database
+	palettes      []palette
+	paletteIndex  int  // palette used unless the ROM database gives colours
+	paletteChosen bool // palette picked by the user, which takes precedence over the ROM database
+	romColors     bool // pixelColors are the ROM database's
+	scaling       scalingMode
+	uiImage       *ebiten.Image // the panels layout at its native size, scaled to the window
+	phosphor      phosphor      // recent frames of the display, see phosphor.go
+	displayImage  *ebiten.Image // the display a pixel per CHIP-8 pixel, see drawChip8Pixels
+	displayPixels [chip8.HighResWidth * chip8.HighResHeight * 4]byte
+
+	// CRT shader, see crt.go
+	crt       crtSettings
+	crtPreset int // last preset picked, which F8 cycles on from
+	crtShader *ebiten.Shader
+	crtImage  *ebiten.Image // the display scaled to the size the shader draws it at
+
+	// Screenshots and GIF recordings, see capture.go
+	captureDir   string
+	captureScale int
+	gifRecorder  *capture.Recorder // GIF being recorded, nil when not recording
 }
 
 func main() {
 	options := parseCommandLineOptions()
 
-	emu := chip8.New()
+	emulatorOptions := []chip8.EmulatorOption{
+		chip8.WithPlatform(options.platform),
+		chip8.WithInstructionsPerFrame(options.instructionsPerFrame),
+		chip8.WithTiming(options.timing),
+	}
+	if options.useROMDatabase {
+		db, err := chip8.LoadROMDatabase(options.romDatabasePath)
+		if err != nil {
+			fmt.Printf("Error loading ROM database: %v\n", err)
+			os.Exit(1)
+		}
+		emulatorOptions = append(emulatorOptions, chip8.WithROMDatabase(db))
+	}
+	emu := chip8.New(emulatorOptions...)
 	fmt.Println("=== CHIP-8 Emulator initialized ===")
 
 	if err := initEbiten(emu, options); err != nil {
@@ -46,19 +94,39 @@ func main() {
 }
 
 func initEbiten(emu *chip8.Emulator, options *Options) error {
-	cyclesPerSecond := 4
-	if options.cycleMode != "step" {
-		cyclesPerSecond = options.cyclesPerSecond
+	keyConfig, err := loadKeyConfig(options.keyConfigPath)
+	if err != nil {
+		return err
+	}
+	palettes, err := loadPalettes(options.paletteConfigPath)
+	if err != nil {
+		return err
 	}
 
 	game := &Game{
-		emulator:        emu,
-		stepMode:        options.cycleMode == "step",
-		cyclesPerSecond: cyclesPerSecond,
-		isWasm:          runtime.GOOS == "js",
+		emulator:      emu,
+		stepMode:      options.cycleMode == "step",
+		isWasm:        runtime.GOOS == "js",
+		rewind:        newRewindBuffer(options.rewindDepth, options.rewindFrames),
+		keyConfig:     keyConfig,
+		keyConfigPath: options.keyConfigPath,
+		palettes:      palettes,
+		scaling:       options.scaling,
+		captureDir:    options.captureDir,
+		captureScale:  options.captureScale,
+	}
+	game.phosphor.setMode(options.deflicker, &emu.Display, emu.HighRes)
+	game.crtPreset = options.crtPreset
+	if err := game.setCRT(options.crt); err != nil {
+		return err
+	}
+	if options.palette != "" {
+		if err := game.selectPalette(options.palette); err != nil {
+			return err
+		}
 	}
 
-	if err := game.initSound(); err != nil {
+	if err := game.initSound(options.sound); err != nil {
 		return fmt.Errorf("error loading sound: %w", err)
 	}
 
@@ -72,31 +140,79 @@ func initEbiten(emu *chip8.Emulator, options *Options) error {
 			return fmt.Errorf("error loading ROM: %w", err)
 		}
 		game.isRunning = true
+		game.romName = filepath.Base(options.romPath)
+
+		// settings given on the command line take precedence over the ROM database
+		if options.platformSet {
+			emu.Config.SetPlatform(options.platform)
+		}
+		if options.speedSet {
+			emu.Config.InstructionsPerFrame = options.instructionsPerFrame
+		}
+
+		if err := game.startMovie(options); err != nil {
+			return fmt.Errorf("error starting movie: %w", err)
+		}
 	}
 
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Emulator Display")
-	if !game.stepMode {
-		ebiten.SetTPS(cyclesPerSecond)
+	if err := game.applyROMInfo(); err != nil {
+		return err
 	}
 
-	if err := ebiten.RunGame(game); err != nil {
-		return fmt.Errorf("error while running: %w", err)
+	ebiten.SetWindowSize(screenWidth*options.windowScale, screenHeight*options.windowScale)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetFullscreen(options.fullscreen)
+	ebiten.SetWindowTitle("Emulator Display")
+	ebiten.SetTPS(chip8.FramesPerSecond)
+
+	runErr := ebiten.RunGame(game)
+	// saved even if emulation failed, so the movie reproduces the failure
+	if err := game.saveMovie(); err != nil {
+		fmt.Printf("Failed to save movie: %v\n", err)
+	}
+	if err := game.saveGIF(); err != nil {
+		fmt.Printf("Failed to save GIF: %v\n", err)
+	}
+	if runErr != nil {
+		return fmt.Errorf("error while running: %w", runErr)
 	}
 
 	return nil
 }
 
 func (g *Game) Update() error {
+	// once per 60Hz frame, whether or not the emulator ran, with the
+	// resolution the frame ended in
+	defer func() { g.phosphor.advance(&g.emulator.Display, g.emulator.HighRes) }()
+	defer g.recordGIFFrame()
+
+	g.handleDisplayInput()
+	g.handleCaptureInput()
+	g.handleSoundInput()
 	if !g.isRunning {
 		return nil
 	}
 
+	g.updateGamepads()
+	if g.handleRemapInput() {
+		g.handleSound()
+		return nil
+	}
+
+	g.handleSaveStateInput()
+
+	if g.handleRewind() {
+		g.handleSound()
+		return nil
+	}
+
 	if g.handleInput() {
-		// time to run a cycle
-		g.cycleCount++
-		deltaTime := time.Second / time.Duration(g.cyclesPerSecond)
-		if err := g.emulator.Step(deltaTime); err != nil {
+		// time to run a frame, or an instruction in step mode
+		g.recordRewind()
+		if err := g.runEmulator(); errors.Is(err, chip8.ErrProgramExit) {
+			// program finished, leave final frame on screen
+			g.isRunning = false
+		} else if err != nil {
 			return err
 		}
 	}
@@ -106,27 +222,32 @@ func (g *Game) Update() error {
 	return nil
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	// Clear screen
-	screen.Fill(colorBackground)
-
-	g.drawChip8Display(screen)
-	g.drawUI(screen)
+// runEmulator runs a frame, or a single instruction in step mode with the
+// timers advancing as they would at full speed
+func (g *Game) runEmulator() error {
+	if g.stepMode {
+		instructions := max(1, g.emulator.Config.InstructionsPerFrame)
+		return g.emulator.Step(chip8.FrameDuration / time.Duration(instructions))
+	}
+	_, err := g.emulator.RunFrame()
+	return err
 }
 
 func (g *Game) ToggleStepMode() {
-	if g.stepMode {
-		g.stepMode = false
-		ebiten.SetTPS(g.cyclesPerSecond)
-	} else {
-		g.stepMode = true
-		ebiten.SetTPS(60)
+	if g.movieActive() {
+		// movies count frames, so can't be stepped through
+		fmt.Printf("Step mode is %v\n", errMovieActive)
+		return
 	}
+	g.stepMode = !g.stepMode
 }
 
+// SetCyclesPerSecond sets the speed, run as the nearest number of instructions per frame
 func (g *Game) SetCyclesPerSecond(cycles int) {
-	g.cyclesPerSecond = cycles
-	if !g.stepMode {
-		ebiten.SetTPS(g.cyclesPerSecond)
+	if g.movieActive() {
+		return
 	}
+	instructions := max(1, (cycles+chip8.FramesPerSecond/2)/chip8.FramesPerSecond)
+	g.emulator.Config.InstructionsPerFrame = instructions
+	g.emulator.BaseConfig().InstructionsPerFrame = instructions // kept for the next ROM loaded
 }
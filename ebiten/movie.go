@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// errMovieActive is returned when restoring state would break a movie being recorded or played
+var errMovieActive = errors.New("not available while recording or playing a movie")
+
+// startMovie starts recording or replaying a movie as set by the options
+func (g *Game) startMovie(options *Options) error {
+	if g.stepMode && (options.replayPath != "" || options.recordPath != "") {
+		return fmt.Errorf("movies can't be used in step mode")
+	}
+	if options.replayPath != "" {
+		file, err := os.Open(options.replayPath)
+		if err != nil {
+			return fmt.Errorf("failed to open movie: %w", err)
+		}
+		defer file.Close()
+
+		movie, err := chip8.ReadMovie(bufio.NewReader(file))
+		if err != nil {
+			return err
+		}
+		if err := g.emulator.PlayMovie(movie); err != nil {
+			return err
+		}
+		fmt.Printf("Replaying %d frames from %s\n", movie.Length, options.replayPath)
+	}
+
+	if options.recordPath != "" {
+		if err := g.emulator.StartRecording(); err != nil {
+			return err
+		}
+		g.recordPath = options.recordPath
+		fmt.Printf("Recording input to %s\n", options.recordPath)
+	}
+	return nil
+}
+
+// saveMovie stops recording and writes the movie, if recording
+func (g *Game) saveMovie() error {
+	movie := g.emulator.StopRecording()
+	if movie == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := movie.Write(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(g.recordPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write movie: %w", err)
+	}
+	fmt.Printf("Saved %d frames of input to %s\n", movie.Length, g.recordPath)
+	return nil
+}
+
+// movieActive reports whether a movie is being recorded or played
+func (g *Game) movieActive() bool {
+	return g.emulator.Recording() || g.emulator.Playing()
+}
@@ -5,30 +5,99 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/sound"
 )
 
 type Options struct {
-	romPath         string
-	cycleMode       string
-	cyclesPerSecond int
-	displayRate     int
+	romPath              string
+	cycleMode            string
+	cyclesPerSecond      int
+	displayRate          int
+	instructionsPerFrame int
+	platform             chip8.Platform
+	timing               chip8.Timing
+	rewindDepth          int    // number of rewind snapshots kept
+	rewindFrames         int    // 60Hz frames between rewind snapshots
+	recordPath           string // movie file to record input to
+	replayPath           string // movie file to replay
+	keyConfigPath        string // key binding config file, empty for the defaults
+	platformSet          bool   // -platform was given, overriding the ROM database
+	speedSet             bool   // -speed or -ipf was given, overriding the ROM database
+	useROMDatabase       bool   // apply the settings listed for the ROM in the ROM database
+	romDatabasePath      string // ROM database file overriding the built-in entries
+	palette              string // palette name or list of colours, empty for the default
+	paletteConfigPath    string // user palette config file
+	scaling              scalingMode
+	windowScale          int // initial window size, as a multiple of the layout size
+	fullscreen           bool
+	deflicker            deflickerMode
+	crtPreset            int         // CRT preset picked, see crt.go
+	crt                  crtSettings // CRT effect intensities, the preset's unless overridden
+	captureDir           string      // directory screenshots and GIFs are saved to
+	captureScale         int         // screen pixels per CHIP-8 pixel of screenshots and GIFs
+	sound                sound.Settings
 }
 
 func parseCommandLineOptions() *Options {
 	if runtime.GOOS == "js" { // we are in WASM
 		return &Options{
-			romPath:         "",
-			cycleMode:       "continuous",
-			cyclesPerSecond: 700,
-			displayRate:     60,
+			romPath:              "",
+			cycleMode:            "continuous",
+			cyclesPerSecond:      700,
+			displayRate:          60,
+			instructionsPerFrame: chip8.DefaultInstructionsPerFrame,
+			platform:             chip8.DefaultPlatform,
+			rewindDepth:          600,
+			rewindFrames:         1,
+			useROMDatabase:       true,
+			windowScale:          1,
+			captureScale:         8,
+			sound:                sound.DefaultSettings,
 		}
 	} else {
 		romPath := flag.String("rom", "", "Path to the ROM")
 		cycleMode := flag.String("mode", "continuous", "Execution mode: 'step' for manual stepping or 'continuous' for continuous execution")
-		cyclesPerSecond := flag.Int("speed", 700, "Number of cycles per second in continuous mode")
+		cyclesPerSecond := flag.Int("speed", 700, "Number of instructions per second in continuous mode, run in 60Hz frames unless -ipf is given")
+		instructionsPerFrame := flag.Int("ipf", 0, "Number of instructions per 60Hz frame (default -speed / 60)")
 		displayRate := flag.Int("refresh", 60, "Display refresh rate in Hz")
+		keyConfigPath := flag.String("keys", defaultKeyConfigPath(), "Key binding config file, written when keys are remapped with F5")
+		timingName := flag.String("timing", chip8.TimingFixed.String(), "Timing model: 'fixed' runs -ipf instructions per frame, 'vip' charges COSMAC VIP machine cycles per instruction and ignores -speed and -ipf")
+		platformName := flag.String("platform", chip8.DefaultPlatform.String(), "Platform preset setting instruction set and quirks: "+strings.Join(chip8.PlatformNames(), ", "))
+		rewindDepth := flag.Int("rewind-depth", 600, "Number of rewind snapshots to keep (0 disables rewind)")
+		rewindFrames := flag.Int("rewind-interval", 1, "Frames (1/60s) between rewind snapshots in continuous mode")
+		recordPath := flag.String("record", "", "Record key input to a movie file, saved on exit")
+		replayPath := flag.String("replay", "", "Replay key input from a movie file")
+		useROMDatabase := flag.Bool("romdb", true, "Apply the platform, quirks, speed, controls and colours listed for the ROM in the ROM database, unless -platform, -speed or -ipf is given")
+		romDatabasePath := flag.String("romdb-file", chip8.UserROMDatabasePath(), "ROM database file in the community chip-8-database programs.json format, whose entries take precedence over the built-in ones")
+		palette := flag.String("palette", "", "Display palette: a name, or 2 or 4 comma separated #RRGGBB colours for the background and planes. Overrides ROM database colours. F6 cycles palettes")
+		paletteConfigPath := flag.String("palettes", defaultPaletteConfigPath(), "Palette config file of named palettes, e.g. {\"gameboy\": [\"#0F380F\", \"#9BBC0F\"]}")
+		scalingName := flag.String("scaling", scalingFit.String(), "How the window contents scale when it is resized: 'fit' the window, or by 'integer' multiples to keep pixels sharp")
+		windowScale := flag.Int("scale", 1, "Initial window size, as a multiple of the layout size")
+		deflickerName := flag.String("deflicker", deflickerOff.String(), "Flicker reduction: 'off', 'fade' unlit pixels out over a few frames, or 'or' the last two frames. F7 cycles modes")
+		crtName := flag.String("crt", crtPresets[0].name, "CRT shader preset: "+strings.Join(crtPresetNames(), ", ")+". F8 cycles presets")
+		crtScanlines := flag.Float64("crt-scanlines", 0, "CRT scanline intensity from 0 to 1, overriding the -crt preset's")
+		crtBloom := flag.Float64("crt-bloom", 0, "CRT bloom intensity from 0 to 1, overriding the -crt preset's")
+		crtCurvature := flag.Float64("crt-curvature", 0, "CRT screen curvature from 0 to 1, overriding the -crt preset's")
+		crtVignette := flag.Float64("crt-vignette", 0, "CRT vignette intensity from 0 to 1, overriding the -crt preset's")
+		captureDir := flag.String("capture-dir", ".", "Directory screenshots and GIFs of the CHIP-8 display are saved to. F9 saves a screenshot, F10 starts and stops recording a GIF")
+		captureScale := flag.Int("capture-scale", 8, "Pixel scale of screenshots and GIFs, in the current palette")
+		waveformName := flag.String("waveform", sound.DefaultSettings.Waveform.String(), "Buzzer waveform: "+strings.Join(sound.WaveformNames(), ", ")+". XO-CHIP programs play their audio pattern instead")
+		tone := flag.Float64("tone", sound.DefaultSettings.Frequency, "Buzzer frequency in Hz")
+		volume := flag.Float64("volume", sound.DefaultSettings.Volume, "Buzzer volume from 0 to 1. M mutes, - and = change the volume")
+		fullscreen := flag.Bool("fullscreen", false, "Start fullscreen, showing only the CHIP-8 display. F11 toggles fullscreen")
 		flag.Parse()
 
+		var platformSet, speedSet bool
+		given := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) {
+			platformSet = platformSet || f.Name == "platform"
+			speedSet = speedSet || f.Name == "speed" || f.Name == "ipf"
+			given[f.Name] = true
+		})
+
 		if *romPath == "" {
 			fmt.Println("Please provide a ROM path using the -rom flag")
 			os.Exit(1)
@@ -43,15 +112,118 @@ func parseCommandLineOptions() *Options {
 			fmt.Println("Speed must be a positive number")
 			os.Exit(1)
 		}
+		if *instructionsPerFrame < 0 {
+			fmt.Println("Instructions per frame must be a positive number")
+			os.Exit(1)
+		}
+		if *instructionsPerFrame == 0 {
+			*instructionsPerFrame = max(1, (*cyclesPerSecond+chip8.FramesPerSecond/2)/chip8.FramesPerSecond)
+		}
 		if *displayRate <= 0 {
 			fmt.Println("Display rate must be a positive number")
 			os.Exit(1)
 		}
+
+		if *rewindDepth < 0 {
+			fmt.Println("Rewind depth must not be negative")
+			os.Exit(1)
+		}
+		if *rewindFrames <= 0 {
+			fmt.Println("Rewind interval must be a positive number")
+			os.Exit(1)
+		}
+
+		platform, err := chip8.ParsePlatform(*platformName)
+		if err != nil {
+			fmt.Printf("Invalid platform. Use one of: %s\n", strings.Join(chip8.PlatformNames(), ", "))
+			os.Exit(1)
+		}
+		timing, err := chip8.ParseTiming(*timingName)
+		if err != nil {
+			fmt.Println("Invalid timing. Use 'fixed' or 'vip'")
+			os.Exit(1)
+		}
+		scaling, err := parseScalingMode(*scalingName)
+		if err != nil {
+			fmt.Println("Invalid scaling. Use 'fit' or 'integer'")
+			os.Exit(1)
+		}
+		deflicker, err := parseDeflickerMode(*deflickerName)
+		if err != nil {
+			fmt.Println("Invalid deflicker mode. Use 'off', 'fade' or 'or'")
+			os.Exit(1)
+		}
+		if *windowScale <= 0 {
+			fmt.Println("Scale must be a positive number")
+			os.Exit(1)
+		}
+		if *captureScale <= 0 {
+			fmt.Println("Capture scale must be a positive number")
+			os.Exit(1)
+		}
+		waveform, err := sound.ParseWaveform(*waveformName)
+		if err != nil {
+			fmt.Printf("Invalid waveform. Use one of: %s\n", strings.Join(sound.WaveformNames(), ", "))
+			os.Exit(1)
+		}
+		soundSettings := sound.Settings{Waveform: waveform, Frequency: *tone, Volume: *volume}
+		if err := soundSettings.Validate(); err != nil {
+			fmt.Printf("Invalid sound settings: %v\n", err)
+			os.Exit(1)
+		}
+		crtPreset, err := parseCRTPreset(*crtName)
+		if err != nil {
+			fmt.Printf("Invalid CRT preset. Use one of: %s\n", strings.Join(crtPresetNames(), ", "))
+			os.Exit(1)
+		}
+		crt := crtPresets[crtPreset].settings
+		for _, override := range []struct {
+			name  string
+			value *float64
+			field *float32
+		}{
+			{"crt-scanlines", crtScanlines, &crt.Scanlines},
+			{"crt-bloom", crtBloom, &crt.Bloom},
+			{"crt-curvature", crtCurvature, &crt.Curvature},
+			{"crt-vignette", crtVignette, &crt.Vignette},
+		} {
+			if !given[override.name] {
+				continue
+			}
+			if *override.value < 0 || *override.value > 1 {
+				fmt.Printf("-%s must be between 0 and 1\n", override.name)
+				os.Exit(1)
+			}
+			*override.field = float32(*override.value)
+		}
 		return &Options{
-			romPath:         *romPath,
-			cycleMode:       *cycleMode,
-			cyclesPerSecond: *cyclesPerSecond,
-			displayRate:     min(*displayRate, *cyclesPerSecond), // display rate has no reason to ever be above cycle rate
+			romPath:              *romPath,
+			cycleMode:            *cycleMode,
+			cyclesPerSecond:      *cyclesPerSecond,
+			displayRate:          min(*displayRate, *cyclesPerSecond), // display rate has no reason to ever be above cycle rate
+			instructionsPerFrame: *instructionsPerFrame,
+			platform:             platform,
+			timing:               timing,
+			rewindDepth:          *rewindDepth,
+			rewindFrames:         *rewindFrames,
+			recordPath:           *recordPath,
+			replayPath:           *replayPath,
+			keyConfigPath:        *keyConfigPath,
+			platformSet:          platformSet,
+			speedSet:             speedSet,
+			useROMDatabase:       *useROMDatabase,
+			romDatabasePath:      *romDatabasePath,
+			palette:              *palette,
+			paletteConfigPath:    *paletteConfigPath,
+			scaling:              scaling,
+			windowScale:          *windowScale,
+			fullscreen:           *fullscreen,
+			deflicker:            deflicker,
+			crtPreset:            crtPreset,
+			crt:                  crt,
+			captureDir:           *captureDir,
+			captureScale:         *captureScale,
+			sound:                soundSettings,
 		}
 	}
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/capture"
+)
+
+// palette is a named set of CHIP-8 display colours, indexed by the XO-CHIP
+// plane bits of a pixel: background, plane 1, plane 2 and both planes
+type palette struct {
+	name   string
+	colors [4]color.RGBA
+}
+
+// builtinPalettes are the selectable palettes, the first is the default.
+// The Octo themes match the colours of the Octo IDE.
+var builtinPalettes = []palette{
+	{"default", [4]color.RGBA{colorBackground, {0, 255, 0, 255}, {255, 170, 0, 255}, {245, 245, 245, 255}}},
+	{"green", [4]color.RGBA{{0, 20, 0, 255}, {51, 255, 51, 255}, {0, 140, 0, 255}, {190, 255, 190, 255}}},
+	{"amber", [4]color.RGBA{{20, 10, 0, 255}, {255, 176, 0, 255}, {150, 90, 0, 255}, {255, 225, 150, 255}}},
+	{"white", [4]color.RGBA{{0, 0, 0, 255}, {255, 255, 255, 255}, {128, 128, 128, 255}, {200, 200, 200, 255}}},
+	{"octo", [4]color.RGBA{{0x99, 0x66, 0x00, 255}, {0xFF, 0xCC, 0x00, 255}, {0xFF, 0x66, 0x00, 255}, {0x66, 0x22, 0x00, 255}}},
+	{"lcd", [4]color.RGBA{{0xF9, 0xFF, 0xB3, 255}, {0x3D, 0x80, 0x26, 255}, {0xAB, 0xCC, 0x47, 255}, {0x00, 0x13, 0x1A, 255}}},
+	{"hotdog", [4]color.RGBA{{0x00, 0x00, 0x00, 255}, {0xFF, 0x00, 0x00, 255}, {0xFF, 0xFF, 0x00, 255}, {0xFF, 0xFF, 0xFF, 255}}},
+	{"gray", [4]color.RGBA{{0xAA, 0xAA, 0xAA, 255}, {0x00, 0x00, 0x00, 255}, {0xFF, 0xFF, 0xFF, 255}, {0x66, 0x66, 0x66, 255}}},
+	{"cga0", [4]color.RGBA{{0x00, 0x00, 0x00, 255}, {0x00, 0xFF, 0x00, 255}, {0xFF, 0x00, 0x00, 255}, {0xFF, 0xFF, 0x00, 255}}},
+	{"cga1", [4]color.RGBA{{0x00, 0x00, 0x00, 255}, {0xFF, 0x00, 0xFF, 255}, {0x00, 0xFF, 0xFF, 255}, {0xFF, 0xFF, 0xFF, 255}}},
+}
+
+// defaultPaletteConfigPath returns the palette config path in the user's config directory
+func defaultPaletteConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chip8-emulator", "palettes.json")
+}
+
+// loadPalettes returns the built-in palettes followed by those in the palette
+// config, which maps names to lists of colours, e.g.
+//
+//	{"gameboy": ["#0F380F", "#9BBC0F"], "sunset": ["#200020", "#FF8040", "#8040FF", "#FFFFFF"]}
+//
+// A user palette with a built-in name replaces it. The config is optional.
+func loadPalettes(path string) ([]palette, error) {
+	palettes := slices.Clone(builtinPalettes)
+	if path == "" {
+		return palettes, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return palettes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read palette config: %w", err)
+	}
+	var config map[string][]string
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse palette config %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		colors, err := capture.ParsePalette(strings.Join(config[name], ","))
+		if err != nil {
+			return nil, fmt.Errorf("palette %s: %w", name, err)
+		}
+		i := slices.IndexFunc(palettes, func(p palette) bool { return p.name == name })
+		if i < 0 {
+			palettes = append(palettes, palette{name, colors})
+		} else {
+			palettes[i].colors = colors
+		}
+	}
+	return palettes, nil
+}
+
+// selectPalette makes the palette with the given name, or list of hex
+// colours, the active one, overriding the ROM database's colours
+func (g *Game) selectPalette(name string) error {
+	i := slices.IndexFunc(g.palettes, func(p palette) bool { return p.name == name })
+	if i < 0 && strings.HasPrefix(name, "#") {
+		colors, err := capture.ParsePalette(name)
+		if err != nil {
+			return err
+		}
+		g.palettes = append(g.palettes, palette{name, colors})
+		i = len(g.palettes) - 1
+	}
+	if i < 0 {
+		return fmt.Errorf("unknown palette: %q, use one of %s or a list of colours", name, strings.Join(g.paletteNames(), ", "))
+	}
+	g.paletteIndex = i
+	g.paletteChosen = true
+	g.romColors = false
+	g.pixelColors = g.palettes[i].colors
+	return nil
+}
+
+// nextPalette cycles to the next palette, or from the ROM database's colours
+// back to the current palette
+func (g *Game) nextPalette() {
+	next := (g.paletteIndex + 1) % len(g.palettes)
+	if g.romColors {
+		next = g.paletteIndex
+	}
+	g.selectPalette(g.palettes[next].name)
+	fmt.Printf("Palette: %s\n", g.palettes[next].name)
+}
+
+// paletteNames returns the names of the selectable palettes
+func (g *Game) paletteNames() []string {
+	names := make([]string, len(g.palettes))
+	for i, p := range g.palettes {
+		names[i] = p.name
+	}
+	return names
+}
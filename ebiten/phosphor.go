@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// deflickerMode is how the display hides the flicker of sprites being
+// erased and redrawn with XOR
+type deflickerMode int
+
+const (
+	deflickerOff  deflickerMode = iota // draw the display as it is
+	deflickerFade                      // unlit pixels fade out over several frames, like a CRT phosphor
+	deflickerOR                        // draw pixels lit in either of the last two frames
+)
+
+// phosphorDecay is the brightness an unlit pixel keeps each frame in fade mode,
+// and phosphorCutoff the brightness below which it is no longer drawn
+const (
+	phosphorDecay  = 0.6
+	phosphorCutoff = 0.05
+)
+
+// String returns the name of the deflicker mode, as accepted by parseDeflickerMode
+func (m deflickerMode) String() string {
+	switch m {
+	case deflickerOff:
+		return "off"
+	case deflickerFade:
+		return "fade"
+	case deflickerOR:
+		return "or"
+	}
+	return fmt.Sprintf("deflickerMode(%d)", int(m))
+}
+
+// parseDeflickerMode returns the deflicker mode with the given name
+func parseDeflickerMode(name string) (deflickerMode, error) {
+	for _, m := range []deflickerMode{deflickerOff, deflickerFade, deflickerOR} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown deflicker mode: %q", name)
+}
+
+// phosphor is the frontend's memory of recent frames of the display, advanced
+// once per 60Hz frame however many instructions ran in it
+type phosphor struct {
+	mode     deflickerMode
+	highRes  bool
+	previous [chip8.HighResWidth * chip8.HighResHeight]byte    // display at the frame before last
+	last     [chip8.HighResWidth * chip8.HighResHeight]byte    // display at the last frame
+	planes   [chip8.HighResWidth * chip8.HighResHeight]byte    // planes each pixel was last lit with
+	levels   [chip8.HighResWidth * chip8.HighResHeight]float32 // brightness of each pixel, 1 while lit
+}
+
+// setMode changes the deflicker mode, starting from the current display
+func (p *phosphor) setMode(mode deflickerMode, display *[chip8.HighResWidth * chip8.HighResHeight]byte, highRes bool) {
+	p.mode = mode
+	p.reset(display, highRes)
+}
+
+// setDeflickerMode changes how the display hides flicker
+func (g *Game) setDeflickerMode(mode deflickerMode) {
+	g.phosphor.setMode(mode, &g.emulator.Display, g.emulator.HighRes)
+}
+
+// reset forgets earlier frames, so only the display is shown
+func (p *phosphor) reset(display *[chip8.HighResWidth * chip8.HighResHeight]byte, highRes bool) {
+	p.highRes = highRes
+	p.previous = *display
+	p.last = *display
+	for i, planes := range display {
+		p.planes[i] = planes & 0x3
+		p.levels[i] = 0
+		if planes&0x3 != 0 {
+			p.levels[i] = 1
+		}
+	}
+}
+
+// advance records a frame of the display
+func (p *phosphor) advance(display *[chip8.HighResWidth * chip8.HighResHeight]byte, highRes bool) {
+	if p.mode == deflickerOff {
+		return
+	}
+	if highRes != p.highRes {
+		// the pixels no longer line up
+		p.reset(display, highRes)
+		return
+	}
+	p.previous = p.last
+	p.last = *display
+	for i, planes := range display {
+		if planes&0x3 != 0 {
+			p.planes[i] = planes & 0x3
+			p.levels[i] = 1
+		} else {
+			p.levels[i] *= phosphorDecay
+		}
+	}
+}
+
+// pixel returns the colour to draw a display pixel in, or false if it isn't drawn
+func (p *phosphor) pixel(display *[chip8.HighResWidth * chip8.HighResHeight]byte, i int, colors *[4]color.RGBA) (color.RGBA, bool) {
+	planes := display[i] & 0x3
+	switch p.mode {
+	case deflickerFade:
+		if planes != 0 {
+			return colors[planes], true
+		}
+		if p.levels[i] < phosphorCutoff {
+			return color.RGBA{}, false
+		}
+		return blendColor(colors[0], colors[p.planes[i]], p.levels[i]), true
+	case deflickerOR:
+		planes |= p.previous[i] & 0x3
+	}
+	return colors[planes], planes != 0
+}
+
+// blendColor returns the colour t of the way from a to b
+func blendColor(a, b color.RGBA, t float32) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float32(x) + (float32(y)-float32(x))*t)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
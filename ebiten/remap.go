@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+)
+
+// remapScreen binds each CHIP-8 key in turn to the next key or gamepad button pressed
+type remapScreen struct {
+	key      int  // CHIP-8 key being bound
+	allROMs  bool // remapping the bindings for every ROM, rather than the loaded one's
+	bindings *keyBindings
+}
+
+// romHash returns the SHA-1 of the loaded ROM in hex, which identifies its key bindings
+func (g *Game) romHash() string {
+	hash := g.emulator.ROMHash()
+	return hex.EncodeToString(hash[:])
+}
+
+// updateKeyBindings applies the key config for the loaded ROM, on top of the
+// controls listed for it in the ROM database
+func (g *Game) updateKeyBindings() error {
+	info, _ := g.emulator.ROMInfo()
+	bindings, err := g.keyConfig.bindings(g.romHash(), g.romName, info.Keys)
+	if err != nil {
+		return err
+	}
+	g.keyBindings = bindings
+	return nil
+}
+
+// Handles the remap key, Shift+key remaps for every ROM, and input on the remap
+// screen. Returns true while the screen is open, in which case no cycle should run.
+func (g *Game) handleRemapInput() bool {
+	if g.remap == nil {
+		if !inpututil.IsKeyJustPressed(remapKey) {
+			return false
+		}
+		g.releaseKeys()
+		g.remap = &remapScreen{bindings: g.keyBindings.clone()}
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.remap.allROMs = true
+			if bindings, err := g.keyConfig.bindings("", "", nil); err == nil {
+				g.remap.bindings = bindings
+			}
+		}
+		return true
+	}
+
+	r := g.remap
+	for _, k := range inpututil.AppendJustPressedKeys(nil) {
+		switch k {
+		case ebiten.KeyEscape:
+			g.remap = nil
+			fmt.Println("Key remapping cancelled")
+			return true
+		case ebiten.KeyEnter:
+			// keep the current binding
+		default:
+			r.bindings.bindKey(r.key, k)
+		}
+		g.nextRemapKey()
+		return true
+	}
+	for _, id := range g.gamepads {
+		for button := range ebiten.StandardGamepadButtonMax + 1 {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				r.bindings.bindButton(r.key, button)
+				g.nextRemapKey()
+				return true
+			}
+		}
+	}
+	return true
+}
+
+// nextRemapKey moves on to the next CHIP-8 key, saving the bindings after the last
+func (g *Game) nextRemapKey() {
+	r := g.remap
+	r.key++
+	if r.key < len(r.bindings.keys) {
+		return
+	}
+	g.remap = nil
+
+	if r.allROMs {
+		g.keyConfig.bindingConfig = newBindingConfig(r.bindings)
+	} else {
+		name, ok := g.keyConfig.romOverride(g.romHash(), g.romName)
+		if !ok {
+			name = g.romHash()
+		}
+		if g.keyConfig.ROMs == nil {
+			g.keyConfig.ROMs = make(map[string]bindingConfig)
+		}
+		g.keyConfig.ROMs[name] = newBindingConfig(r.bindings)
+	}
+	if err := g.updateKeyBindings(); err != nil {
+		fmt.Printf("Failed to apply key bindings: %v\n", err)
+	}
+
+	if g.keyConfigPath == "" {
+		return
+	}
+	if err := g.keyConfig.save(g.keyConfigPath); err != nil {
+		fmt.Printf("Failed to save key bindings: %v\n", err)
+	} else {
+		fmt.Printf("Saved key bindings to %s\n", g.keyConfigPath)
+	}
+}
+
+// drawRemapScreen draws the remap prompt over the CHIP-8 display
+func (g *Game) drawRemapScreen(screen *ebiten.Image) {
+	r := g.remap
+	vector.DrawFilledRect(
+		screen,
+		marginX,
+		marginY,
+		chip8DisplayWidth*chip8PixelSize,
+		chip8DisplayHeight*chip8PixelSize,
+		colorBackground,
+		false,
+	)
+
+	var bound []string
+	for _, k := range r.bindings.keys[r.key] {
+		bound = append(bound, k.String())
+	}
+	for _, button := range r.bindings.buttons[r.key] {
+		bound = append(bound, "Gamepad "+gamepadButtonNames[button])
+	}
+	if len(bound) == 0 {
+		bound = []string{"nothing"}
+	}
+
+	scope := "the loaded ROM"
+	if r.allROMs {
+		scope = "all ROMs"
+	}
+	lines := []string{
+		"Remapping keys for " + scope,
+		"",
+		fmt.Sprintf("Press a key or gamepad button for CHIP-8 key %X", r.key),
+		"Currently " + strings.Join(bound, ", "),
+		"",
+		"Enter keeps the current binding, Esc cancels",
+	}
+
+	face := text.NewGoXFace(basicfont.Face7x13)
+	textOptions := &text.DrawOptions{}
+	textOptions.ColorScale.ScaleWithColor(colorPrimary)
+	textOptions.GeoM.Translate(marginX*3, marginY*3)
+	for _, line := range lines {
+		text.Draw(screen, line, face, textOptions)
+		textOptions.GeoM.Translate(0, lineHeight)
+	}
+}
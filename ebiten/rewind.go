@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// rewindBuffer is a ring buffer of emulator snapshots. Only the newest snapshot
+// is kept in full, each older one is stored as the difference to the snapshot
+// after it (XOR, then run-length encoded), which is mostly zeroes between frames.
+type rewindBuffer struct {
+	deltas   [][]byte // ring of backward deltas, oldest at start
+	start    int      // index of oldest delta
+	count    int      // number of deltas in ring
+	newest   []byte   // most recent full snapshot
+	interval int      // frames between snapshots in run mode
+	elapsed  int      // updates since last snapshot
+}
+
+func newRewindBuffer(depth int, interval int) *rewindBuffer {
+	return &rewindBuffer{
+		deltas:   make([][]byte, depth),
+		interval: max(1, interval),
+	}
+}
+
+// push records a snapshot as the newest entry, dropping the oldest if full.
+// Snapshots change size when the platform switches memory model, and history
+// from before the switch is discarded.
+func (r *rewindBuffer) push(state []byte) {
+	if len(r.deltas) == 0 {
+		// rewind disabled
+		return
+	}
+	if r.newest != nil && len(r.newest) != len(state) {
+		r.clear()
+	}
+	if r.newest != nil {
+		delta := encodeDelta(state, r.newest)
+		if r.count == len(r.deltas) {
+			// overwrite oldest
+			r.deltas[r.start] = delta
+			r.start = (r.start + 1) % len(r.deltas)
+		} else {
+			r.deltas[(r.start+r.count)%len(r.deltas)] = delta
+			r.count++
+		}
+	}
+	r.newest = state
+}
+
+// pop removes the newest snapshot and returns it, or false if the buffer is empty.
+// The snapshot before it becomes the newest.
+func (r *rewindBuffer) pop() ([]byte, bool) {
+	if r.newest == nil {
+		return nil, false
+	}
+
+	state := r.newest
+	if r.count == 0 {
+		r.newest = nil
+		return state, true
+	}
+
+	last := (r.start + r.count - 1) % len(r.deltas)
+	previous, err := decodeDelta(state, r.deltas[last])
+	r.deltas[last] = nil
+	r.count--
+	if err != nil {
+		// corrupt history can't be walked back any further
+		fmt.Printf("Discarding rewind history: %v\n", err)
+		r.clear()
+	} else {
+		r.newest = previous
+	}
+	return state, true
+}
+
+func (r *rewindBuffer) clear() {
+	clear(r.deltas)
+	r.start = 0
+	r.count = 0
+	r.newest = nil
+	r.elapsed = 0
+}
+
+// tick counts an update in run mode and reports whether a snapshot is due
+func (r *rewindBuffer) tick() bool {
+	r.elapsed++
+	if r.elapsed >= r.interval {
+		r.elapsed = 0
+		return true
+	}
+	return false
+}
+
+// encodeDelta returns base XOR target, run-length encoded as a series of
+// (zero run length, literal length, literal bytes) records. The snapshots
+// must be the same size.
+func encodeDelta(base []byte, target []byte) []byte {
+	var out []byte
+	for i := 0; i < len(target); {
+		zeroStart := i
+		for i < len(target) && base[i] == target[i] {
+			i++
+		}
+		literalStart := i
+		for i < len(target) && base[i] != target[i] {
+			i++
+		}
+		out = binary.AppendUvarint(out, uint64(literalStart-zeroStart))
+		out = binary.AppendUvarint(out, uint64(i-literalStart))
+		for j := literalStart; j < i; j++ {
+			out = append(out, base[j]^target[j])
+		}
+	}
+	return out
+}
+
+// decodeDelta applies a delta from encodeDelta to base, returning the target snapshot
+func decodeDelta(base []byte, delta []byte) ([]byte, error) {
+	target := make([]byte, len(base))
+	pos := 0
+	for len(delta) > 0 {
+		zeros, n := binary.Uvarint(delta)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid rewind delta")
+		}
+		delta = delta[n:]
+		literals, n := binary.Uvarint(delta)
+		if n <= 0 || uint64(len(delta)-n) < literals || uint64(len(base)-pos) < zeros+literals {
+			return nil, fmt.Errorf("invalid rewind delta")
+		}
+		delta = delta[n:]
+
+		copy(target[pos:], base[pos:pos+int(zeros)])
+		pos += int(zeros)
+		for i := range int(literals) {
+			target[pos+i] = base[pos+i] ^ delta[i]
+		}
+		delta = delta[literals:]
+		pos += int(literals)
+	}
+	copy(target[pos:], base[pos:])
+	return target, nil
+}
+
+// Handles rewind keys and returns true if rewinding, in which case no cycle should run.
+// In run mode holding a rewind key scrubs backwards at the rate snapshots were taken,
+// in step mode each press steps back a single cycle.
+func (g *Game) handleRewind() bool {
+	if g.movieActive() {
+		return false
+	}
+
+	pressed := false
+	justPressed := false
+	for _, key := range rewindKeys {
+		if g.keyBindings.bound(key) {
+			continue
+		}
+		pressed = pressed || ebiten.IsKeyPressed(key)
+		justPressed = justPressed || inpututil.IsKeyJustPressed(key)
+	}
+
+	if g.stepMode {
+		if justPressed {
+			g.rewindStep()
+		}
+		return pressed
+	}
+
+	if !pressed {
+		return false
+	}
+	if g.rewind.tick() {
+		g.rewindStep()
+	}
+	return true
+}
+
+// rewindStep restores the newest snapshot in the rewind buffer
+func (g *Game) rewindStep() {
+	state, ok := g.rewind.pop()
+	if !ok {
+		return
+	}
+	if err := g.loadState(state); err != nil {
+		fmt.Printf("Failed to rewind: %v\n", err)
+		g.rewind.clear()
+	}
+}
+
+// recordRewind takes a snapshot before the emulator runs, every instruction in step
+// mode so rewinding lands exactly on earlier instructions, otherwise every rewind interval
+func (g *Game) recordRewind() {
+	if !g.stepMode && !g.rewind.tick() {
+		return
+	}
+	state, err := g.saveState()
+	if err != nil {
+		fmt.Printf("Failed to record rewind snapshot: %v\n", err)
+		return
+	}
+	g.rewind.push(state)
+}
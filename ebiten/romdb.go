@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/capture"
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// applyROMInfo reports the ROM database entry the loaded ROM matched, if any,
+// and applies its controls, and its colours unless the user chose a palette
+func (g *Game) applyROMInfo() error {
+	g.pixelColors = g.palettes[g.paletteIndex].colors
+	g.romColors = false
+	if info, ok := g.emulator.ROMInfo(); ok {
+		fmt.Println(describeROMInfo(info))
+		if colors, err := capture.NewPalette(info.Colors); err == nil && !g.paletteChosen {
+			g.pixelColors = colors
+			g.romColors = true
+		}
+	}
+	return g.updateKeyBindings()
+}
+
+// describeROMInfo summarises a ROM database entry in one line
+func describeROMInfo(info chip8.ROMInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ROM database (%s): %s", info.Source, info.Title)
+	if info.File != "" {
+		fmt.Fprintf(&b, " (%s)", info.File)
+	}
+	fmt.Fprintf(&b, ", platform %s", info.Platform)
+	if info.Quirks != info.Platform.Profile().Quirks {
+		fmt.Fprint(&b, " with custom quirks")
+	}
+	if info.TickRate > 0 {
+		fmt.Fprintf(&b, ", %d instructions per frame", info.TickRate)
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Handles quick-save slot keys, saving with Shift+F1-F4 and loading with F1-F4
+func (g *Game) handleSaveStateInput() {
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+
+	for slot, key := range saveSlotKeys {
+		if !inpututil.IsKeyJustPressed(key) {
+			continue
+		}
+
+		if shift {
+			if err := g.saveToSlot(slot); err != nil {
+				fmt.Printf("Failed to save state to slot %d: %v\n", slot+1, err)
+			} else {
+				fmt.Printf("Saved state to slot %d\n", slot+1)
+			}
+		} else {
+			if err := g.loadFromSlot(slot); err != nil {
+				fmt.Printf("Failed to load state from slot %d: %v\n", slot+1, err)
+			} else {
+				fmt.Printf("Loaded state from slot %d\n", slot+1)
+			}
+		}
+	}
+}
+
+func (g *Game) saveToSlot(slot int) error {
+	state, err := g.saveState()
+	if err != nil {
+		return err
+	}
+	g.saveSlots[slot] = state
+	return nil
+}
+
+func (g *Game) loadFromSlot(slot int) error {
+	if g.saveSlots[slot] == nil {
+		return fmt.Errorf("slot is empty")
+	}
+	return g.loadState(g.saveSlots[slot])
+}
+
+// saveState returns a snapshot of the emulator
+func (g *Game) saveState() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := g.emulator.SaveState(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadState restores a snapshot of the emulator
+func (g *Game) loadState(state []byte) error {
+	if g.movieActive() {
+		return errMovieActive
+	}
+	return g.emulator.LoadState(bytes.NewReader(state))
+}
@@ -2,64 +2,92 @@ package main
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 
+	"github.com/bdeatock/chip8-emulator/sound"
 	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-// stream implements audio.ReadCloser interface for sine wave gen
+// stream implements the audio.ReadCloser interface, reading stereo float32
+// samples from the buzzer
 type stream struct {
-	pos int64
+	buzzer  *sound.Buzzer
+	samples []float32
 }
 
-// Read generates sine wave data and writes it to buffer, then updates the stream
-// position to ensure continuous playback across multiple calls
+// Read generates the buzzer's samples into buf, the same on both channels
 func (s *stream) Read(buf []byte) (int, error) {
 	const bytesPerSample = 8
-	const samplesPerCycle = sampleRate / frequency
 
 	sampleCount := len(buf) / bytesPerSample
+	if cap(s.samples) < sampleCount {
+		s.samples = make([]float32, sampleCount)
+	}
+	samples := s.samples[:sampleCount]
+	s.buzzer.Generate(samples)
 
-	for i := 0; i < sampleCount; i++ {
-		// Calculate sine wave value
-		phase := float64((s.pos/bytesPerSample)+int64(i)) / float64(samplesPerCycle)
-		sineValue := float32(math.Sin(2 * math.Pi * phase))
-
-		bits := math.Float32bits(sineValue)
-
-		// Write identical values to left and right channels
+	for i, sample := range samples {
+		bits := math.Float32bits(sample)
 		offset := i * bytesPerSample
 		binary.LittleEndian.PutUint32(buf[offset:], bits)
 		binary.LittleEndian.PutUint32(buf[offset+4:], bits)
 	}
 
-	bytesWritten := sampleCount * bytesPerSample
-	s.pos = (s.pos + int64(bytesWritten)) % (samplesPerCycle * bytesPerSample)
-
-	return bytesWritten, nil
+	return sampleCount * bytesPerSample, nil
 }
 
 func (s *stream) Close() error {
 	return nil
 }
 
-func (g *Game) initSound() error {
-	g.audioContext = audio.NewContext(sampleRate)
+// initSound starts the audio player, which plays continuously with the buzzer
+// fading in and out as the sound timer starts and stops
+func (g *Game) initSound(settings sound.Settings) error {
+	g.audioContext = audio.NewContext(sound.SampleRate)
+	g.buzzer = sound.NewBuzzer(sound.SampleRate, settings)
 
 	var err error
-	if g.audioPlayer, err = g.audioContext.NewPlayerF32(&stream{}); err != nil {
+	if g.audioPlayer, err = g.audioContext.NewPlayerF32(&stream{buzzer: g.buzzer}); err != nil {
 		return err
 	}
-
-	g.audioPlayer.SetVolume(0.2)
+	g.audioPlayer.Play()
 
 	return nil
 }
 
 func (g *Game) handleSound() {
-	if g.emulator.SoundTimer > 0 && !g.audioPlayer.IsPlaying() {
-		g.audioPlayer.Play()
-	} else if g.emulator.SoundTimer == 0 && g.audioPlayer.IsPlaying() {
-		g.audioPlayer.Pause()
+	g.buzzer.Update(g.emulator)
+}
+
+// handleSoundInput handles the mute and volume keys, unless they are bound to
+// CHIP-8 keys
+func (g *Game) handleSoundInput() {
+	if g.remap != nil {
+		return
+	}
+	if !g.keyBindings.bound(muteKey) && inpututil.IsKeyJustPressed(muteKey) {
+		g.buzzer.SetMuted(!g.buzzer.Muted())
+		if g.buzzer.Muted() {
+			fmt.Println("Sound muted")
+		} else {
+			fmt.Println("Sound unmuted")
+		}
+	}
+	if !g.keyBindings.bound(volumeDownKey) && inpututil.IsKeyJustPressed(volumeDownKey) {
+		g.setVolume(g.buzzer.Settings().Volume - volumeStep)
 	}
+	if !g.keyBindings.bound(volumeUpKey) && inpututil.IsKeyJustPressed(volumeUpKey) {
+		g.setVolume(g.buzzer.Settings().Volume + volumeStep)
+	}
+}
+
+// setVolume sets the buzzer volume, clamped from 0 to 1, and unmutes it
+func (g *Game) setVolume(volume float64) {
+	settings := g.buzzer.Settings()
+	settings.Volume = math.Round(max(0, min(1, volume))*100) / 100
+	g.buzzer.SetSettings(settings)
+	g.buzzer.SetMuted(false)
+	fmt.Printf("Volume: %.0f%%\n", settings.Volume*100)
 }
@@ -3,7 +3,11 @@
 package main
 
 import (
+	"fmt"
 	"syscall/js"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+	"github.com/bdeatock/chip8-emulator/sound"
 )
 
 // jsEnvironment implements the environment interface for WebAssembly
@@ -14,10 +18,15 @@ func (je *jsEnvironment) setupWasm(game *Game) {
 	js.Global().Set("loadROM", js.FuncOf(createLoadROMHandler(game)))
 	js.Global().Set("switchMode", js.FuncOf(createSwitchModeHandler(game)))
 	js.Global().Set("updateCycleRate", js.FuncOf(createSetCycleRateHandler(game)))
-	js.Global().Set("toggleLegacyShift", js.FuncOf(createToggleLegacyShiftHandler(game)))
-	js.Global().Set("toggleLegacyJump", js.FuncOf(createToggleLegacyJumpHandler(game)))
-	js.Global().Set("toggleLegacyStoreLoad", js.FuncOf(createToggleLegacyStoreLoadHandler(game)))
+	js.Global().Set("toggleQuirk", js.FuncOf(createToggleQuirkHandler(game)))
+	js.Global().Set("setPlatform", js.FuncOf(createSetPlatformHandler(game)))
+	js.Global().Set("setPalette", js.FuncOf(createSetPaletteHandler(game)))
+	js.Global().Set("setDeflicker", js.FuncOf(createSetDeflickerHandler(game)))
+	js.Global().Set("setCRT", js.FuncOf(createSetCRTHandler(game)))
+	js.Global().Set("setSound", js.FuncOf(createSetSoundHandler(game)))
 	js.Global().Set("resetEmulator", js.FuncOf(createResetEmulatorHandler(game)))
+	js.Global().Set("saveState", js.FuncOf(createSaveStateHandler(game)))
+	js.Global().Set("loadState", js.FuncOf(createLoadStateHandler(game)))
 }
 
 func createLoadROMHandler(g *Game) func(js.Value, []js.Value) any {
@@ -38,33 +47,213 @@ func createLoadROMHandler(g *Game) func(js.Value, []js.Value) any {
 			})
 		}
 		g.currentRom = romData
+		g.rewind.clear()
 		g.isRunning = true
+		if err := g.applyROMInfo(); err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+
+		return romInfoToJS(g)
+	}
+}
+
+// romInfoToJS returns the settings the loaded ROM runs with, and the title,
+// file and source of the ROM database entry it matched, if any
+func romInfoToJS(g *Game) any {
+	settings := map[string]any{
+		"platform":  g.emulator.Config.Platform.String(),
+		"quirks":    quirksToJS(&g.emulator.Config.Quirks),
+		"cycleRate": g.emulator.Config.InstructionsPerFrame * chip8.FramesPerSecond,
+	}
+	if info, ok := g.emulator.ROMInfo(); ok {
+		settings["title"] = info.Title
+		settings["file"] = info.File
+		settings["source"] = info.Source
+	}
+	return js.ValueOf(settings)
+}
+
+// quirkFields maps the quirk names used by the site to the emulator's quirk flags
+func quirkFields(q *chip8.Quirks) map[string]*bool {
+	return map[string]*bool{
+		"shift":            &q.ShiftUsesVY,
+		"jump":             &q.JumpUsesV0,
+		"storeLoad":        &q.StoreLoadIncrementsI,
+		"vfReset":          &q.VFReset,
+		"displayWait":      &q.DisplayWait,
+		"wrap":             &q.WrapSprites,
+		"indexOverflow":    &q.IndexOverflowVF,
+		"lowResBigSprite":  &q.LowResBigSprite8x16,
+		"lowResHalfScroll": &q.LowResHalfScroll,
+	}
+}
+
+// quirksToJS returns the current quirks as a JS object of name -> enabled
+func quirksToJS(q *chip8.Quirks) js.Value {
+	quirks := make(map[string]any)
+	for name, enabled := range quirkFields(q) {
+		quirks[name] = *enabled
+	}
+	return js.ValueOf(quirks)
+}
+
+// Toggles the named quirk and returns whether it is now enabled
+func createToggleQuirkHandler(g *Game) func(js.Value, []js.Value) any {
+	return func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return js.ValueOf(map[string]any{
+				"error": "No quirk name provided",
+			})
+		}
+
+		enabled, ok := quirkFields(&g.emulator.Config.Quirks)[args[0].String()]
+		if !ok {
+			return js.ValueOf(map[string]any{
+				"error": fmt.Sprintf("unknown quirk: %q", args[0].String()),
+			})
+		}
+		*enabled = !*enabled
+		// keep the choice for ROMs loaded later
+		*quirkFields(&g.emulator.BaseConfig().Quirks)[args[0].String()] = *enabled
+		return *enabled
+	}
+}
 
+// Applies a platform preset and returns the quirks it set
+func createSetPlatformHandler(g *Game) func(js.Value, []js.Value) any {
+	return func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return js.ValueOf(map[string]any{
+				"error": "No platform provided",
+			})
+		}
+
+		platform, err := chip8.ParsePlatform(args[0].String())
+		if err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+		g.emulator.Config.SetPlatform(platform)
+		g.emulator.BaseConfig().SetPlatform(platform)
+		return quirksToJS(&g.emulator.Config.Quirks)
+	}
+}
+
+// Selects a palette by name, or list of #RRGGBB colours
+func createSetPaletteHandler(g *Game) func(js.Value, []js.Value) any {
+	return func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return js.ValueOf(map[string]any{
+				"error": "No palette provided",
+			})
+		}
+
+		if err := g.selectPalette(args[0].String()); err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
 		return nil
 	}
 }
 
-// Legacy mode: Set VX to VY then shift, Modern mode: Shift VX directly
-func createToggleLegacyShiftHandler(g *Game) func(js.Value, []js.Value) any {
+// Sets the deflicker mode by name: off, fade or or
+func createSetDeflickerHandler(g *Game) func(js.Value, []js.Value) any {
 	return func(this js.Value, args []js.Value) any {
-		g.emulator.Config.LegacyShift = !g.emulator.Config.LegacyShift
-		return g.emulator.Config.LegacyShift
+		if len(args) < 1 {
+			return js.ValueOf(map[string]any{
+				"error": "No deflicker mode provided",
+			})
+		}
+
+		mode, err := parseDeflickerMode(args[0].String())
+		if err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+		g.setDeflickerMode(mode)
+		return nil
 	}
 }
 
-// Legacy mode: Jump to NNN + V0, Modern mode: Jump to NNN + VX
-func createToggleLegacyJumpHandler(g *Game) func(js.Value, []js.Value) any {
+// Sets the CRT shader preset by name, optionally followed by an object
+// overriding its scanlines, bloom, curvature and vignette intensities
+func createSetCRTHandler(g *Game) func(js.Value, []js.Value) any {
 	return func(this js.Value, args []js.Value) any {
-		g.emulator.Config.LegacyJump = !g.emulator.Config.LegacyJump
-		return g.emulator.Config.LegacyJump
+		if len(args) < 1 {
+			return js.ValueOf(map[string]any{
+				"error": "No CRT preset provided",
+			})
+		}
+
+		preset, err := parseCRTPreset(args[0].String())
+		if err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+		settings := crtPresets[preset].settings
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			for name, field := range map[string]*float32{
+				"scanlines": &settings.Scanlines,
+				"bloom":     &settings.Bloom,
+				"curvature": &settings.Curvature,
+				"vignette":  &settings.Vignette,
+			} {
+				if v := args[1].Get(name); v.Type() == js.TypeNumber {
+					*field = float32(min(1, max(0, v.Float())))
+				}
+			}
+		}
+		if err := g.setCRT(settings); err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+		g.crtPreset = preset
+		return nil
 	}
 }
 
-// Legacy mode: Increment I after store/load, Modern mode: I remains unchanged
-func createToggleLegacyStoreLoadHandler(g *Game) func(js.Value, []js.Value) any {
+// Sets the buzzer from an object with any of waveform, frequency, volume and muted
+func createSetSoundHandler(g *Game) func(js.Value, []js.Value) any {
 	return func(this js.Value, args []js.Value) any {
-		g.emulator.Config.LegacyStoreLoad = !g.emulator.Config.LegacyStoreLoad
-		return g.emulator.Config.LegacyStoreLoad
+		if len(args) < 1 || args[0].Type() != js.TypeObject {
+			return js.ValueOf(map[string]any{
+				"error": "No sound settings provided",
+			})
+		}
+
+		settings := g.buzzer.Settings()
+		if v := args[0].Get("waveform"); v.Type() == js.TypeString {
+			waveform, err := sound.ParseWaveform(v.String())
+			if err != nil {
+				return js.ValueOf(map[string]any{
+					"error": err.Error(),
+				})
+			}
+			settings.Waveform = waveform
+		}
+		if v := args[0].Get("frequency"); v.Type() == js.TypeNumber {
+			settings.Frequency = v.Float()
+		}
+		if v := args[0].Get("volume"); v.Type() == js.TypeNumber {
+			settings.Volume = v.Float()
+		}
+		if err := settings.Validate(); err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+		g.buzzer.SetSettings(settings)
+		if v := args[0].Get("muted"); v.Type() == js.TypeBoolean {
+			g.buzzer.SetMuted(v.Bool())
+		}
+		return nil
 	}
 }
 
@@ -93,7 +282,11 @@ func createSetCycleRateHandler(g *Game) func(js.Value, []js.Value) any {
 
 func createResetEmulatorHandler(g *Game) func(js.Value, []js.Value) any {
 	return func(this js.Value, args []js.Value) any {
+		// restart with the settings the user chose since the ROM was loaded,
+		// rather than those of its ROM database entry
+		config := *g.emulator.Config
 		g.emulator.Reset()
+		g.rewind.clear()
 
 		if g.currentRom != nil {
 			if err := g.emulator.LoadROMFromData(g.currentRom); err != nil {
@@ -101,6 +294,44 @@ func createResetEmulatorHandler(g *Game) func(js.Value, []js.Value) any {
 					"error": err.Error(),
 				})
 			}
+			*g.emulator.Config = config
+		}
+		return nil
+	}
+}
+
+// Returns a snapshot of the emulator as a Uint8Array
+func createSaveStateHandler(g *Game) func(js.Value, []js.Value) any {
+	return func(this js.Value, args []js.Value) any {
+		state, err := g.saveState()
+		if err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
+		}
+
+		stateArray := js.Global().Get("Uint8Array").New(len(state))
+		js.CopyBytesToJS(stateArray, state)
+		return stateArray
+	}
+}
+
+// Restores a snapshot from a Uint8Array returned by saveState
+func createLoadStateHandler(g *Game) func(js.Value, []js.Value) any {
+	return func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return js.ValueOf(map[string]any{
+				"error": "No state data provided",
+			})
+		}
+
+		state := make([]byte, args[0].Length())
+		js.CopyBytesToGo(state, args[0])
+
+		if err := g.loadState(state); err != nil {
+			return js.ValueOf(map[string]any{
+				"error": err.Error(),
+			})
 		}
 		return nil
 	}
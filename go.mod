@@ -5,6 +5,7 @@ go 1.24.1
 require (
 	github.com/hajimehoshi/ebiten/v2 v2.8.6
 	golang.org/x/image v0.25.0
+	golang.org/x/sys v0.31.0
 )
 
 require (
@@ -15,6 +16,5 @@ require (
 	github.com/go-text/typesetting v0.2.0 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	golang.org/x/sync v0.12.0 // indirect
-	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 )
@@ -62,9 +62,21 @@ window.addEventListener("message", function (event) {
   if (event.data && event.data.type === "wasmReady") {
     wasmReady = true;
     cacheElements();
+  } else if (event.data && event.data.type === "romInfo") {
+    showROMDatabaseSettings(event.data.info);
   }
 });
 
+// Updates the controls to the settings the loaded ROM runs with, which may
+// come from its ROM database entry
+function showROMDatabaseSettings(info) {
+  document.getElementById("platform-picker").value = info.platform;
+  document.querySelectorAll("[data-quirk]").forEach((button) => {
+    setToggleState(button, info.quirks[button.dataset.quirk]);
+  });
+  document.getElementById("cycle-rate").value = info.cycleRate;
+}
+
 function refocusEmulator() {
   if (!elements.iframe) return;
 
@@ -170,45 +182,41 @@ function handleResetEmulator(event) {
   refocusEmulator();
 }
 
-function handleToggleLegacyShift(event) {
-  if (!wasmReady || !elements.iframe) return;
-
-  if (elements.iframe.contentWindow.toggleLegacyShift()) {
-    event.target.classList.add("toggle-on");
-    event.target.setAttribute("aria-pressed", "true");
+function setToggleState(button, enabled) {
+  if (enabled) {
+    button.classList.add("toggle-on");
+    button.setAttribute("aria-pressed", "true");
   } else {
-    event.target.classList.remove("toggle-on");
-    event.target.setAttribute("aria-pressed", "false");
+    button.classList.remove("toggle-on");
+    button.setAttribute("aria-pressed", "false");
   }
-
-  refocusEmulator();
 }
 
-function handleToggleLegacyJump(event) {
+function handleToggleQuirk(event) {
   if (!wasmReady || !elements.iframe) return;
 
-  if (elements.iframe.contentWindow.toggleLegacyJump()) {
-    event.target.classList.add("toggle-on");
-    event.target.setAttribute("aria-pressed", "true");
-  } else {
-    event.target.classList.remove("toggle-on");
-    event.target.setAttribute("aria-pressed", "false");
-  }
+  const button = event.currentTarget;
+  const enabled = elements.iframe.contentWindow.toggleQuirk(
+    button.dataset.quirk
+  );
+  setToggleState(button, enabled);
 
   refocusEmulator();
 }
 
-function handleToggleLegacyStoreLoad(event) {
+function handleSetPlatform(event) {
   if (!wasmReady || !elements.iframe) return;
 
-  if (elements.iframe.contentWindow.toggleLegacyStoreLoad()) {
-    event.target.classList.add("toggle-on");
-    event.target.setAttribute("aria-pressed", "true");
-  } else {
-    event.target.classList.remove("toggle-on");
-    event.target.setAttribute("aria-pressed", "false");
+  const quirks = elements.iframe.contentWindow.setPlatform(event.target.value);
+  if (quirks.error) {
+    console.error("Error setting platform:", quirks.error);
+    return;
   }
 
+  document.querySelectorAll("[data-quirk]").forEach((button) => {
+    setToggleState(button, quirks[button.dataset.quirk]);
+  });
+
   refocusEmulator();
 }
 
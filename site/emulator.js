@@ -5,7 +5,19 @@ function doneLoading() {
   window.addEventListener("message", function (event) {
     if (event.data && event.data.type === "loadROM") {
       const uint8Array = new Uint8Array(event.data.data);
-      loadROM(uint8Array);
+      const result = loadROM(uint8Array);
+      if (result && result.error) {
+        console.error("Error loading ROM:", result.error);
+      } else if (result) {
+        // the ROM's settings, from its ROM database entry if it matched one
+        if (result.title) {
+          console.log(`ROM database (${result.source}): ${result.title}`);
+        }
+        window.parent.postMessage(
+          { type: "romInfo", info: result },
+          window.location.origin
+        );
+      }
     } else if (event.data && event.data.type === "focus") {
       document.querySelector("canvas").focus();
     }
@@ -0,0 +1,258 @@
+// Package sound synthesises the CHIP-8 buzzer, which sounds while the sound
+// timer is non-zero. The tone is a configurable waveform, or for XO-CHIP
+// programs the audio pattern loaded by F002 played at the FX3A pitch. Short
+// attack and release ramps keep the buzzer from clicking as it starts and stops.
+package sound
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// SampleRate is the sample rate the frontends play and record at, in Hz
+const SampleRate = 48000
+
+// Lengths of the ramps the buzzer fades in and out over
+const (
+	AttackTime  = 5 * time.Millisecond
+	ReleaseTime = 10 * time.Millisecond
+)
+
+// Waveform is the shape of the buzzer's tone
+type Waveform int
+
+const (
+	WaveSquare   Waveform = iota // the classic buzzer
+	WaveSine                     // a soft pure tone
+	WaveTriangle                 // between square and sine
+	WaveNoise                    // white noise, changing value twice a cycle of the frequency
+)
+
+var waveforms = []Waveform{WaveSquare, WaveSine, WaveTriangle, WaveNoise}
+
+// String returns the name of the waveform, as accepted by ParseWaveform
+func (w Waveform) String() string {
+	switch w {
+	case WaveSquare:
+		return "square"
+	case WaveSine:
+		return "sine"
+	case WaveTriangle:
+		return "triangle"
+	case WaveNoise:
+		return "noise"
+	}
+	return fmt.Sprintf("Waveform(%d)", int(w))
+}
+
+// ParseWaveform returns the waveform with the given name
+func ParseWaveform(name string) (Waveform, error) {
+	for _, w := range waveforms {
+		if w.String() == name {
+			return w, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown waveform: %q, use one of %s", name, strings.Join(WaveformNames(), ", "))
+}
+
+// WaveformNames returns the names of the waveforms, for use in help text
+func WaveformNames() []string {
+	names := make([]string, len(waveforms))
+	for i, w := range waveforms {
+		names[i] = w.String()
+	}
+	return names
+}
+
+// Settings configure the buzzer's tone
+type Settings struct {
+	Waveform  Waveform
+	Frequency float64 // in Hz
+	Volume    float64 // from 0 to 1
+}
+
+// DefaultSettings are a square wave at A4
+var DefaultSettings = Settings{
+	Waveform:  WaveSquare,
+	Frequency: 440,
+	Volume:    0.2,
+}
+
+// Validate returns an error if the frequency or volume is out of range
+func (s Settings) Validate() error {
+	if s.Frequency <= 0 || s.Frequency > SampleRate/2 {
+		return fmt.Errorf("frequency must be between 0 and %dHz", SampleRate/2)
+	}
+	if s.Volume < 0 || s.Volume > 1 {
+		return fmt.Errorf("volume must be between 0 and 1")
+	}
+	return nil
+}
+
+// patternRate returns the playback rate in bits per second of an XO-CHIP
+// audio pattern at the given pitch
+func patternRate(pitch byte) float64 {
+	return 4000 * math.Pow(2, (float64(pitch)-64)/48)
+}
+
+// Buzzer generates the buzzer's samples. Its state is set from the emulator
+// with Update, and it can be read from another goroutine, such as an audio
+// player's.
+type Buzzer struct {
+	mu         sync.Mutex
+	sampleRate float64
+	settings   Settings
+	muted      bool
+
+	on          bool // sound timer running
+	usePattern  bool // playing the audio pattern rather than the waveform
+	pattern     [chip8.AudioPatternSize]byte
+	patternRate float64
+
+	phase    float64 // position in the waveform's cycle, or the pattern in bits
+	level    float64 // envelope, ramping between 0 and 1
+	volume   float64 // volume, ramping to the set volume so changes don't click
+	noise    uint16  // linear feedback shift register for noise
+	noiseBit float64
+}
+
+// NewBuzzer returns a silent buzzer generating samples at sampleRate
+func NewBuzzer(sampleRate int, settings Settings) *Buzzer {
+	return &Buzzer{
+		sampleRate: float64(sampleRate),
+		settings:   settings,
+		volume:     settings.Volume,
+		noise:      1,
+		noiseBit:   1,
+	}
+}
+
+// Settings returns the buzzer's tone settings
+func (b *Buzzer) Settings() Settings {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.settings
+}
+
+// SetSettings changes the buzzer's tone
+func (b *Buzzer) SetSettings(settings Settings) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.settings = settings
+}
+
+// Muted reports whether the buzzer is muted
+func (b *Buzzer) Muted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.muted
+}
+
+// SetMuted mutes or unmutes the buzzer, fading like the volume changing
+func (b *Buzzer) SetMuted(muted bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.muted = muted
+}
+
+// Update sets the buzzer sounding while the emulator's sound timer is
+// non-zero. XO-CHIP programs which have loaded an audio pattern play it at
+// their pitch, others the waveform. A pattern of all zeros is taken as not
+// loaded, as it would be silent.
+func (b *Buzzer) Update(emu *chip8.Emulator) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.on = emu.SoundTimer > 0
+	usePattern := false
+	if emu.Config.Variant >= chip8.VariantXOChip {
+		for _, bits := range emu.AudioPattern {
+			usePattern = usePattern || bits != 0
+		}
+	}
+	if usePattern != b.usePattern {
+		// the phase is in different units
+		b.phase = 0
+	}
+	b.usePattern = usePattern
+	b.pattern = emu.AudioPattern
+	b.patternRate = patternRate(emu.Pitch)
+}
+
+// Generate fills samples with the buzzer's output, from -1 to 1
+func (b *Buzzer) Generate(samples []float32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	targetLevel := 0.0
+	if b.on {
+		targetLevel = 1
+	}
+	targetVolume := b.settings.Volume
+	if b.muted {
+		targetVolume = 0
+	}
+	attackStep := 1 / (AttackTime.Seconds() * b.sampleRate)
+	releaseStep := 1 / (ReleaseTime.Seconds() * b.sampleRate)
+
+	for i := range samples {
+		switch {
+		case b.level < targetLevel:
+			b.level = min(targetLevel, b.level+attackStep)
+		case b.level > targetLevel:
+			b.level = max(targetLevel, b.level-releaseStep)
+		}
+		switch {
+		case b.volume < targetVolume:
+			b.volume = min(targetVolume, b.volume+attackStep)
+		case b.volume > targetVolume:
+			b.volume = max(targetVolume, b.volume-releaseStep)
+		}
+
+		if b.level == 0 {
+			samples[i] = 0
+			continue
+		}
+		samples[i] = float32(b.next() * b.level * b.volume)
+	}
+}
+
+// next returns the next sample of the tone at full volume, advancing the phase
+func (b *Buzzer) next() float64 {
+	if b.usePattern {
+		const bits = chip8.AudioPatternSize * 8
+		bit := int(b.phase) % bits
+		b.phase = math.Mod(b.phase+b.patternRate/b.sampleRate, bits)
+		if b.pattern[bit/8]&(0x80>>(bit%8)) != 0 {
+			return 1
+		}
+		return -1
+	}
+
+	p := b.phase
+	b.phase += b.settings.Frequency / b.sampleRate
+	if b.settings.Waveform == WaveNoise && math.Floor(b.phase*2) != math.Floor(p*2) {
+		// a new random value each half cycle
+		bit := (b.noise ^ b.noise>>2 ^ b.noise>>3 ^ b.noise>>5) & 1
+		b.noise = b.noise>>1 | bit<<15
+		b.noiseBit = float64(b.noise&1)*2 - 1
+	}
+	b.phase -= math.Floor(b.phase)
+
+	switch b.settings.Waveform {
+	case WaveSine:
+		return math.Sin(2 * math.Pi * p)
+	case WaveTriangle:
+		return 1 - 4*math.Abs(p-0.5)
+	case WaveNoise:
+		return b.noiseBit
+	}
+	if p < 0.5 {
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,196 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// generate returns n samples from the buzzer
+func generate(b *Buzzer, n int) []float32 {
+	samples := make([]float32, n)
+	b.Generate(samples)
+	return samples
+}
+
+// peak returns the largest absolute sample
+func peak(samples []float32) float64 {
+	p := 0.0
+	for _, s := range samples {
+		p = max(p, math.Abs(float64(s)))
+	}
+	return p
+}
+
+// maxStep returns the largest difference between consecutive samples
+func maxStep(samples []float32) float64 {
+	step := 0.0
+	for i := 1; i < len(samples); i++ {
+		step = max(step, math.Abs(float64(samples[i]-samples[i-1])))
+	}
+	return step
+}
+
+func TestParseWaveform(t *testing.T) {
+	for _, w := range waveforms {
+		got, err := ParseWaveform(w.String())
+		if err != nil || got != w {
+			t.Errorf("ParseWaveform(%q) = %v, %v, expected %v", w.String(), got, err, w)
+		}
+	}
+	if _, err := ParseWaveform("sawtooth"); err == nil {
+		t.Errorf("Expected error parsing unknown waveform")
+	}
+}
+
+func TestBuzzer(t *testing.T) {
+	emu := chip8.New()
+
+	t.Run("Silent while the sound timer is zero", func(t *testing.T) {
+		b := NewBuzzer(SampleRate, DefaultSettings)
+		b.Update(emu)
+		if p := peak(generate(b, SampleRate/10)); p != 0 {
+			t.Errorf("Expected silence, got peak %f", p)
+		}
+	})
+
+	for _, w := range waveforms {
+		t.Run(w.String(), func(t *testing.T) {
+			settings := DefaultSettings
+			settings.Waveform = w
+			b := NewBuzzer(SampleRate, settings)
+
+			emu.SoundTimer = 10
+			b.Update(emu)
+			on := generate(b, SampleRate/10)
+			emu.SoundTimer = 0
+			b.Update(emu)
+			off := generate(b, SampleRate/10)
+
+			if p := peak(on[SampleRate/100:]); math.Abs(p-settings.Volume) > 0.01 {
+				t.Errorf("Expected peak %f after the attack, got %f", settings.Volume, p)
+			}
+			if p := peak(off[SampleRate/50:]); p != 0 {
+				t.Errorf("Expected silence after the release, got peak %f", p)
+			}
+
+			// the attack starts from silence and the release from where the tone was
+			if s := math.Abs(float64(on[0])); s > 0.01 {
+				t.Errorf("Expected the attack to start near zero, got %f", s)
+			}
+			if s := math.Abs(float64(off[0] - on[len(on)-1])); w != WaveNoise && w != WaveSquare && s > 0.05 {
+				t.Errorf("Expected the release to start where the tone was, got a step of %f", s)
+			}
+		})
+	}
+
+	t.Run("Square frequency", func(t *testing.T) {
+		b := NewBuzzer(SampleRate, DefaultSettings)
+		emu.SoundTimer = 60
+		b.Update(emu)
+		samples := generate(b, SampleRate)
+		emu.SoundTimer = 0
+
+		rises := 0
+		for i := 1; i < len(samples); i++ {
+			if samples[i-1] <= 0 && samples[i] > 0 {
+				rises++
+			}
+		}
+		if rises < 439 || rises > 441 {
+			t.Errorf("Expected 440 cycles in a second, got %d", rises)
+		}
+	})
+
+	t.Run("Volume and mute ramp", func(t *testing.T) {
+		settings := DefaultSettings
+		settings.Waveform = WaveSine
+		b := NewBuzzer(SampleRate, settings)
+		emu.SoundTimer = 60
+		b.Update(emu)
+		generate(b, SampleRate/10)
+
+		b.SetMuted(true)
+		muted := generate(b, SampleRate/10)
+		if step := maxStep(muted); step > 0.05 {
+			t.Errorf("Expected muting to fade, got a step of %f", step)
+		}
+		if p := peak(muted[SampleRate/50:]); p != 0 {
+			t.Errorf("Expected silence once muted, got peak %f", p)
+		}
+
+		b.SetMuted(false)
+		settings.Volume = 0.5
+		b.SetSettings(settings)
+		if p := peak(generate(b, SampleRate/10)[SampleRate/50:]); math.Abs(p-0.5) > 0.01 {
+			t.Errorf("Expected peak 0.5 at the new volume, got %f", p)
+		}
+		emu.SoundTimer = 0
+	})
+
+	t.Run("XO-CHIP audio pattern", func(t *testing.T) {
+		xo := chip8.New(chip8.WithVariant(chip8.VariantXOChip))
+		// alternating bytes of ones and zeros, 8 bits high then 8 low
+		for i := range xo.AudioPattern {
+			if i%2 == 0 {
+				xo.AudioPattern[i] = 0xFF
+			}
+		}
+		xo.SoundTimer = 60
+		b := NewBuzzer(SampleRate, DefaultSettings)
+		b.Update(xo)
+		samples := generate(b, SampleRate)
+
+		// 4000 bits per second at the default pitch, 16 bits per cycle
+		rises := 0
+		for i := 1; i < len(samples); i++ {
+			if samples[i-1] <= 0 && samples[i] > 0 {
+				rises++
+			}
+		}
+		if rises < 249 || rises > 251 {
+			t.Errorf("Expected 250 cycles in a second, got %d", rises)
+		}
+	})
+}
+
+func TestSettingsValidate(t *testing.T) {
+	if err := DefaultSettings.Validate(); err != nil {
+		t.Errorf("Expected default settings to be valid, got %v", err)
+	}
+	for _, s := range []Settings{
+		{Waveform: WaveSquare, Frequency: 0, Volume: 0.5},
+		{Waveform: WaveSquare, Frequency: SampleRate, Volume: 0.5},
+		{Waveform: WaveSquare, Frequency: 440, Volume: 1.5},
+		{Waveform: WaveSquare, Frequency: 440, Volume: -0.1},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Expected error validating %+v", s)
+		}
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, []float32{0, 1, -1, 2}, 8000); err != nil {
+		t.Fatalf("WriteWAV returned error: %v", err)
+	}
+	data := buf.Bytes()
+	if len(data) != 44+8 {
+		t.Fatalf("Expected 52 bytes, got %d", len(data))
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " || string(data[36:40]) != "data" {
+		t.Errorf("Unexpected header %q", data[:44])
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:]); rate != 8000 {
+		t.Errorf("Expected sample rate 8000, got %d", rate)
+	}
+	var samples [4]int16
+	binary.Read(bytes.NewReader(data[44:]), binary.LittleEndian, &samples)
+	if samples != [4]int16{0, math.MaxInt16, -math.MaxInt16, math.MaxInt16} {
+		t.Errorf("Unexpected samples %v", samples)
+	}
+}
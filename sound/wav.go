@@ -0,0 +1,58 @@
+package sound
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WriteWAV writes mono samples from -1 to 1 as a 16-bit PCM WAV file
+func WriteWAV(w io.Writer, samples []float32, sampleRate int) error {
+	const (
+		channels      = 1
+		bitsPerSample = 16
+		blockAlign    = channels * bitsPerSample / 8
+	)
+	dataSize := len(samples) * blockAlign
+
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          uint32(36 + dataSize),
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		Format:        1, // PCM
+		Channels:      channels,
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(sampleRate * blockAlign),
+		BlockAlign:    blockAlign,
+		BitsPerSample: bitsPerSample,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(dataSize),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	data := make([]byte, dataSize)
+	for i, s := range samples {
+		v := int16(math.Round(float64(max(-1, min(1, s))) * math.MaxInt16))
+		binary.LittleEndian.PutUint16(data[i*blockAlign:], uint16(v))
+	}
+	_, err := w.Write(data)
+	return err
+}
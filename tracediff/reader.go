@@ -0,0 +1,218 @@
+package tracediff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// Format selects how a reference trace is parsed
+type Format int
+
+const (
+	// FormatAuto detects the format from the start of the trace
+	FormatAuto Format = iota
+	// FormatChip8 is a text or binary trace written by chip8.TraceWriter
+	FormatChip8
+	// FormatKeyValue is one instruction per line of NAME:VALUE or NAME=VALUE
+	// pairs in hex, as logged by many emulators, e.g.
+	//   PC:0200 OP:6E05 V0:00 V1:00 ... VF:00 I:0000 SP:00 DT:00 ST:00
+	// Names are case-insensitive and other text is ignored. Recognised names
+	// are PC, OP or OPCODE, V0 to VF, I, SP, DT or DELAY, ST or SOUND, and
+	// CYCLE (in decimal). Lines without a PC are skipped.
+	FormatKeyValue
+)
+
+// String returns the name of the format, as accepted by ParseFormat
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatChip8:
+		return "chip8"
+	case FormatKeyValue:
+		return "keyvalue"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	for _, f := range []Format{FormatAuto, FormatChip8, FormatKeyValue} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown trace format: %q", name)
+}
+
+// traceReader reads chip8.TraceWriter traces, which record every field
+type traceReader struct {
+	r *chip8.TraceReader
+}
+
+func (t *traceReader) Next() (Record, error) {
+	record, err := t.r.Next()
+	return Record{TraceRecord: record, Fields: allFields}, err
+}
+
+// keyValueReader reads FormatKeyValue traces
+type keyValueReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (k *keyValueReader) Next() (Record, error) {
+	for {
+		text, err := k.r.ReadString('\n')
+		if err != nil && (err != io.EOF || text == "") {
+			return Record{}, err
+		}
+		k.line++
+		record, err := parseKeyValueLine(text)
+		if err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", k.line, err)
+		}
+		if record.Fields&FieldPC != 0 {
+			return record, nil
+		}
+	}
+}
+
+// NewReader returns a source of records from a reference trace
+func NewReader(r io.Reader, format Format) (Source, error) {
+	br := bufio.NewReader(r)
+	if format == FormatAuto {
+		format = detectFormat(br)
+	}
+	switch format {
+	case FormatChip8:
+		return &traceReader{r: chip8.NewTraceReader(br)}, nil
+	case FormatKeyValue:
+		return &keyValueReader{r: br}, nil
+	}
+	return nil, fmt.Errorf("unknown trace format: %s", format)
+}
+
+// detectFormat guesses the format from the first line with content
+func detectFormat(r *bufio.Reader) Format {
+	start, _ := r.Peek(4096)
+	if bytes.HasPrefix(start, []byte("C8TR")) {
+		return FormatChip8
+	}
+	for line := range strings.Lines(string(start)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// chip8.TraceWriter text lines start with the cycle, PC and opcode
+		fields := strings.Fields(line)
+		if len(fields) > 3 && strings.HasPrefix(fields[3], "V=") {
+			return FormatChip8
+		}
+		break
+	}
+	return FormatKeyValue
+}
+
+// parseKeyValueLine parses the NAME:VALUE pairs of a FormatKeyValue line
+func parseKeyValueLine(text string) (Record, error) {
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '|' || r == '\r' || r == '\n'
+	})
+
+	var record Record
+	for i := 0; i < len(tokens); i++ {
+		name, value, found := strings.Cut(tokens[i], ":")
+		if !found {
+			name, value, found = strings.Cut(tokens[i], "=")
+		}
+		if !found {
+			continue
+		}
+		if value == "" && i+1 < len(tokens) {
+			// "PC: 0200" style, the value is the next token
+			i++
+			value = tokens[i]
+		}
+		if err := record.set(strings.ToUpper(name), value); err != nil {
+			return Record{}, err
+		}
+	}
+	return record, nil
+}
+
+// set parses the value of a named field
+func (r *Record) set(name string, value string) error {
+	parse := func(size int) (uint64, error) {
+		text := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(value), "0x"), "$")
+		v, err := strconv.ParseUint(text, 16, size)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s value %q", name, value)
+		}
+		return v, nil
+	}
+
+	var field Field
+	var v uint64
+	var err error
+	switch name {
+	case "PC":
+		field = FieldPC
+		v, err = parse(16)
+		r.PC = uint16(v)
+	case "OP", "OPCODE":
+		field = FieldOpcode
+		v, err = parse(16)
+		r.Opcode = uint16(v)
+	case "I":
+		field = FieldI
+		v, err = parse(16)
+		r.I = uint16(v)
+	case "SP":
+		field = FieldSP
+		v, err = parse(8)
+		r.SP = byte(v)
+	case "DT", "DELAY":
+		field = FieldDT
+		v, err = parse(8)
+		r.DT = byte(v)
+	case "ST", "SOUND":
+		field = FieldST
+		v, err = parse(8)
+		r.ST = byte(v)
+	case "CYCLE":
+		field = FieldCycle
+		r.Cycle, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			err = fmt.Errorf("invalid %s value %q", name, value)
+		}
+	default:
+		reg, ok := registerIndex(name)
+		if !ok {
+			return nil // other text, e.g. a mnemonic
+		}
+		field = FieldV0 << reg
+		v, err = parse(8)
+		r.V[reg] = byte(v)
+	}
+	if err != nil {
+		return err
+	}
+	r.Fields |= field
+	return nil
+}
+
+// registerIndex returns the index of a V0-VF register name
+func registerIndex(name string) (int, bool) {
+	if len(name) != 2 || name[0] != 'V' {
+		return 0, false
+	}
+	reg, err := strconv.ParseUint(name[1:], 16, 4)
+	return int(reg), err == nil
+}
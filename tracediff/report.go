@@ -0,0 +1,123 @@
+package tracediff
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// Limits of the display region shown in a report, so side by side views fit a terminal
+const (
+	regionMargin    = 2
+	maxRegionWidth  = 48
+	maxRegionHeight = 32
+)
+
+// WriteReport writes a side by side view of a divergence, with mnemonics decoded for variant
+func WriteReport(w io.Writer, d *Divergence, variant chip8.Variant) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "First divergence at instruction %d (cycle %d", d.Index+1, d.Ours.Cycle)
+	if d.Reference.Fields&FieldCycle != 0 {
+		fmt.Fprintf(&b, ", reference cycle %d", d.Reference.Cycle)
+	}
+	fmt.Fprintln(&b, ")")
+
+	if len(d.Previous) > 0 {
+		fmt.Fprintln(&b, "\nPreceding instructions:")
+		for i, r := range d.Previous {
+			index := d.Index - uint64(len(d.Previous)) + uint64(i) + 1
+			fmt.Fprintf(&b, "  %8d  %04X  %04X  %s\n", index, r.PC, r.Opcode, r.Mnemonic(variant))
+		}
+	}
+	fmt.Fprintf(&b, "\nOurs:      %04X  %04X  %s\n", d.Ours.PC, d.Ours.Opcode, d.Ours.Mnemonic(variant))
+	if d.Reference.Fields&FieldOpcode != 0 {
+		fmt.Fprintf(&b, "Reference: %04X  %04X  %s\n", d.Reference.PC, d.Reference.Opcode, d.Reference.Mnemonic(variant))
+	} else {
+		fmt.Fprintf(&b, "Reference: %04X\n", d.Reference.PC)
+	}
+
+	fmt.Fprintf(&b, "\nState after the instruction:\n      %-6s %s\n", "ours", "reference")
+	row := func(name string, field Field, ours, ref string) {
+		if d.Reference.Fields&field == 0 {
+			ref = "--"
+		}
+		marker := ""
+		if d.Fields&field != 0 {
+			marker = "  <<"
+		}
+		line := fmt.Sprintf("%-5s %-6s %-9s%s", name, ours, ref, marker)
+		fmt.Fprintln(&b, strings.TrimRight(line, " "))
+	}
+	row("PC", FieldPC, hex(d.Ours.PC, 4), hex(d.Reference.PC, 4))
+	for reg := range d.Ours.V {
+		row(fmt.Sprintf("V%X", reg), FieldV0<<reg, hex(d.Ours.V[reg], 2), hex(d.Reference.V[reg], 2))
+	}
+	row("I", FieldI, hex(d.Ours.I, 4), hex(d.Reference.I, 4))
+	row("SP", FieldSP, hex(d.Ours.SP, 1), hex(d.Reference.SP, 1))
+	row("DT", FieldDT, hex(d.Ours.DT, 2), hex(d.Reference.DT, 2))
+	row("ST", FieldST, hex(d.Ours.ST, 2), hex(d.Reference.ST, 2))
+
+	fmt.Fprintf(&b, "\nStack:\n  ours       %s\n", formatStack(d.Emulator))
+	if d.ReferenceEmulator != nil {
+		marker := ""
+		if d.Fields&FieldStack != 0 {
+			marker = "  <<"
+		}
+		fmt.Fprintf(&b, "  reference  %s%s\n", formatStack(d.ReferenceEmulator), marker)
+	}
+
+	if d.Display {
+		writeRegion(&b, d.Emulator.DisplayImage(), d.ReferenceEmulator.DisplayImage(), d.Region)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func hex[T uint16 | byte](value T, digits int) string {
+	return fmt.Sprintf("%0*X", digits, value)
+}
+
+func formatStack(emu *chip8.Emulator) string {
+	if emu.SP == 0 {
+		return "(empty)"
+	}
+	entries := make([]string, emu.SP)
+	for i, address := range emu.Stack[:emu.SP] {
+		entries[i] = hex(address, 4)
+	}
+	return strings.Join(entries, " ")
+}
+
+// writeRegion writes the differing region of two displays side by side, using
+// the glyphs of headless text output: '.' off, '#' plane 1, 'o' plane 2, '@' both
+func writeRegion(b *strings.Builder, ours, ref *image.Paletted, region image.Rectangle) {
+	shown := region.Inset(-regionMargin).Intersect(ours.Bounds().Union(ref.Bounds()))
+	shown.Max.X = min(shown.Max.X, shown.Min.X+maxRegionWidth)
+	shown.Max.Y = min(shown.Max.Y, shown.Min.Y+maxRegionHeight)
+
+	fmt.Fprintf(b, "\nDisplay differs in x %d-%d, y %d-%d:\n", region.Min.X, region.Max.X-1, region.Min.Y, region.Max.Y-1)
+	fmt.Fprintf(b, "  %-*s  %s\n", shown.Dx(), "ours", "reference")
+	glyphs := [4]byte{'.', '#', 'o', '@'}
+	pixel := func(img *image.Paletted, x, y int) byte {
+		if !(image.Point{x, y}.In(img.Bounds())) {
+			return ' '
+		}
+		return glyphs[img.ColorIndexAt(x, y)&0x3]
+	}
+	for y := shown.Min.Y; y < shown.Max.Y; y++ {
+		line := []byte("  ")
+		for x := shown.Min.X; x < shown.Max.X; x++ {
+			line = append(line, pixel(ours, x, y))
+		}
+		line = append(line, ' ', ' ')
+		for x := shown.Min.X; x < shown.Max.X; x++ {
+			line = append(line, pixel(ref, x, y))
+		}
+		fmt.Fprintln(b, string(line))
+	}
+}
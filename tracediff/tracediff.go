@@ -0,0 +1,222 @@
+// Package tracediff runs a ROM and compares its execution, instruction by
+// instruction, with a reference: a trace logged by another emulator or an
+// earlier version of this one, or a second emulator configured differently.
+package tracediff
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// Field is a set of values recorded in a trace
+type Field uint32
+
+// FieldV0 is register V0, V1 to VF are FieldV0 << register
+const FieldV0 Field = 1
+
+const (
+	FieldPC Field = 1 << (16 + iota)
+	FieldOpcode
+	FieldLong // address following an XO-CHIP F000 opcode
+	FieldI
+	FieldSP
+	FieldDT
+	FieldST
+	FieldCycle // reported but never compared, emulators count cycles differently
+	FieldStack // stack contents, only compared against an emulator reference
+
+	FieldRegisters = FieldPC - FieldV0 // V0 to VF
+
+	allFields = FieldRegisters | FieldPC | FieldOpcode | FieldLong | FieldI | FieldSP | FieldDT | FieldST | FieldCycle
+)
+
+// Record is the state after executing an instruction, as far as it is known
+type Record struct {
+	chip8.TraceRecord
+	Fields Field // fields recorded in the reference
+}
+
+// Source produces the records of successive instructions, returning io.EOF at the end
+type Source interface {
+	Next() (Record, error)
+}
+
+// Options configure a comparison
+type Options struct {
+	MaxCycles uint64 // stop after this many cycles, 0 for no limit
+	Context   int    // matching instructions kept before a divergence
+}
+
+// Divergence describes the first instruction where execution differed
+type Divergence struct {
+	Index     uint64          // number of instructions that matched before this one
+	Ours      Record          // our state after the instruction
+	Reference Record          // reference state after the instruction
+	Fields    Field           // fields which differ
+	Previous  []Record        // preceding instructions, oldest first, up to Options.Context
+	Display   bool            // whether the displays differ, only known for an emulator reference
+	Region    image.Rectangle // bounds of the differing pixels when Display is set
+
+	// Emulators stopped after the instruction, for inspecting the stack and
+	// display. The reference is nil unless it was an emulator.
+	Emulator, ReferenceEmulator *chip8.Emulator
+}
+
+// ErrCycleLimit is returned by Compare when MaxCycles ran without divergence
+// or the end of the reference
+var ErrCycleLimit = errors.New("cycle limit reached")
+
+// Compare runs emu instruction by instruction alongside the reference,
+// returning the first divergence, or nil if the reference ended without one.
+// The emulator must have the ROM loaded, and runs in 60Hz frames as set by
+// its config, like the run command. emu's tracer is replaced.
+func Compare(emu *chip8.Emulator, reference Source, options Options) (*Divergence, error) {
+	ours := &EmulatorSource{Emulator: emu, MaxCycles: options.MaxCycles}
+	refEmulator, _ := reference.(*EmulatorSource)
+
+	var previous []Record
+	for index := uint64(0); ; index++ {
+		ref, err := reference.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading reference instruction %d: %w", index+1, err)
+		}
+		record, err := ours.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("program exited after %d instructions, before the end of the reference", index)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", index+1, err)
+		}
+		record.Fields = allFields
+
+		d := &Divergence{
+			Index:     index,
+			Ours:      record,
+			Reference: ref,
+			Fields:    diff(record, ref),
+			Previous:  previous,
+			Emulator:  emu,
+		}
+		if refEmulator != nil {
+			d.ReferenceEmulator = refEmulator.Emulator
+			d.Region = displayDiff(emu.DisplayImage(), refEmulator.Emulator.DisplayImage())
+			d.Display = !d.Region.Empty()
+			if !equalStacks(emu, refEmulator.Emulator) {
+				d.Fields |= FieldStack
+			}
+		}
+		if d.Fields != 0 || d.Display {
+			return d, nil
+		}
+
+		if options.Context > 0 {
+			if len(previous) == options.Context {
+				previous = previous[1:]
+			}
+			previous = append(previous, record)
+		}
+	}
+}
+
+// diff returns the fields recorded in the reference which differ
+func diff(ours, ref Record) Field {
+	var fields Field
+	for reg := range ours.V {
+		if ours.V[reg] != ref.V[reg] {
+			fields |= FieldV0 << reg
+		}
+	}
+	if ours.PC != ref.PC {
+		fields |= FieldPC
+	}
+	if ours.Opcode != ref.Opcode {
+		fields |= FieldOpcode
+	}
+	if ours.Long != ref.Long {
+		fields |= FieldLong
+	}
+	if ours.I != ref.I {
+		fields |= FieldI
+	}
+	if ours.SP != ref.SP {
+		fields |= FieldSP
+	}
+	if ours.DT != ref.DT {
+		fields |= FieldDT
+	}
+	if ours.ST != ref.ST {
+		fields |= FieldST
+	}
+	return fields & ref.Fields &^ FieldCycle
+}
+
+func equalStacks(a, b *chip8.Emulator) bool {
+	return a.SP == b.SP && slices.Equal(a.Stack[:a.SP], b.Stack[:b.SP])
+}
+
+// displayDiff returns the bounds of the pixels which differ between two displays
+func displayDiff(a, b *image.Paletted) image.Rectangle {
+	if a.Bounds() != b.Bounds() {
+		return a.Bounds().Union(b.Bounds())
+	}
+	var region image.Rectangle
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.ColorIndexAt(x, y) != b.ColorIndexAt(x, y) {
+				region = region.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return region
+}
+
+// EmulatorSource runs an emulator in 60Hz frames, as RunFrame would, producing
+// a record per executed instruction
+type EmulatorSource struct {
+	Emulator  *chip8.Emulator
+	MaxCycles uint64 // stop after this many cycles, 0 for no limit
+
+	record  chip8.TraceRecord
+	traced  bool
+	stopErr error
+}
+
+// Next steps the emulator through its frames until it executes an
+// instruction, returning io.EOF once the program has exited
+func (s *EmulatorSource) Next() (Record, error) {
+	if s.stopErr != nil {
+		return Record{}, s.stopErr
+	}
+	s.Emulator.SetTracer(func(r chip8.TraceRecord) {
+		s.record = r
+		s.traced = true
+	})
+	defer s.Emulator.SetTracer(nil)
+
+	s.traced = false
+	for !s.traced {
+		if s.MaxCycles > 0 && s.Emulator.Cycle() >= s.MaxCycles {
+			return Record{}, ErrCycleLimit
+		}
+		_, err := s.Emulator.StepFrame()
+		var watchErr *chip8.WatchpointError
+		switch {
+		case errors.Is(err, chip8.ErrProgramExit):
+			// the exit instruction is traced, the next call ends the source
+			s.stopErr = io.EOF
+		case errors.As(err, &watchErr):
+		case err != nil:
+			return Record{}, err
+		}
+	}
+	return Record{TraceRecord: s.record, Fields: allFields}, nil
+}
@@ -0,0 +1,182 @@
+package tracediff
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/bdeatock/chip8-emulator/asm"
+	"github.com/bdeatock/chip8-emulator/chip8"
+)
+
+// newEmulator returns an emulator with source assembled and loaded
+func newEmulator(t *testing.T, source string, options ...chip8.EmulatorOption) *chip8.Emulator {
+	t.Helper()
+	emu := chip8.New(append([]chip8.EmulatorOption{chip8.WithSeed(0)}, options...)...)
+	if err := emu.LoadROMFromData(asm.MustAssemble(source)); err != nil {
+		t.Fatalf("LoadROMFromData returned error: %v", err)
+	}
+	return emu
+}
+
+// indexOverflowSource adds to I past 0x0FFF, which only sets VF with the IndexOverflowVF quirk
+const indexOverflowSource = `
+	ld  v0, 0x10
+	ld  i, 0xFF8
+	add i, v0
+	call sub
+	exit
+sub:
+	ret
+`
+
+func TestCompare(t *testing.T) {
+	t.Run("Matches own trace", func(t *testing.T) {
+		var trace bytes.Buffer
+		tw := chip8.NewTraceWriter(&trace, chip8.TraceText, chip8.VariantSuperChip)
+		emu := newEmulator(t, indexOverflowSource, chip8.WithTracer(tw.Trace))
+		for range 6 {
+			emu.Step(0)
+		}
+		if err := tw.Flush(); err != nil {
+			t.Fatalf("Flush returned error: %v", err)
+		}
+
+		reference, err := NewReader(&trace, FormatAuto)
+		if err != nil {
+			t.Fatalf("NewReader returned error: %v", err)
+		}
+		d, err := Compare(newEmulator(t, indexOverflowSource), reference, Options{})
+		if err != nil {
+			t.Fatalf("Compare returned error: %v", err)
+		}
+		if d != nil {
+			t.Errorf("Expected no divergence, got instruction %d fields %b", d.Index+1, d.Fields)
+		}
+	})
+
+	t.Run("Matches own trace run in frames", func(t *testing.T) {
+		// the timers tick between frames, so each instruction sees the DT run would
+		const source = `
+			ld  v0, 30
+			ld  dt, v0
+		loop:
+			ld  v1, dt
+			ld  st, v1
+			se  v1, 0
+			jp  loop
+			exit
+		`
+		var trace bytes.Buffer
+		tw := chip8.NewTraceWriter(&trace, chip8.TraceText, chip8.VariantSuperChip)
+		emu := newEmulator(t, source, chip8.WithInstructionsPerFrame(7), chip8.WithTracer(tw.Trace))
+		for range 10 {
+			if _, err := emu.RunFrame(); err != nil {
+				t.Fatalf("RunFrame returned error: %v", err)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			t.Fatalf("Flush returned error: %v", err)
+		}
+
+		reference, err := NewReader(&trace, FormatAuto)
+		if err != nil {
+			t.Fatalf("NewReader returned error: %v", err)
+		}
+		d, err := Compare(newEmulator(t, source, chip8.WithInstructionsPerFrame(7)), reference, Options{})
+		if err != nil {
+			t.Fatalf("Compare returned error: %v", err)
+		}
+		if d != nil {
+			t.Errorf("Expected no divergence, got instruction %d fields %b", d.Index+1, d.Fields)
+		}
+	})
+
+	t.Run("Key value reference", func(t *testing.T) {
+		// VF set by FX1E in the reference, and only some fields recorded
+		log := `
+			emulator log, no PC on this line
+			PC: 0x0200 | OP: 6010 | V0: 10 | VF: 00 | I: 0000
+			PC=0202 OP=AFF8 V0=10 VF=00 I=0FF8
+			pc:204 op:F01E v0:10 vf:01 i:1008 ; ADD I, V0
+			PC:206 OP:2208 V0:10 VF:01 I:1008 SP:1
+		`
+		reference, err := NewReader(strings.NewReader(log), FormatAuto)
+		if err != nil {
+			t.Fatalf("NewReader returned error: %v", err)
+		}
+		d, err := Compare(newEmulator(t, indexOverflowSource), reference, Options{Context: 1})
+		if err != nil {
+			t.Fatalf("Compare returned error: %v", err)
+		}
+		if d == nil {
+			t.Fatal("Expected a divergence")
+		}
+		if d.Index != 2 || d.Fields != FieldV0<<0xF {
+			t.Errorf("Expected VF to differ on instruction 3, got instruction %d fields %b", d.Index+1, d.Fields)
+		}
+		if len(d.Previous) != 1 || d.Previous[0].PC != 0x202 {
+			t.Errorf("Expected the previous instruction at 0x202 as context, got %+v", d.Previous)
+		}
+
+		var report bytes.Buffer
+		if err := WriteReport(&report, d, chip8.VariantSuperChip); err != nil {
+			t.Fatalf("WriteReport returned error: %v", err)
+		}
+		for _, expected := range []string{"instruction 3", "ADD I, V0", "VF    00     01         <<", "SP    0      --"} {
+			if !strings.Contains(report.String(), expected) {
+				t.Errorf("Expected report to contain %q, got:\n%s", expected, report.String())
+			}
+		}
+	})
+
+	t.Run("Invalid key value reference", func(t *testing.T) {
+		reference, _ := NewReader(strings.NewReader("PC:0200 V0:XYZ\n"), FormatKeyValue)
+		if _, err := reference.Next(); err == nil {
+			t.Error("Expected an error for an invalid register value")
+		}
+	})
+
+	t.Run("Emulator reference with different quirks", func(t *testing.T) {
+		quirks := chip8.Quirks{IndexOverflowVF: true}
+		ref := newEmulator(t, indexOverflowSource, chip8.WithQuirks(quirks))
+		d, err := Compare(newEmulator(t, indexOverflowSource), &EmulatorSource{Emulator: ref}, Options{})
+		if err != nil {
+			t.Fatalf("Compare returned error: %v", err)
+		}
+		if d == nil || d.Index != 2 || d.Fields != FieldV0<<0xF {
+			t.Fatalf("Expected VF to differ on instruction 3, got %+v", d)
+		}
+	})
+
+	t.Run("Display divergence", func(t *testing.T) {
+		// sprite at x=62 is clipped, or wraps to x=0-1 with WrapSprites
+		source := `
+			ld  v0, 62
+			ld  v1, 0
+			ld  i, sprite
+			drw v0, v1, 1
+			exit
+		sprite:
+			:byte 0xF0
+		`
+		ref := newEmulator(t, source, chip8.WithQuirks(chip8.Quirks{WrapSprites: true}))
+		d, err := Compare(newEmulator(t, source), &EmulatorSource{Emulator: ref}, Options{})
+		if err != nil {
+			t.Fatalf("Compare returned error: %v", err)
+		}
+		if d == nil || d.Index != 3 || !d.Display || d.Fields != 0 {
+			t.Fatalf("Expected only the display to differ on instruction 4, got %+v", d)
+		}
+		if d.Region != image.Rect(0, 0, 2, 1) {
+			t.Errorf("Expected differing region (0,0)-(2,1), got %v", d.Region)
+		}
+
+		var report bytes.Buffer
+		WriteReport(&report, d, chip8.VariantSuperChip)
+		if !strings.Contains(report.String(), "Display differs in x 0-1, y 0-0") {
+			t.Errorf("Expected report to show the display region, got:\n%s", report.String())
+		}
+	})
+}